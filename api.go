@@ -0,0 +1,484 @@
+package reputationfuzz
+
+// Config describes the network that a laddering attack is performed on, and
+// the mechanisms that are in place to defend against it. The zero value of
+// each optional field keeps the model's default behavior.
+type Config struct {
+	// FirstNodeTraffic is the amount of payment traffic that is forwarded
+	// by the node that the attacker connects to, expressed as total volume
+	// over the reputation period.
+	FirstNodeTraffic uint64
+
+	// TrafficFlows describes each node in the route, starting with the
+	// node that the attacker connects to. The attack targets the
+	// reputation of the penultimate node with the final node.
+	TrafficFlows []TrafficFlow
+
+	// TimeAveraged evaluates the target's reputation as an average over
+	// the revenue period.
+	TimeAveraged bool
+
+	// RecencyWeighting optionally returns the percentage weight that is
+	// applied to traffic forwarded weeksAgo weeks ago.
+	RecencyWeighting func(weeksAgo uint64) uint64
+
+	// HopBuildWeeks is the number of weeks that the attacker spends on
+	// each hop after the first, if the ladder is built sequentially.
+	HopBuildWeeks uint64
+
+	// WeeklyDecayPercent is the percentage of the attacker's reputation
+	// with the first node that decays each week that it isn't routing.
+	WeeklyDecayPercent uint8
+
+	// LastHopWeightPercent scales the reputation that the target earns
+	// with the final node.
+	LastHopWeightPercent uint16
+
+	// AttackerOnProbation ignores the attacker's reputation with the first
+	// node.
+	AttackerOnProbation bool
+
+	// EndorsementLevel is the graded endorsement level (1-7) that the
+	// attacker requires on each hop. Level 0 is not endorsed, so a zero
+	// value requires full endorsement.
+	EndorsementLevel uint8
+
+	// WeeklyGrowthCap caps the reputation that can be gained per week.
+	WeeklyGrowthCap uint64
+
+	// TargetUptimeLoss is the number of percentage points of uptime that
+	// the attacker can knock off the target.
+	TargetUptimeLoss uint8
+
+	// AttackerSlots is the number of HTLCs that the attacker splits its
+	// endorsed amount across.
+	AttackerSlots uint16
+
+	// ChannelPurchaseCost is the price of buying a channel that is already
+	// reputable with the target, zero if channels can't be bought.
+	ChannelPurchaseCost uint64
+
+	// Params holds the parameters of the reputation algorithm.
+	Params Params
+
+	// MinHTLCSize is the size of the smallest HTLC that the attacker can
+	// use to occupy a slot, in msat.
+	MinHTLCSize uint64
+
+	// ProtectedSlotPercent is the percentage of each channel's slots that
+	// are reserved for endorsed HTLCs from reputable peers, zero if slots
+	// aren't split into protected and general buckets.
+	ProtectedSlotPercent uint8
+
+	// ProtectedLiquidity is the liquidity in msat that is reserved for
+	// protected HTLCs on the target's outgoing link, zero if unlimited.
+	ProtectedLiquidity uint64
+
+	// CapitalRateBasisPoints is the annualized rate of return that the
+	// attacker forgoes on capital locked in its HTLCs, in basis points,
+	// zero to ignore the opportunity cost of locked capital.
+	CapitalRateBasisPoints uint16
+
+	// SettlePercent is the percentage of the attacker's payments that
+	// settle and earn reputation, nil if every payment settles.
+	SettlePercent *uint8
+}
+
+// TrafficFlow describes a single node in a laddering attack's route.
+type TrafficFlow struct {
+	// PortionBasisPoints is the portion of the node's outgoing traffic
+	// that is provided by the node that precedes it, in basis points.
+	PortionBasisPoints uint16
+
+	// RoundTripPercent is the percentage of traffic that is routed in
+	// both directions, zero if round trips are not required.
+	RoundTripPercent uint8
+
+	// UptimePercent is the percentage of time that the node is online,
+	// zero for full uptime.
+	UptimePercent uint8
+
+	// SlotCapacity is the number of endorsed slots on the node's outgoing
+	// link, zero for the protocol maximum.
+	SlotCapacity uint16
+
+	// FeePolicy is the fee policy that the node charges, zero to count
+	// traffic volume 1:1.
+	FeePolicy FeePolicy
+
+	// CltvDelta is the node's cltv delta, or the final cltv delta for the
+	// last node in the route. Nodes that leave it zero require the default
+	// delta, and the final node must set it if any node in the route does.
+	CltvDelta uint64
+
+	// WeeklyProfile optionally describes the relative weight of the
+	// node's traffic in each week, most recent first.
+	WeeklyProfile []uint64
+}
+
+// trafficFlow converts the flow to the internal description of a node.
+func (f TrafficFlow) trafficFlow() trafficFlow {
+	return trafficFlow{
+		portionBasisPoints: f.PortionBasisPoints,
+		roundTripPercent:   f.RoundTripPercent,
+		uptimePercent:      f.UptimePercent,
+		slotCapacity:       f.SlotCapacity,
+		feePolicy:          f.FeePolicy,
+		cltvDelta:          f.CltvDelta,
+		weeklyProfile:      f.WeeklyProfile,
+	}
+}
+
+// trafficFlows converts each of the flows provided to the internal
+// description of a node.
+func trafficFlows(flows []TrafficFlow) []trafficFlow {
+	converted := make([]trafficFlow, len(flows))
+	for i, flow := range flows {
+		converted[i] = flow.trafficFlow()
+	}
+
+	return converted
+}
+
+// ladderCfg converts the config to the internal configuration of the model.
+func (c Config) ladderCfg() ladderingAttackCfg {
+	return ladderingAttackCfg{
+		firstNodeTraffic:     c.FirstNodeTraffic,
+		trafficFlows:         trafficFlows(c.TrafficFlows),
+		timeAveraged:         c.TimeAveraged,
+		recencyWeighting:     c.RecencyWeighting,
+		hopBuildWeeks:        c.HopBuildWeeks,
+		weeklyDecayPercent:   c.WeeklyDecayPercent,
+		lastHopWeightPercent: c.LastHopWeightPercent,
+		attackerOnProbation:  c.AttackerOnProbation,
+		endorsementLevel:     c.EndorsementLevel,
+		weeklyGrowthCap:      c.WeeklyGrowthCap,
+		targetUptimeLoss:     c.TargetUptimeLoss,
+		attackerSlots:        c.AttackerSlots,
+		channelPurchaseCost:  c.ChannelPurchaseCost,
+		params:               c.Params,
+		minHTLCSize:          c.MinHTLCSize,
+		protectedSlotPercent: c.ProtectedSlotPercent,
+		protectedLiquidity:   c.ProtectedLiquidity,
+
+		capitalRateBasisPoints: c.CapitalRateBasisPoints,
+		settlePercent:          c.SettlePercent,
+	}
+}
+
+// NewLadderingAttack creates a laddering attack on the network described by
+// the config provided.
+func NewLadderingAttack(cfg Config) (*LadderingAttack, error) {
+	return newLadderingAttack(cfg.ladderCfg())
+}
+
+// FanInBranch describes one of the incoming paths that an attacker ladders
+// up to the target over in a fan-in attack.
+type FanInBranch struct {
+	// FirstNodeTraffic is the amount of payment traffic that is forwarded
+	// by the node that the attacker connects to in this branch.
+	FirstNodeTraffic uint64
+
+	// TrafficFlows describes each node in the branch, starting with the
+	// node that the attacker connects to and ending with the target's
+	// incoming peer.
+	TrafficFlows []TrafficFlow
+}
+
+// FanInConfig describes a fan-in attack, where the attacker builds several
+// incoming branches that converge on a single target.
+type FanInConfig struct {
+	// Config holds the settings that are shared by every branch. Its
+	// first node traffic and traffic flows are ignored.
+	Config
+
+	// TargetTraffic is the amount of payment traffic that is forwarded
+	// by the target.
+	TargetTraffic uint64
+
+	// Target describes the target node. Its portion is ignored, because
+	// each branch provides its own portion of the target's traffic.
+	Target TrafficFlow
+
+	// Final describes the final node that the target's reputation is
+	// measured with.
+	Final TrafficFlow
+
+	// Branches describes each incoming path to the target, which must
+	// together forward no more than the target's traffic.
+	Branches []FanInBranch
+}
+
+// NewFanInAttack creates a laddering attack where the attacker builds each of
+// the config's incoming branches to the target.
+func NewFanInAttack(cfg FanInConfig) (*FanInAttack, error) {
+	branches := make([]fanInBranch, len(cfg.Branches))
+	for i, branch := range cfg.Branches {
+		branches[i] = fanInBranch{
+			firstNodeTraffic: branch.FirstNodeTraffic,
+			trafficFlows:     trafficFlows(branch.TrafficFlows),
+		}
+	}
+
+	return newFanInAttack(fanInCfg{
+		shared:        cfg.ladderCfg(),
+		targetTraffic: cfg.TargetTraffic,
+		target:        cfg.Target.trafficFlow(),
+		final:         cfg.Final.trafficFlow(),
+		branches:      branches,
+	})
+}
+
+// SurgeConfig holds optional parameters that adjust how a surge attack is
+// modeled. The zero value models the attack without any protective
+// mechanisms in place.
+type SurgeConfig struct {
+	// ReputationFloor protects peers with at least this much reputation
+	// from being cut off, zero to disable.
+	ReputationFloor uint64
+
+	// PeerGroups optionally groups channels by the peer that they belong
+	// to, so that they're aggregated and cut off together.
+	PeerGroups []int
+
+	// BandLowIndex restricts the attacker to cutting off peers from this
+	// index up to the cutoff index.
+	BandLowIndex int
+
+	// Allowlist optionally marks peers that are always trusted.
+	Allowlist []bool
+
+	// Insider optionally marks the one peer that is controlled by the
+	// attacker, which the node loses the revenue of during the attack.
+	Insider []bool
+
+	// PeerAges optionally holds the age of each peer in weeks.
+	PeerAges []uint64
+
+	// GraceWeeks is the number of weeks during which new peers have
+	// provisional reputation and can't be cut off, zero to disable.
+	GraceWeeks uint64
+
+	// JamSettledRevenue is the fee revenue that the attacker's jamming
+	// HTLCs pay the node if they settle, zero if they're failed back.
+	JamSettledRevenue uint64
+
+	// WeeklyRevenue is the node's normal revenue per week, used to model
+	// the revenue that it loses while recovering from the attack. Zero
+	// doesn't model recovery.
+	WeeklyRevenue uint64
+
+	// JamLiquidity is the liquidity that the attacker locks up in its
+	// general jamming HTLCs for the attack, zero to model an attacker
+	// that fills every slot on the targeted link with a minimum sized
+	// HTLC.
+	JamLiquidity uint64
+
+	// CapitalRateBasisPoints is the annualized rate of return that the
+	// attacker forgoes on its jam liquidity, in basis points, zero to
+	// ignore the opportunity cost of locked capital.
+	CapitalRateBasisPoints uint16
+
+	// ThresholdMultiplierPercent sets the threshold that peers must clear
+	// to have good reputation to this percentage of the node's revenue,
+	// zero to use the node's revenue.
+	ThresholdMultiplierPercent uint16
+
+	// Params holds the parameters of the reputation algorithm.
+	Params Params
+}
+
+// surgeCfg converts the config to the internal configuration of the model.
+func (c SurgeConfig) surgeCfg() surgeAttackCfg {
+	return surgeAttackCfg{
+		reputationFloor:        c.ReputationFloor,
+		peerGroups:             c.PeerGroups,
+		bandLowIndex:           c.BandLowIndex,
+		allowlist:              c.Allowlist,
+		insider:                c.Insider,
+		peerAges:               c.PeerAges,
+		graceWeeks:             c.GraceWeeks,
+		jamSettledRevenue:      c.JamSettledRevenue,
+		weeklyRevenue:          c.WeeklyRevenue,
+		jamLiquidity:           c.JamLiquidity,
+		capitalRateBasisPoints: c.CapitalRateBasisPoints,
+		thresholdPercent:       c.ThresholdMultiplierPercent,
+		params:                 c.Params,
+	}
+}
+
+// SurgeAttack models a surge attack against a node with the honest peers
+// provided, where the attacker cuts off the reputation of peers up to the
+// cutoff index in the sorted set of peers.
+func SurgeAttack(honestPeers []uint64, cutoffIndex int,
+	cfg SurgeConfig) (*SurgeAttackOutcome, error) {
+
+	return surgeAttack(honestPeers, cutoffIndex, cfg.surgeCfg())
+}
+
+// SurgeAttackAllCutoffs returns the outcome of a surge attack for every
+// possible cutoff index, indexed by cutoff. Outcomes for cutoffs beneath the
+// config's band are nil.
+func SurgeAttackAllCutoffs(honestPeers []uint64,
+	cfg SurgeConfig) ([]*SurgeAttackOutcome, error) {
+
+	return surgeAttackAllCutoffs(honestPeers, cfg.surgeCfg())
+}
+
+// GeneralJam models plainly general jamming a node with the honest peers
+// provided for its revenue period, as a baseline for the other attacks.
+func GeneralJam(honestPeers []uint64,
+	cfg SurgeConfig) (*GeneralJamOutcome, error) {
+
+	return generalJam(honestPeers, cfg.surgeCfg())
+}
+
+// ParetoFrontier returns the surge attacks against a node with the honest
+// peers provided that are not dominated by any other attack, in order of
+// increasing cost and damage.
+func ParetoFrontier(honestPeers []uint64,
+	cfg SurgeConfig) ([]FrontierPoint, error) {
+
+	return paretoFrontier(honestPeers, cfg.surgeCfg())
+}
+
+// LadderScenario describes a single laddering attack to be evaluated: the
+// network that the attack is performed on and the attacker's choices.
+type LadderScenario struct {
+	// Config describes the network that the attack is performed on.
+	Config Config
+
+	// AttackerPayment is the amount that the attacker pays to build
+	// reputation.
+	AttackerPayment uint64
+
+	// CltvTotal is the total cltv of the attacker's HTLCs.
+	CltvTotal uint64
+}
+
+// scenario converts the scenario to the internal scenario of the model.
+func (s LadderScenario) scenario() ladderScenario {
+	return ladderScenario{
+		cfg:             s.Config.ladderCfg(),
+		attackerPayment: s.AttackerPayment,
+		cltvTotal:       s.CltvTotal,
+	}
+}
+
+// CorpusComparison describes the change in effective attacks between two
+// corpora of scenarios.
+type CorpusComparison struct {
+	// Added is the set of effective attacks that are only present in the
+	// second corpus.
+	Added []LadderScenario
+
+	// Removed is the set of effective attacks that are only present in
+	// the first corpus.
+	Removed []LadderScenario
+}
+
+// CompareCorpora evaluates two corpora of scenarios with the params provided,
+// returning the effective attacks that have been introduced and closed
+// between the first and second corpus.
+func CompareCorpora(before, after []LadderScenario,
+	params Params) CorpusComparison {
+
+	comparison := compareCorpora(
+		ladderScenarios(before), ladderScenarios(after), params,
+	)
+
+	return CorpusComparison{
+		Added:   matchScenarios(after, comparison.added),
+		Removed: matchScenarios(before, comparison.removed),
+	}
+}
+
+// ladderScenarios converts a corpus to the internal scenarios of the model.
+func ladderScenarios(corpus []LadderScenario) []ladderScenario {
+	scenarios := make([]ladderScenario, len(corpus))
+	for i, scenario := range corpus {
+		scenarios[i] = scenario.scenario()
+	}
+
+	return scenarios
+}
+
+// matchScenarios returns the first scenario in the corpus that matches each
+// of the internal scenarios provided, in the same order.
+func matchScenarios(corpus []LadderScenario,
+	scenarios []ladderScenario) []LadderScenario {
+
+	var matched []LadderScenario
+	for _, scenario := range scenarios {
+		for _, candidate := range corpus {
+			converted := []ladderScenario{candidate.scenario()}
+			if containsScenario(converted, scenario) {
+				matched = append(matched, candidate)
+				break
+			}
+		}
+	}
+
+	return matched
+}
+
+// NetworkNode describes a single node in a network snapshot, along with the
+// contexts in which it may be attacked.
+type NetworkNode struct {
+	// Revenue is the node's revenue over the revenue period, which is used
+	// to weight its contribution to the network's exposure.
+	Revenue uint64
+
+	// HonestPeers is the reputation of each of the node's peers, which is
+	// used to evaluate surge attacks against the node.
+	HonestPeers []uint64
+
+	// Ladders is the set of routes in which the node is the target of a
+	// laddering attack.
+	Ladders []LadderScenario
+}
+
+// NetworkSnapshot describes the nodes in a network at a point in time.
+type NetworkSnapshot struct {
+	Nodes []NetworkNode
+}
+
+// NetworkExposureScore summarizes the systemic exposure of a network to
+// attacks as the fraction of the network's revenue that is earned by nodes
+// that can be attacked within the budget provided.
+func NetworkExposureScore(snapshot NetworkSnapshot, budget uint64) (float64,
+	error) {
+
+	nodes := make([]networkNode, len(snapshot.Nodes))
+	for i, node := range snapshot.Nodes {
+		nodes[i] = networkNode{
+			revenue:     node.Revenue,
+			honestPeers: node.HonestPeers,
+			ladders:     ladderScenarios(node.Ladders),
+		}
+	}
+
+	return networkExposureScore(networkSnapshot{nodes: nodes}, budget)
+}
+
+// SurgeTarget describes a node that may be vulnerable to surge attacks.
+type SurgeTarget struct {
+	// HonestPeers is the reputation of each of the node's peers.
+	HonestPeers []uint64
+
+	// Config holds the protective mechanisms that the node has in place.
+	Config SurgeConfig
+}
+
+// CheapestDefense evaluates each of the levers provided against the target,
+// returning the cheapest lever that makes the target non-attackable for an
+// attacker with the budget provided. A false boolean is returned if none of
+// the levers protect the target.
+func CheapestDefense(target SurgeTarget, levers []DefenseLever,
+	budget uint64) (DefenseLever, bool, error) {
+
+	return cheapestDefense(surgeTarget{
+		honestPeers: target.HonestPeers,
+		cfg:         target.Config.surgeCfg(),
+	}, levers, budget)
+}
@@ -0,0 +1,184 @@
+package reputationfuzz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewLadderingAttack tests that the exported config produces the same
+// attack as the internal configuration that it mirrors.
+func TestNewLadderingAttack(t *testing.T) {
+	cfg := Config{
+		FirstNodeTraffic: 120_000,
+		TrafficFlows: []TrafficFlow{
+			{PortionBasisPoints: 10_000},
+			{PortionBasisPoints: 1_000},
+			{PortionBasisPoints: 2_500},
+			{PortionBasisPoints: 5_000},
+		},
+	}
+
+	attack, err := NewLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	internal, err := newLadderingAttack(newScenario(
+		120_000, []uint8{100, 10, 25, 50}, 30_000, 300,
+	).cfg)
+	require.NoError(t, err)
+	require.Equal(t, internal.channels, attack.channels)
+
+	endorsed, err := attack.TotalEndorsedOnTarget(30_000, 300)
+	require.NoError(t, err)
+	require.EqualValues(t, 10, endorsed)
+	require.False(t, attack.Outcome(endorsed, 300).Effective(30_000))
+
+	// Config validation is shared with the internal constructor.
+	_, err = NewLadderingAttack(Config{FirstNodeTraffic: 120_000})
+	require.Error(t, err)
+}
+
+// TestSurgeAttackAPI tests that the exported surge attack matches the
+// internal model for the same configuration.
+func TestSurgeAttackAPI(t *testing.T) {
+	peers := []uint64{20_000, 1_000_000, 172_145_567, 184_831_534}
+
+	outcome, err := SurgeAttack(peers, 2, SurgeConfig{
+		ReputationFloor: 500_000,
+	})
+	require.NoError(t, err)
+
+	internal, err := surgeAttack(peers, 2, surgeAttackCfg{
+		reputationFloor: 500_000,
+	})
+	require.NoError(t, err)
+	require.Equal(t, internal, outcome)
+}
+
+// TestParetoFrontierAPI tests that the exported frontier matches the internal
+// model for the same configuration.
+func TestParetoFrontierAPI(t *testing.T) {
+	peers := equalPeers(10, 12_000_000_000)
+
+	frontier, err := ParetoFrontier(peers, SurgeConfig{BandLowIndex: 2})
+	require.NoError(t, err)
+	require.NotEmpty(t, frontier)
+
+	internal, err := paretoFrontier(peers, surgeAttackCfg{
+		bandLowIndex: 2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, internal, frontier)
+}
+
+// TestCompareCorporaAPI tests that the exported corpus comparison reports the
+// caller's scenarios that were added and removed.
+func TestCompareCorporaAPI(t *testing.T) {
+	scenario := func(weight uint16, portions ...uint16) LadderScenario {
+		flows := make([]TrafficFlow, len(portions))
+		for i, portion := range portions {
+			flows[i] = TrafficFlow{
+				PortionBasisPoints: portion,
+			}
+		}
+
+		return LadderScenario{
+			Config: Config{
+				FirstNodeTraffic:     10_000_000_000,
+				TrafficFlows:         flows,
+				LastHopWeightPercent: weight,
+			},
+			AttackerPayment: 10_000_000_000,
+			CltvTotal:       1000,
+		}
+	}
+
+	var (
+		closed      = scenario(50, 10_000, 5_000, 2_500, 5_000)
+		introduced  = scenario(50, 10_000, 2_500, 2_500, 10_000)
+		ineffective = scenario(0, 10_000, 10_000, 10_000, 10_000)
+	)
+
+	comparison := CompareCorpora(
+		[]LadderScenario{closed, ineffective},
+		[]LadderScenario{ineffective, introduced, introduced},
+		Params{},
+	)
+	require.Equal(t, []LadderScenario{introduced}, comparison.Added)
+	require.Equal(t, []LadderScenario{closed}, comparison.Removed)
+}
+
+// TestNetworkExposureScoreAPI tests that the exported exposure score matches
+// the internal model for the same network.
+func TestNetworkExposureScoreAPI(t *testing.T) {
+	ladder := LadderScenario{
+		Config: Config{
+			FirstNodeTraffic: 1_000_000,
+			TrafficFlows: []TrafficFlow{
+				{PortionBasisPoints: 10_000},
+				{PortionBasisPoints: 5_000},
+				{PortionBasisPoints: 5_000},
+				{PortionBasisPoints: 2_500},
+			},
+			LastHopWeightPercent: 50,
+		},
+		AttackerPayment: 1_000_000,
+		CltvTotal:       1000,
+	}
+
+	snapshot := NetworkSnapshot{
+		Nodes: []NetworkNode{
+			{
+				Revenue:     10_000_000_000,
+				HonestPeers: equalPeers(10, 12_000_000_000),
+			},
+			{
+				Revenue: 10_000_000_000,
+				Ladders: []LadderScenario{ladder},
+			},
+		},
+	}
+
+	// Only the ladder is affordable with a small budget, while a larger
+	// budget can surge the first node as well.
+	score, err := NetworkExposureScore(snapshot, 1_000_000)
+	require.NoError(t, err)
+	require.Equal(t, 0.5, score)
+
+	score, err = NetworkExposureScore(snapshot, 2_000_000_000)
+	require.NoError(t, err)
+	require.Equal(t, 1.0, score)
+}
+
+// TestCheapestDefenseAPI tests that the exported defense search recommends
+// the cheapest lever that protects a target configured through the exported
+// surge config.
+func TestCheapestDefenseAPI(t *testing.T) {
+	target := SurgeTarget{
+		HonestPeers: equalPeers(10, 12_000_000_000),
+	}
+	levers := []DefenseLever{
+		AllowlistLever(10, 500),
+		FeeIncreaseLever(10, 100),
+		AddPeerLever(1_000_000_000, 50),
+	}
+
+	lever, found, err := CheapestDefense(target, levers, 2_000_000_000)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "raise fees", lever.Name)
+
+	// If every peer is already allowlisted, there's nothing to attack and
+	// the cheapest lever protects the target. Adding a peer extends the
+	// allowlist to cover it.
+	allowlist := make([]bool, len(target.HonestPeers))
+	for i := range allowlist {
+		allowlist[i] = true
+	}
+	target.Config.Allowlist = allowlist
+
+	lever, found, err = CheapestDefense(target, levers, 2_000_000_000)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "add peer", lever.Name)
+}
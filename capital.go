@@ -0,0 +1,28 @@
+package reputationfuzz
+
+const (
+	// secondsPerWeek is the number of seconds in a week.
+	secondsPerWeek uint64 = 7 * 24 * 60 * 60
+
+	// secondsPerYear is the number of seconds in a (non-leap) year.
+	secondsPerYear uint64 = 365 * 24 * 60 * 60
+)
+
+// capitalCost returns the opportunity cost in msat of locking up the liquidity
+// provided for holdBlocks blocks, given an annualized rate of return on that
+// capital expressed in basis points. Capital that is held in jamming HTLCs
+// can't be put to use elsewhere, so very long holds of large amounts are
+// expensive for an attacker even if they don't burn much reputation. The hold
+// is converted to years using the params' expected block time.
+func capitalCost(lockedLiquidity, holdBlocks uint64,
+	annualRateBasisPoints uint16, params Params) uint64 {
+
+	// Convert the capital locked to msat-years first, so that amounts
+	// which overflow saturate at the maximum cost rather than being
+	// divided back down.
+	lockedYears := mulDiv(
+		lockedLiquidity, holdBlocks, params.blocksPerYear(),
+	)
+
+	return mulDiv(lockedYears, uint64(annualRateBasisPoints), basisPoints)
+}
@@ -0,0 +1,130 @@
+package reputationfuzz
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestCapitalCost tests calculating the opportunity cost of locked capital.
+func TestCapitalCost(t *testing.T) {
+	blocksPerYear := Params{}.blocksPerYear()
+	require.EqualValues(t, 365*144, blocksPerYear)
+
+	// Locking up capital for a year forgoes the full annual return.
+	require.EqualValues(t, 50_000_000, capitalCost(
+		1_000_000_000, blocksPerYear, 500, Params{},
+	))
+
+	// A week is a fraction of the year's return.
+	require.EqualValues(t, 1008, Params{}.blocksPerWeek())
+	require.EqualValues(t, 958_904, capitalCost(
+		1_000_000_000, Params{}.blocksPerWeek(), 500, Params{},
+	))
+
+	// With five minute blocks, the same number of blocks is half as
+	// long, so it forgoes half of the return.
+	fastBlocks := Params{SecondsPerBlock: 5 * 60}
+	require.EqualValues(t, 2*blocksPerYear, fastBlocks.blocksPerYear())
+	require.EqualValues(t, 25_000_000, capitalCost(
+		1_000_000_000, blocksPerYear, 500, fastBlocks,
+	))
+
+	// A zero rate has no opportunity cost.
+	require.Zero(t, capitalCost(1_000_000_000, blocksPerYear, 0, Params{}))
+
+	// Large amounts and holds saturate rather than overflowing.
+	require.EqualValues(t, uint64(math.MaxUint64), capitalCost(
+		math.MaxUint64, math.MaxUint64, math.MaxUint16, Params{},
+	))
+}
+
+// TestCapitalCostEffective tests that the opportunity cost of the attacker's
+// locked capital is charged to both the ladder and a direct attack on the
+// target, because both lock up the same liquidity for the same hold.
+func TestCapitalCostEffective(t *testing.T) {
+	scenario := newScenario(
+		1_000_000_000, []uint8{100, 50, 50, 10}, 1_000_000_000, 1000,
+	)
+
+	attack, err := newLadderingAttack(scenario.cfg)
+	require.NoError(t, err)
+
+	endorsed, err := attack.TotalEndorsedOnTarget(
+		scenario.attackerPayment, scenario.cltvTotal,
+	)
+	require.NoError(t, err)
+
+	// Without an opportunity cost, the ladder is cheaper than attacking
+	// the target directly.
+	outcome := attack.Outcome(endorsed, scenario.cltvTotal)
+	require.Zero(t, outcome.capitalCost)
+	require.True(t, outcome.Effective(scenario.attackerPayment))
+	require.EqualValues(t, 94_199_733,
+		outcome.Margin(scenario.attackerPayment))
+
+	// A high rate of return makes both attacks more expensive by the
+	// same amount, so the ladder is still just as much cheaper.
+	scenario.cfg.capitalRateBasisPoints = 50_000
+
+	attack, err = newLadderingAttack(scenario.cfg)
+	require.NoError(t, err)
+
+	withCapital := attack.Outcome(endorsed, scenario.cltvTotal)
+	require.EqualValues(t, 10_855, withCapital.capitalCost)
+	require.Equal(t, outcome.targetCost+10_855, withCapital.targetCost)
+	require.Equal(t,
+		outcome.ladderCost(scenario.attackerPayment)+10_855,
+		withCapital.ladderCost(scenario.attackerPayment),
+	)
+	require.True(t, withCapital.Effective(scenario.attackerPayment))
+	require.EqualValues(t, 94_199_733,
+		withCapital.Margin(scenario.attackerPayment))
+}
+
+// TestTotalCapital tests that the total capital that an attacker needs
+// includes the liquidity locked in its jamming HTLCs.
+func TestTotalCapital(t *testing.T) {
+	scenario := newScenario(
+		1_000_000_000, []uint8{100, 100, 100, 100}, 1_000_000_000, 300,
+	)
+	scenario.cfg.trafficFlows[1].portionBasisPoints = 9_999
+	scenario.cfg.lastHopWeightPercent = 50
+
+	attack, err := newLadderingAttack(scenario.cfg)
+	require.NoError(t, err)
+
+	endorsed, err := attack.TotalEndorsedOnTarget(
+		scenario.attackerPayment, scenario.cltvTotal,
+	)
+	require.NoError(t, err)
+	require.EqualValues(t, 458_333, endorsed)
+
+	// The attacker needs its payment to build reputation plus the value
+	// that it holds endorsed on the target.
+	outcome := attack.Outcome(endorsed, scenario.cltvTotal)
+	require.EqualValues(t, 1_000_458_333,
+		outcome.TotalCapital(scenario.attackerPayment))
+	require.Contains(t, outcome.String(), "attacker locked: 458333")
+
+	// A surge attacker needs its payment to inflate the threshold plus
+	// the liquidity that it locks up to general jam the link.
+	surge, err := SurgeAttack(
+		[]uint64{12_000_000, 24_000_000}, 0, SurgeConfig{
+			JamLiquidity: 5_000_000,
+		},
+	)
+	require.NoError(t, err)
+	require.EqualValues(t, 9_000_000, surge.attackerPays())
+	require.EqualValues(t, 14_000_000, surge.TotalCapital())
+	require.Contains(t, surge.String(), "total capital: 14000000")
+
+	// Without a configured liquidity, the attacker fills every slot on
+	// the link with a minimum sized HTLC.
+	surge, err = SurgeAttack(
+		[]uint64{12_000_000, 24_000_000}, 0, SurgeConfig{},
+	)
+	require.NoError(t, err)
+	require.EqualValues(t, 9_483_000, surge.TotalCapital())
+}
@@ -0,0 +1,130 @@
+package reputationfuzz
+
+// combinedOutcome is the outcome of a two stage attack where an attacker first
+// ladders up reputation with a target node, and then uses that reputation to
+// fund a surge attack against the target's peer.
+type combinedOutcome struct {
+	// ladder is the outcome of the laddering stage of the attack.
+	ladder AttackOutcome
+
+	// ladderPayment is the amount that the attacker paid to ladder up
+	// reputation.
+	ladderPayment uint64
+
+	// ladderedReputation is the reputation that the attacker holds at the
+	// target as a result of laddering.
+	ladderedReputation uint64
+
+	// surge is the outcome of the surge stage of the attack, with the
+	// attacker's laddered reputation contributing as a peer.
+	surge *SurgeAttackOutcome
+
+	// surgeOnly is the outcome of the surge attack without any laddered
+	// reputation.
+	surgeOnly *SurgeAttackOutcome
+}
+
+// cost returns the total amount that the attacker pays for both stages of the
+// attack, including the fees that it burns and the capital cost that it
+// incurs laddering.
+func (c *combinedOutcome) cost() uint64 {
+	return saturatingAdd(
+		c.ladder.ladderCost(c.ladderPayment), c.surge.attackerPays(),
+	)
+}
+
+// cheaperThanSurge returns a boolean indicating whether the two stage attack
+// is cheaper than performing the surge attack alone.
+func (c *combinedOutcome) cheaperThanSurge() bool {
+	return c.cost() < c.surgeOnly.attackerPays()
+}
+
+// cheaperThanDirect returns a boolean indicating whether the two stage attack
+// is cheaper than acquiring the reputation directly with the target and then
+// performing the surge attack.
+func (c *combinedOutcome) cheaperThanDirect() bool {
+	direct := saturatingAdd(c.ladder.targetCost, c.surge.attackerPays())
+	return c.cost() < direct
+}
+
+// cheaper returns a boolean indicating whether the two stage attack is
+// cheaper than either the surge attack alone or acquiring reputation with the
+// target directly.
+func (c *combinedOutcome) cheaper() bool {
+	return c.cheaperThanSurge() && c.cheaperThanDirect()
+}
+
+// cheaperThanBest returns a boolean indicating whether the two stage attack is
+// cheaper than the best standalone attack: acquiring reputation with the target
+// directly, or the cheapest successful surge attack at any cutoff. A nil best
+// surge indicates that no standalone surge attack is successful.
+func (c *combinedOutcome) cheaperThanBest(bestSurge *SurgeAttackOutcome) bool {
+	if !c.cheaperThanDirect() {
+		return false
+	}
+
+	return bestSurge == nil || c.cost() < bestSurge.attackerPays()
+}
+
+// ladderThenSurge bridges a laddering attack into a surge attack. The amount
+// that the attacker is able to get endorsed on the target is converted into
+// the reputation that backs it for the hold on the target's outgoing link, and
+// that reputation is added to the surge target's peers as an attacker
+// controlled peer that is never cut off. This accounts for the reputation that
+// the attacker acquired by laddering when calculating the surge's threshold
+// and attacker payment. The cutoff index refers to the sorted set of honest
+// peers.
+func ladderThenSurge(ladder *LadderingAttack, attackerPayment, cltvTotal uint64,
+	honestPeers []uint64, cutoffIndex int,
+	cfg surgeAttackCfg) (*combinedOutcome, error) {
+
+	totalEndorsed, err := ladder.TotalEndorsedOnTarget(
+		attackerPayment, cltvTotal,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	surgeOnly, err := surgeAttack(honestPeers, cutoffIndex, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		laddered = budgetForEndorsedValue(
+			totalEndorsed, ladder.targetHold(cltvTotal),
+			ladder.params,
+		)
+		peers = append(append([]uint64(nil), honestPeers...), laddered)
+
+		// The attacker's peer can't be cut off, because the attacker
+		// controls it.
+		attackerCfg = cfg.withPeer(len(honestPeers), true)
+	)
+
+	// Our cutoff index refers to the sorted set of honest peers, so if
+	// the attacker's peer sorts beneath the cutoff we need to shift the
+	// index to cut off the same set of honest peers.
+	sorted, err := cfg.sortedPeers(outgoingPeers(honestPeers))
+	if err != nil {
+		return nil, err
+	}
+
+	attackerCutoff := cutoffIndex
+	if laddered <= sorted[cutoffIndex].reputation() {
+		attackerCutoff++
+	}
+
+	surge, err := surgeAttack(peers, attackerCutoff, attackerCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &combinedOutcome{
+		ladder:             ladder.Outcome(totalEndorsed, cltvTotal),
+		ladderPayment:      attackerPayment,
+		ladderedReputation: laddered,
+		surge:              surge,
+		surgeOnly:          surgeOnly,
+	}, nil
+}
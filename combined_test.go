@@ -0,0 +1,120 @@
+package reputationfuzz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestLadderThenSurge tests bridging reputation acquired by laddering into a
+// surge attack against the target's peer.
+func TestLadderThenSurge(t *testing.T) {
+	scenario := newScenario(
+		1_000_000, []uint8{100, 50, 50, 25}, 1_000_000, 1000,
+	)
+	scenario.cfg.lastHopWeightPercent = 50
+
+	ladder, err := newLadderingAttack(scenario.cfg)
+	require.NoError(t, err)
+
+	// A surge alone must pay 2e9 to cut off every peer, which is the
+	// cost that the laddered reputation is measured against.
+	peers := equalPeers(10, 12_000_000_000)
+
+	outcome, err := ladderThenSurge(
+		ladder, scenario.attackerPayment, scenario.cltvTotal, peers, 9,
+		surgeAttackCfg{},
+	)
+	require.NoError(t, err)
+
+	// The attacker gets 135 endorsed on the target, which is backed by
+	// 756_000 reputation for the 840 blocks that it is held there.
+	require.EqualValues(t, 756_000, outcome.ladderedReputation)
+	require.True(t, outcome.ladder.Effective(scenario.attackerPayment))
+
+	// The attacker's peer contributes to the node's revenue, so the
+	// attacker needs to pay less to surge.
+	require.EqualValues(t, 2_000_000_000, outcome.surgeOnly.attackerPays())
+	require.EqualValues(t, 63_000, outcome.surge.attackRevenue)
+	require.EqualValues(t, 2_000_000_000-63_000,
+		outcome.surge.attackerPays())
+
+	// Laddering is cheaper than acquiring the reputation directly, but
+	// the reputation it earns doesn't offset the cost of laddering so
+	// it's cheaper to just surge.
+	require.True(t, outcome.cheaperThanDirect())
+	require.False(t, outcome.cheaperThanSurge())
+	require.False(t, outcome.cheaper())
+
+	// If laddering were to provide enough reputation to offset its cost,
+	// the combined attack is cheaper than either alone.
+	outcome.ladderPayment = 50_000
+	require.True(t, outcome.cheaperThanSurge())
+	require.True(t, outcome.cheaper())
+}
+
+// TestCombinedCheaperThanBest tests comparing a combined attack with the best
+// standalone attack.
+func TestCombinedCheaperThanBest(t *testing.T) {
+	outcome := &combinedOutcome{
+		ladder:        AttackOutcome{targetCost: 10_000},
+		ladderPayment: 1_000,
+		surge: &SurgeAttackOutcome{
+			cutoffReputation: 12_000,
+			peaceRevenue:     10_000,
+		},
+	}
+
+	// The combined attack costs 3_000, which is cheaper than acquiring
+	// the reputation directly and surging for 2_000 more.
+	require.True(t, outcome.cheaperThanDirect())
+	require.True(t, outcome.cheaperThanBest(nil))
+
+	bestSurge := &SurgeAttackOutcome{
+		cutoffReputation: 14_000,
+		peaceRevenue:     10_000,
+	}
+	require.True(t, outcome.cheaperThanBest(bestSurge))
+
+	// A standalone surge at a cheaper cutoff beats the combined attack.
+	bestSurge.cutoffReputation = 12_500
+	require.False(t, outcome.cheaperThanBest(bestSurge))
+
+	// So does acquiring reputation directly.
+	outcome.ladder.targetCost = 500
+	require.False(t, outcome.cheaperThanBest(nil))
+}
+
+// TestCombinedCostBurnedFees tests that the combined attack is charged for
+// the fees that it burns and the capital that it locks up laddering.
+func TestCombinedCostBurnedFees(t *testing.T) {
+	outcome := &combinedOutcome{
+		ladder: AttackOutcome{
+			targetCost: 3_500,
+			// Half of the payment settles, burning a 10% fee, and
+			// locking up capital to jam the target costs 200.
+			firstHopFees:  FeePolicy{PPM: 100_000},
+			settlePercent: 50,
+			capitalCost:   200,
+		},
+		ladderPayment: 1_000,
+		surge: &SurgeAttackOutcome{
+			cutoffReputation: 12_000,
+			peaceRevenue:     10_000,
+		},
+	}
+
+	// The attacker pays 1_000 + 50 + 200 to ladder and 2_000 to surge.
+	require.EqualValues(t, 3_250, outcome.cost())
+
+	// Acquiring the reputation directly costs 5_500, which is still more
+	// expensive.
+	require.True(t, outcome.cheaperThanDirect())
+
+	// Without the burned fees and capital cost, the combined attack's
+	// 3_000 would be cheaper than acquiring the reputation directly for
+	// 1_100 and surging, but it isn't once they're charged.
+	outcome.ladder.targetCost = 1_100
+	require.False(t, outcome.cheaperThanDirect())
+	require.False(t, outcome.cheaperThanBest(nil))
+}
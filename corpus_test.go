@@ -0,0 +1,172 @@
+package reputationfuzz
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ladderSeedDir is the directory that holds seed corpus files for
+// FuzzLadderAttack.
+var ladderSeedDir = filepath.Join("testdata", "ladder_seeds")
+
+// ladderSeedHeaderLen is the length of the fixed size fields at the start of
+// an encoded ladder seed: firstNodeTraffic, attackerPayment and cltvTotal as
+// little endian uint64s, followed by a single networkLength byte.
+const ladderSeedHeaderLen = 8*3 + 1
+
+var errMalformedSeed = errors.New("malformed seed")
+
+// ladderSeed holds the arguments to FuzzLadderAttack, in the order that the
+// fuzz function accepts them.
+type ladderSeed struct {
+	firstNodeTraffic   uint64
+	attackerPayment    uint64
+	cltvTotal          uint64
+	networkLength      uint8
+	networkDescription []byte
+}
+
+// encode serializes the seed in the fuzz function's argument order, with the
+// network description making up the remainder of the encoding.
+func (s ladderSeed) encode() []byte {
+	length := ladderSeedHeaderLen + len(s.networkDescription)
+
+	encoded := make([]byte, 0, length)
+	encoded = binary.LittleEndian.AppendUint64(encoded, s.firstNodeTraffic)
+	encoded = binary.LittleEndian.AppendUint64(encoded, s.attackerPayment)
+	encoded = binary.LittleEndian.AppendUint64(encoded, s.cltvTotal)
+	encoded = append(encoded, s.networkLength)
+
+	return append(encoded, s.networkDescription...)
+}
+
+// decodeLadderSeed deserializes a seed that was encoded with encode.
+func decodeLadderSeed(encoded []byte) (ladderSeed, error) {
+	if len(encoded) < ladderSeedHeaderLen {
+		return ladderSeed{}, fmt.Errorf("%w: length: %v < %v",
+			errMalformedSeed, len(encoded), ladderSeedHeaderLen)
+	}
+
+	return ladderSeed{
+		firstNodeTraffic: binary.LittleEndian.Uint64(encoded[0:8]),
+		attackerPayment:  binary.LittleEndian.Uint64(encoded[8:16]),
+		cltvTotal:        binary.LittleEndian.Uint64(encoded[16:24]),
+		networkLength:    encoded[24],
+		networkDescription: append(
+			[]byte{}, encoded[ladderSeedHeaderLen:]...,
+		),
+	}, nil
+}
+
+// loadLadderSeeds reads every file in the directory provided as a ladder
+// seed. Files that can't be read or decoded are skipped and reported with the
+// log function provided, and a missing directory holds no seeds.
+func loadLadderSeeds(dir string,
+	logf func(format string, args ...any)) ([]ladderSeed, error) {
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var seeds []ladderSeed
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		encoded, err := os.ReadFile(path)
+		if err != nil {
+			logf("Skipping seed: %v: %v", path, err)
+			continue
+		}
+
+		seed, err := decodeLadderSeed(encoded)
+		if err != nil {
+			logf("Skipping seed: %v: %v", path, err)
+			continue
+		}
+
+		seeds = append(seeds, seed)
+	}
+
+	return seeds, nil
+}
+
+// addLadderSeeds adds each seed in the ladder seed directory to the fuzz
+// test's corpus.
+func addLadderSeeds(f *testing.F) {
+	seeds, err := loadLadderSeeds(ladderSeedDir, f.Logf)
+	require.NoError(f, err)
+
+	for _, seed := range seeds {
+		f.Add(
+			seed.firstNodeTraffic, seed.attackerPayment,
+			seed.cltvTotal, seed.networkLength,
+			seed.networkDescription,
+		)
+	}
+}
+
+// TestLoadLadderSeeds tests loading seeds from a directory, skipping files
+// that are malformed.
+func TestLoadLadderSeeds(t *testing.T) {
+	dir := t.TempDir()
+
+	seed := ladderSeed{
+		firstNodeTraffic:   120_000,
+		attackerPayment:    20_667,
+		cltvTotal:          300,
+		networkLength:      4,
+		networkDescription: encodePortions(10_000, 1_000, 2_500, 5_000),
+	}
+	require.NoError(t, os.WriteFile(
+		filepath.Join(dir, "valid"), seed.encode(), 0o600,
+	))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(dir, "truncated"), seed.encode()[:10], 0o600,
+	))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "nested"), 0o700))
+
+	var logged int
+	seeds, err := loadLadderSeeds(dir, func(string, ...any) {
+		logged++
+	})
+	require.NoError(t, err)
+	require.Equal(t, []ladderSeed{seed}, seeds)
+	require.Equal(t, 1, logged)
+
+	// A directory that doesn't exist has no seeds.
+	seeds, err = loadLadderSeeds(filepath.Join(dir, "missing"), t.Logf)
+	require.NoError(t, err)
+	require.Empty(t, seeds)
+
+	// The checked in seeds should all be well formed, and describe
+	// scenarios that the fuzz test will evaluate.
+	seeds, err = loadLadderSeeds(ladderSeedDir, func(format string,
+		args ...any) {
+
+		t.Errorf(format, args...)
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, seeds)
+
+	for _, seed := range seeds {
+		_, err := decodeLadderInputs(
+			seed.firstNodeTraffic, seed.attackerPayment,
+			seed.cltvTotal, seed.networkLength,
+			seed.networkDescription,
+		)
+		require.NoError(t, err)
+	}
+}
@@ -0,0 +1,195 @@
+package reputationfuzz
+
+// surgeTarget describes a node that may be targeted by a surge attack.
+type surgeTarget struct {
+	// honestPeers is the reputation of each of the node's peers.
+	honestPeers []uint64
+
+	// cfg holds the protective mechanisms that the node has in place.
+	cfg surgeAttackCfg
+}
+
+// attackable returns a boolean indicating whether any cutoff of the target's
+// peers is a successful surge attack that the attacker can afford with the
+// budget provided. Cutoffs refer to the target's grouped peers, and cutoffs
+// beneath its band aren't considered.
+func (s surgeTarget) attackable(budget uint64) (bool, error) {
+	// A node without peers has nothing for a surge to cut off.
+	if len(s.honestPeers) == 0 {
+		return false, nil
+	}
+
+	outcomes, err := surgeAttackAllCutoffs(s.honestPeers, s.cfg)
+	if err != nil {
+		return false, err
+	}
+
+	for _, outcome := range outcomes {
+		// Cutoffs beneath the config's band aren't valid attacks.
+		if outcome == nil {
+			continue
+		}
+
+		if outcome.attackerPays() > budget {
+			continue
+		}
+
+		success, err := outcome.Success()
+		if err != nil {
+			return false, err
+		}
+
+		if success {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// DefenseLever is an intervention that a defender can make to protect a node
+// from surge attacks.
+type DefenseLever struct {
+	// Name describes the lever.
+	Name string
+
+	// Cost is the cost to the defender of pulling the lever.
+	Cost uint64
+
+	// apply returns the target once the lever has been pulled.
+	apply func(surgeTarget) surgeTarget
+}
+
+// FeeIncreaseLever raises the node's fees by the percentage provided, which
+// increases the revenue (and thus reputation) that every peer contributes.
+func FeeIncreaseLever(percent uint64, cost uint64) DefenseLever {
+	return DefenseLever{
+		Name: "raise fees",
+		Cost: cost,
+		apply: func(target surgeTarget) surgeTarget {
+			peers := make([]uint64, len(target.honestPeers))
+			for i, reputation := range target.honestPeers {
+				peers[i] = mulDiv(
+					reputation, saturatingAdd(100, percent),
+					100,
+				)
+			}
+			target.honestPeers = peers
+
+			return target
+		},
+	}
+}
+
+// AddPeerLever adds a new peer with the reputation provided to the node.
+func AddPeerLever(reputation uint64, cost uint64) DefenseLever {
+	return DefenseLever{
+		Name: "add peer",
+		Cost: cost,
+		apply: func(target surgeTarget) surgeTarget {
+			peers := make([]uint64, 0, len(target.honestPeers)+1)
+			peers = append(peers, target.honestPeers...)
+			target.honestPeers = append(peers, reputation)
+
+			// Any per-peer config needs to cover the new peer.
+			target.cfg = target.cfg.withPeer(len(peers), false)
+
+			return target
+		},
+	}
+}
+
+// ReputationFloorLever protects peers with reputation at or above the floor
+// provided from being cut off, buffering them against the attacker raising
+// the node's threshold.
+func ReputationFloorLever(floor uint64, cost uint64) DefenseLever {
+	return DefenseLever{
+		Name: "reputation floor",
+		Cost: cost,
+		apply: func(target surgeTarget) surgeTarget {
+			target.cfg.reputationFloor = floor
+			return target
+		},
+	}
+}
+
+// ThresholdBufferLever sets the threshold that peers must clear to have good
+// reputation to the percentage of the node's revenue provided. A buffer above
+// 100% means that the attacker's payment has to overcome more than the node's
+// revenue, but peers that don't clear the buffer lose good reputation too.
+func ThresholdBufferLever(percent uint16, cost uint64) DefenseLever {
+	return DefenseLever{
+		Name: "threshold buffer",
+		Cost: cost,
+		apply: func(target surgeTarget) surgeTarget {
+			target.cfg.thresholdPercent = percent
+			return target
+		},
+	}
+}
+
+// AllowlistLever allowlists the node's most valuable peers, up to the count
+// provided.
+func AllowlistLever(count int, cost uint64) DefenseLever {
+	return DefenseLever{
+		Name: "allowlist",
+		Cost: cost,
+		apply: func(target surgeTarget) surgeTarget {
+			allowlist := make([]bool, len(target.honestPeers))
+			copy(allowlist, target.cfg.allowlist)
+
+			for n := count; n > 0; n-- {
+				best := -1
+				for i, reputation := range target.honestPeers {
+					if allowlist[i] {
+						continue
+					}
+
+					if best == -1 ||
+						reputation > target.honestPeers[best] {
+
+						best = i
+					}
+				}
+
+				if best == -1 {
+					break
+				}
+				allowlist[best] = true
+			}
+			target.cfg.allowlist = allowlist
+
+			return target
+		},
+	}
+}
+
+// cheapestDefense evaluates each of the levers provided against the target,
+// returning the cheapest lever that makes the target non-attackable for an
+// attacker with the budget provided. A false boolean is returned if none of
+// the levers protect the target.
+func cheapestDefense(target surgeTarget, levers []DefenseLever,
+	budget uint64) (DefenseLever, bool, error) {
+
+	var (
+		cheapest DefenseLever
+		found    bool
+	)
+
+	for _, lever := range levers {
+		if found && lever.Cost >= cheapest.Cost {
+			continue
+		}
+
+		attackable, err := lever.apply(target).attackable(budget)
+		if err != nil {
+			return DefenseLever{}, false, err
+		}
+
+		if !attackable {
+			cheapest, found = lever, true
+		}
+	}
+
+	return cheapest, found, nil
+}
@@ -0,0 +1,119 @@
+package reputationfuzz
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestCheapestDefense tests that the cheapest lever that protects a crafted
+// target from surge attacks is recommended.
+func TestCheapestDefense(t *testing.T) {
+	// Cutting off all of the target's peers costs exactly the attacker's
+	// budget of 2e9, so any lever that raises that cost at all protects
+	// the target.
+	peers := equalPeers(10, 12_000_000_000)
+
+	var (
+		target = surgeTarget{
+			honestPeers: peers,
+		}
+		budget uint64 = 2_000_000_000
+	)
+
+	attackable, err := target.attackable(budget)
+	require.NoError(t, err)
+	require.True(t, attackable)
+
+	levers := []DefenseLever{
+		AllowlistLever(10, 500),
+		ReputationFloorLever(10_000_000_000, 300),
+		// A threshold of 125% of the node's revenue is more than any
+		// of its peers can clear, so there are no peers with good
+		// reputation left to cut off.
+		ThresholdBufferLever(125, 200),
+		// Raising fees by 10% means the attacker needs to pay 2.2e9 to
+		// cut off peers, which is beyond their budget.
+		FeeIncreaseLever(10, 100),
+		// A small peer doesn't change the attacker's cost enough.
+		AddPeerLever(1_000_000_000, 50),
+		// Allowlisting half of the peers leaves the rest exposed.
+		AllowlistLever(5, 10),
+	}
+
+	lever, found, err := cheapestDefense(target, levers, budget)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "raise fees", lever.Name)
+	require.EqualValues(t, 100, lever.Cost)
+
+	// Pulling levers doesn't mutate the target.
+	require.Equal(t, peers, target.honestPeers)
+	require.Empty(t, target.cfg.allowlist)
+
+	// An attacker with a larger budget can't be stopped by fees, so the
+	// threshold buffer is the next cheapest option.
+	lever, found, err = cheapestDefense(target, levers, budget*2)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "threshold buffer", lever.Name)
+
+	// Without the buffer, the reputation floor is recommended.
+	lever, found, err = cheapestDefense(
+		target, append(levers[:2:2], levers[3:]...), budget*2,
+	)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "reputation floor", lever.Name)
+
+	// If none of the levers protect the target, none are recommended.
+	_, found, err = cheapestDefense(target, levers[3:], budget*2)
+	require.NoError(t, err)
+	require.False(t, found)
+}
+
+// TestAttackableGroupedBand tests that targets with grouped peers or a band
+// of peers that can be cut off are evaluated over their valid cutoffs.
+func TestAttackableGroupedBand(t *testing.T) {
+	peers := equalPeers(10, 12_000_000_000)
+
+	// Grouping two of the channels leaves nine peers, so there is no
+	// cutoff for the tenth channel.
+	grouped := surgeTarget{
+		honestPeers: peers,
+		cfg: surgeAttackCfg{
+			peerGroups: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 8},
+		},
+	}
+
+	_, err := grouped.attackable(math.MaxUint64)
+	require.NoError(t, err)
+
+	// Cutoffs beneath the band aren't valid attacks, so they're skipped.
+	banded := surgeTarget{
+		honestPeers: peers,
+		cfg: surgeAttackCfg{
+			bandLowIndex: 1,
+		},
+	}
+
+	attackable, err := banded.attackable(math.MaxUint64)
+	require.NoError(t, err)
+	require.True(t, attackable)
+
+	attackable, err = banded.attackable(0)
+	require.NoError(t, err)
+	require.False(t, attackable)
+}
+
+// TestFeeIncreaseLeverSaturates tests that raising the fees of a peer with a
+// large reputation doesn't wrap around to a small reputation.
+func TestFeeIncreaseLeverSaturates(t *testing.T) {
+	target := FeeIncreaseLever(10, 100).apply(surgeTarget{
+		honestPeers: []uint64{math.MaxUint64 / 2, math.MaxUint64},
+	})
+
+	require.Greater(t, target.honestPeers[0], uint64(math.MaxUint64/2))
+	require.EqualValues(t, uint64(math.MaxUint64), target.honestPeers[1])
+}
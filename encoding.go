@@ -0,0 +1,152 @@
+package reputationfuzz
+
+import "encoding/json"
+
+// attackOutcomeJSON is the JSON representation of an AttackOutcome.
+type attackOutcomeJSON struct {
+	TargetReputation uint64 `json:"targetReputation"`
+	TargetThreshold  uint64 `json:"targetThreshold"`
+	ReputationChange uint64 `json:"reputationChange"`
+	TargetCost       uint64 `json:"targetCost"`
+	PurchaseCost     uint64 `json:"purchaseCost,omitempty"`
+	EndorsedSlots    uint64 `json:"endorsedSlots,omitempty"`
+	TargetSlots      uint64 `json:"targetSlots,omitempty"`
+
+	ProtectedSplit     bool   `json:"protectedSplit,omitempty"`
+	ProtectedShortfall uint64 `json:"protectedShortfall,omitempty"`
+
+	CapitalCost      uint64 `json:"capitalCost,omitempty"`
+	SettlePercent    uint8  `json:"settlePercent,omitempty"`
+	FirstHopBaseMsat uint64 `json:"firstHopBaseMsat,omitempty"`
+	FirstHopPPM      uint64 `json:"firstHopPPM,omitempty"`
+	EndorsedValue    uint64 `json:"endorsedValue,omitempty"`
+}
+
+// MarshalJSON encodes the outcome as JSON.
+func (a AttackOutcome) MarshalJSON() ([]byte, error) {
+	return json.Marshal(attackOutcomeJSON{
+		TargetReputation: a.targetReputation,
+		TargetThreshold:  a.targetThreshold,
+		ReputationChange: a.reputationChange,
+		TargetCost:       a.targetCost,
+		PurchaseCost:     a.purchaseCost,
+		EndorsedSlots:    a.endorsedSlots,
+		TargetSlots:      a.targetSlots,
+
+		ProtectedSplit:     a.protectedSplit,
+		ProtectedShortfall: a.protectedShortfall,
+
+		CapitalCost:      a.capitalCost,
+		SettlePercent:    a.settlePercent,
+		FirstHopBaseMsat: a.firstHopFees.BaseMsat,
+		FirstHopPPM:      a.firstHopFees.PPM,
+		EndorsedValue:    a.endorsedValue,
+	})
+}
+
+// UnmarshalJSON decodes the outcome from JSON.
+func (a *AttackOutcome) UnmarshalJSON(data []byte) error {
+	var outcome attackOutcomeJSON
+	if err := json.Unmarshal(data, &outcome); err != nil {
+		return err
+	}
+
+	*a = AttackOutcome{
+		targetReputation: outcome.TargetReputation,
+		targetThreshold:  outcome.TargetThreshold,
+		reputationChange: outcome.ReputationChange,
+		targetCost:       outcome.TargetCost,
+		purchaseCost:     outcome.PurchaseCost,
+		endorsedSlots:    outcome.EndorsedSlots,
+		targetSlots:      outcome.TargetSlots,
+
+		protectedSplit:     outcome.ProtectedSplit,
+		protectedShortfall: outcome.ProtectedShortfall,
+
+		capitalCost:   outcome.CapitalCost,
+		settlePercent: outcome.SettlePercent,
+		firstHopFees: FeePolicy{
+			BaseMsat: outcome.FirstHopBaseMsat,
+			PPM:      outcome.FirstHopPPM,
+		},
+		endorsedValue: outcome.EndorsedValue,
+	}
+
+	return nil
+}
+
+// surgeAttackOutcomeJSON is the JSON representation of a SurgeAttackOutcome.
+type surgeAttackOutcomeJSON struct {
+	CutoffReputation uint64 `json:"cutoffReputation"`
+	PeaceRevenue     uint64 `json:"peaceRevenue"`
+	AttackRevenue    uint64 `json:"attackRevenue"`
+
+	AttackerSettledRevenue uint64 `json:"attackerSettledRevenue,omitempty"`
+	WeeklyRevenue          uint64 `json:"weeklyRevenue,omitempty"`
+	JamLiquidity           uint64 `json:"jamLiquidity,omitempty"`
+	RevenuePeriodWeeks     uint64 `json:"revenuePeriodWeeks,omitempty"`
+	ReputationPeriodWeeks  uint64 `json:"reputationPeriodWeeks,omitempty"`
+	DecayHalfLifeWeeks     uint64 `json:"decayHalfLifeWeeks,omitempty"`
+	MinimumHTLC            uint64 `json:"minimumHTLC,omitempty"`
+	EndorsementMultiplier  uint64 `json:"endorsementMultiplier,omitempty"`
+	SecondsPerBlock        uint64 `json:"secondsPerBlock,omitempty"`
+	BestReputation         uint64 `json:"bestReputation,omitempty"`
+	ThresholdPercent       uint16 `json:"thresholdPercent,omitempty"`
+
+	// LossPercent is computed from the other fields, so it is ignored
+	// when decoding.
+	LossPercent uint64 `json:"lossPercent"`
+}
+
+// MarshalJSON encodes the outcome as JSON, including the percentage of revenue
+// that the node loses.
+func (s *SurgeAttackOutcome) MarshalJSON() ([]byte, error) {
+	return json.Marshal(surgeAttackOutcomeJSON{
+		CutoffReputation: s.cutoffReputation,
+		PeaceRevenue:     s.peaceRevenue,
+		AttackRevenue:    s.attackRevenue,
+		LossPercent:      s.lossPercent(),
+
+		AttackerSettledRevenue: s.attackerSettledRevenue,
+		WeeklyRevenue:          s.weeklyRevenue,
+		JamLiquidity:           s.jamLiquidity,
+		RevenuePeriodWeeks:     s.params.RevenuePeriodWeeks,
+		ReputationPeriodWeeks:  s.params.ReputationPeriodWeeks,
+		DecayHalfLifeWeeks:     s.params.DecayHalfLifeWeeks,
+		MinimumHTLC:            s.params.MinimumHTLC,
+		EndorsementMultiplier:  s.params.EndorsementMultiplier,
+		SecondsPerBlock:        s.params.SecondsPerBlock,
+		BestReputation:         s.bestReputation,
+		ThresholdPercent:       s.thresholdPercent,
+	})
+}
+
+// UnmarshalJSON decodes the outcome from JSON.
+func (s *SurgeAttackOutcome) UnmarshalJSON(data []byte) error {
+	var outcome surgeAttackOutcomeJSON
+	if err := json.Unmarshal(data, &outcome); err != nil {
+		return err
+	}
+
+	*s = SurgeAttackOutcome{
+		cutoffReputation: outcome.CutoffReputation,
+		peaceRevenue:     outcome.PeaceRevenue,
+		attackRevenue:    outcome.AttackRevenue,
+
+		attackerSettledRevenue: outcome.AttackerSettledRevenue,
+		weeklyRevenue:          outcome.WeeklyRevenue,
+		jamLiquidity:           outcome.JamLiquidity,
+		params: Params{
+			RevenuePeriodWeeks:    outcome.RevenuePeriodWeeks,
+			ReputationPeriodWeeks: outcome.ReputationPeriodWeeks,
+			DecayHalfLifeWeeks:    outcome.DecayHalfLifeWeeks,
+			MinimumHTLC:           outcome.MinimumHTLC,
+			EndorsementMultiplier: outcome.EndorsementMultiplier,
+			SecondsPerBlock:       outcome.SecondsPerBlock,
+		},
+		bestReputation:   outcome.BestReputation,
+		thresholdPercent: outcome.ThresholdPercent,
+	}
+
+	return nil
+}
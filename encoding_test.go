@@ -0,0 +1,89 @@
+package reputationfuzz
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestAttackOutcomeJSON tests round trip JSON encoding of ladder outcomes.
+func TestAttackOutcomeJSON(t *testing.T) {
+	outcome := AttackOutcome{
+		targetReputation: 4_800_000,
+		targetThreshold:  800_000,
+		reputationChange: 3_000_000,
+		targetCost:       3_400_000,
+	}
+
+	data, err := json.Marshal(outcome)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"targetReputation": 4800000,
+		"targetThreshold": 800000,
+		"reputationChange": 3000000,
+		"targetCost": 3400000
+	}`, string(data))
+
+	var decoded AttackOutcome
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, outcome, decoded)
+}
+
+// TestSurgeAttackOutcomeJSON tests round trip JSON encoding of surge outcomes,
+// including the computed loss percentage.
+func TestSurgeAttackOutcomeJSON(t *testing.T) {
+	outcome := &SurgeAttackOutcome{
+		cutoffReputation: 12_000_000_000,
+		peaceRevenue:     10_000_000_000,
+		attackRevenue:    3_000_000_000,
+	}
+
+	data, err := json.Marshal(outcome)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"cutoffReputation": 12000000000,
+		"peaceRevenue": 10000000000,
+		"attackRevenue": 3000000000,
+		"lossPercent": 50
+	}`, string(data))
+
+	decoded := &SurgeAttackOutcome{}
+	require.NoError(t, json.Unmarshal(data, decoded))
+	require.Equal(t, outcome, decoded)
+
+	// Revenue from settled jams is only included when present.
+	outcome.attackerSettledRevenue = 1_000_000_000
+
+	data, err = json.Marshal(outcome)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"cutoffReputation": 12000000000,
+		"peaceRevenue": 10000000000,
+		"attackRevenue": 3000000000,
+		"attackerSettledRevenue": 1000000000,
+		"lossPercent": 40
+	}`, string(data))
+
+	decoded = &SurgeAttackOutcome{}
+	require.NoError(t, json.Unmarshal(data, decoded))
+	require.Equal(t, outcome, decoded)
+
+	// Every reputation parameter that the outcome was modeled with
+	// survives the round trip.
+	outcome.params = Params{
+		RevenuePeriodWeeks:    1,
+		ReputationPeriodWeeks: 12,
+		DecayHalfLifeWeeks:    4,
+		MinimumHTLC:           5_000,
+		EndorsementMultiplier: 60,
+		SecondsPerBlock:       30,
+	}
+
+	data, err = json.Marshal(outcome)
+	require.NoError(t, err)
+
+	decoded = &SurgeAttackOutcome{}
+	require.NoError(t, json.Unmarshal(data, decoded))
+	require.Equal(t, outcome, decoded)
+}
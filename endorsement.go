@@ -0,0 +1,64 @@
+package reputationfuzz
+
+// maxEndorsementLevel is the highest level of endorsement in a graded
+// endorsement scheme, equivalent to a HTLC being fully endorsed.
+const maxEndorsementLevel uint8 = 7
+
+// endorsementLevel returns the level of endorsement, from 0 to 7, that a peer
+// with the reputation surplus provided can get for a HTLC of the given amount
+// and hold time. Each level requires a proportional share of the reputation
+// that is required for full endorsement, so a peer that has half of the
+// reputation required for full endorsement will get a mid-level endorsement.
+func endorsementLevel(reputationSurplus, amount, htlcHold uint64,
+	params Params) uint8 {
+
+	cost := budgetForEndorsedValue(amount, htlcHold, params)
+	if cost == 0 {
+		return maxEndorsementLevel
+	}
+
+	level := mulDiv(reputationSurplus, uint64(maxEndorsementLevel), cost)
+	if level > uint64(maxEndorsementLevel) {
+		return maxEndorsementLevel
+	}
+
+	return uint8(level)
+}
+
+// reputationForLevel returns the reputation surplus that a peer requires to
+// get a HTLC with the amount and hold time provided endorsed at the level
+// given. This is rounded up so that the surplus returned is always sufficient
+// for the level.
+func reputationForLevel(amount, htlcHold uint64, level uint8,
+	params Params) uint64 {
+
+	cost := budgetForEndorsedValue(amount, htlcHold, params)
+
+	return mulDivRoundUp(cost, uint64(level), uint64(maxEndorsementLevel))
+}
+
+// htlcSizeAtLevel returns the size of HTLC that a node can get endorsed at
+// the endorsement level provided with its reputation surplus. A zero level
+// means that a HTLC is not endorsed, as for endorsementLevel, so nothing can be
+// endorsed at it.
+func htlcSizeAtLevel(reputationSurplus, htlcHold uint64, level uint8,
+	params Params) uint64 {
+
+	if level == 0 {
+		return 0
+	}
+
+	if level >= maxEndorsementLevel {
+		return endorsedValueForBudget(
+			reputationSurplus, htlcHold, params,
+		)
+	}
+
+	// A lower level only requires a portion of the reputation, so the
+	// surplus stretches further.
+	scaledSurplus := mulDiv(
+		reputationSurplus, uint64(maxEndorsementLevel), uint64(level),
+	)
+
+	return endorsedValueForBudget(scaledSurplus, htlcHold, params)
+}
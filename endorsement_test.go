@@ -0,0 +1,100 @@
+package reputationfuzz
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestEndorsementLevel tests mapping of reputation surplus to graded
+// endorsement levels.
+func TestEndorsementLevel(t *testing.T) {
+	var (
+		amount   uint64 = 1_000
+		htlcHold uint64 = 90
+		fullCost        = budgetForEndorsedValue(
+			amount, htlcHold, Params{},
+		)
+	)
+	require.EqualValues(t, 600_000, fullCost)
+	require.Equal(t, fullCost, reputationForLevel(
+		amount, htlcHold, maxEndorsementLevel, Params{},
+	))
+
+	// A mid-level endorsement requires less reputation than full
+	// endorsement.
+	midCost := reputationForLevel(amount, htlcHold, 4, Params{})
+	require.Less(t, midCost, fullCost)
+	require.EqualValues(t, 4, endorsementLevel(
+		midCost, amount, htlcHold, Params{},
+	))
+	require.EqualValues(t, 3, endorsementLevel(
+		midCost-1, amount, htlcHold, Params{},
+	))
+
+	require.Zero(t, endorsementLevel(0, amount, htlcHold, Params{}))
+	require.Equal(t, maxEndorsementLevel, endorsementLevel(
+		fullCost*2, amount, htlcHold, Params{},
+	))
+
+	// Large surpluses saturate rather than overflowing to a low level.
+	require.Equal(t, maxEndorsementLevel, endorsementLevel(
+		math.MaxUint64, amount, htlcHold, Params{},
+	))
+	require.Equal(t, mulDivRoundUp(math.MaxUint64, 4, 7),
+		reputationForLevel(math.MaxUint64, math.MaxUint64, 4, Params{}))
+}
+
+// TestHTLCSizeAtLevel tests the size of HTLC that a reputation surplus can
+// get endorsed at each level.
+func TestHTLCSizeAtLevel(t *testing.T) {
+	var (
+		surplus  uint64 = 600_000
+		htlcHold uint64 = 90
+	)
+
+	// Level zero is not endorsed, so nothing can be endorsed at it.
+	require.Zero(t, htlcSizeAtLevel(surplus, htlcHold, 0, Params{}))
+
+	require.EqualValues(t, 1_000, htlcSizeAtLevel(
+		surplus, htlcHold, maxEndorsementLevel, Params{},
+	))
+	require.EqualValues(t, 7_000, htlcSizeAtLevel(
+		surplus, htlcHold, 1, Params{},
+	))
+
+	// A large surplus at a low level saturates rather than overflowing.
+	require.Equal(t, htlcSizeAtLevel(
+		math.MaxUint64, htlcHold, maxEndorsementLevel, Params{},
+	), htlcSizeAtLevel(math.MaxUint64, htlcHold, 1, Params{}))
+}
+
+// TestGradedEndorsementLadder tests that an attacker that only requires a
+// mid-level endorsement can get more endorsed on the target than one that
+// requires full endorsement.
+func TestGradedEndorsementLadder(t *testing.T) {
+	scenario := newScenario(
+		120_000, []uint8{100, 10, 25, 50}, 30_000, 300,
+	)
+	cfg := scenario.cfg
+
+	attackAmt, totalCltv := scenario.attackerPayment, scenario.cltvTotal
+
+	full, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	fullEndorsed, err := full.TotalEndorsedOnTarget(attackAmt, totalCltv)
+	require.NoError(t, err)
+	require.EqualValues(t, 10, fullEndorsed)
+
+	cfg.endorsementLevel = 3
+	graded, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	gradedEndorsed, err := graded.TotalEndorsedOnTarget(
+		attackAmt, totalCltv,
+	)
+	require.NoError(t, err)
+	require.EqualValues(t, 23, gradedEndorsed)
+}
@@ -0,0 +1,97 @@
+package reputationfuzz
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	errNoEquilibrium = errors.New("no equilibrium found")
+)
+
+// demandModel returns the volume of traffic, in msat over the reputation
+// period, that the node at the index provided forwards given the fee rates
+// (in parts per million) charged by every node in the market.
+type demandModel func(node int, feeRates []uint64) uint64
+
+// marketRevenue returns the fee revenue earned by the node at the index
+// provided over the reputation period, given the fee rates in the market.
+func marketRevenue(node int, feeRates []uint64, demand demandModel) uint64 {
+	return mulDiv(demand(node, feeRates), feeRates[node], 1_000_000)
+}
+
+// feeEquilibrium iteratively searches for fee rates where no node in the
+// market can increase its revenue by unilaterally changing its fee rate to
+// another one of the candidate rates. Each round, every node in turn adopts
+// its best response to the current rates of the other nodes, until no node
+// changes its rate or the maximum number of rounds is reached.
+func feeEquilibrium(initialRates, candidates []uint64, demand demandModel,
+	maxRounds int) ([]uint64, error) {
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no candidate fee rates")
+	}
+
+	rates := make([]uint64, len(initialRates))
+	copy(rates, initialRates)
+
+	for round := 0; round < maxRounds; round++ {
+		var changed bool
+
+		for node := range rates {
+			best := bestResponse(node, rates, candidates, demand)
+			if best != rates[node] {
+				rates[node] = best
+				changed = true
+			}
+		}
+
+		if !changed {
+			return rates, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: after %v rounds", errNoEquilibrium,
+		maxRounds)
+}
+
+// bestResponse returns the candidate fee rate that maximizes the revenue of
+// the node provided, given the rates of all other nodes. The node's current
+// rate is preferred in the case of a tie so that the search settles.
+func bestResponse(node int, rates, candidates []uint64,
+	demand demandModel) uint64 {
+
+	trial := make([]uint64, len(rates))
+	copy(trial, rates)
+
+	var (
+		bestRate    = rates[node]
+		bestRevenue = marketRevenue(node, trial, demand)
+	)
+
+	for _, candidate := range candidates {
+		trial[node] = candidate
+		revenue := marketRevenue(node, trial, demand)
+
+		if revenue > bestRevenue {
+			bestRate = candidate
+			bestRevenue = revenue
+		}
+	}
+
+	return bestRate
+}
+
+// surgeAtEquilibrium evaluates a surge attack against a node whose peers are
+// the nodes in a market at the fee rates provided, using each node's revenue
+// over the reputation period as its reputation.
+func surgeAtEquilibrium(rates []uint64, demand demandModel, cutoffIndex int,
+	cfg surgeAttackCfg) (*SurgeAttackOutcome, error) {
+
+	peers := make([]uint64, len(rates))
+	for node := range rates {
+		peers[node] = marketRevenue(node, rates, demand)
+	}
+
+	return surgeAttack(peers, cutoffIndex, cfg)
+}
@@ -0,0 +1,72 @@
+package reputationfuzz
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestFeeEquilibrium tests that the solver converges on a simple two node
+// market where each node's demand falls with its own fee rate and rises with
+// its competitor's.
+func TestFeeEquilibrium(t *testing.T) {
+	demand := func(node int, rates []uint64) uint64 {
+		own, other := rates[node], rates[1-node]
+
+		// Demand is 1_000_000 * (2000 - 2 * own + other), so the
+		// best response to a competitor's rate r is (2000 + r) / 4
+		// and the equilibrium is at a rate of ~667 ppm.
+		units := int64(2000) - 2*int64(own) + int64(other)
+		if units < 0 {
+			return 0
+		}
+
+		return uint64(units) * 1_000_000
+	}
+
+	var candidates []uint64
+	for rate := uint64(0); rate <= 2000; rate += 50 {
+		candidates = append(candidates, rate)
+	}
+
+	rates, err := feeEquilibrium(
+		[]uint64{2000, 100}, candidates, demand, 100,
+	)
+	require.NoError(t, err)
+	require.Len(t, rates, 2)
+	require.Equal(t, rates[0], rates[1])
+	require.InDelta(t, 667, rates[0], 50)
+
+	// No node can do better by unilaterally changing its rate.
+	for node := range rates {
+		require.Equal(t, rates[node], bestResponse(
+			node, rates, candidates, demand,
+		))
+	}
+
+	// We can evaluate attacks against a node that peers with the market,
+	// which has equal revenue from both peers at equilibrium.
+	outcome, err := surgeAtEquilibrium(rates, demand, 0, surgeAttackCfg{})
+	require.NoError(t, err)
+	require.Equal(t, outcome.peaceRevenue,
+		2*revenueFromReputation(
+			marketRevenue(0, rates, demand), Params{},
+		))
+
+	// If we don't allow enough rounds to settle, we fail.
+	_, err = feeEquilibrium([]uint64{2000, 100}, candidates, demand, 1)
+	require.ErrorIs(t, err, errNoEquilibrium)
+}
+
+// TestMarketRevenueSaturates tests that revenue on demand too large to
+// multiply by the fee rate doesn't wrap around.
+func TestMarketRevenueSaturates(t *testing.T) {
+	demand := func(int, []uint64) uint64 {
+		return math.MaxUint64
+	}
+
+	require.EqualValues(t, uint64(math.MaxUint64/2), marketRevenue(
+		0, []uint64{500_000}, demand,
+	))
+}
@@ -0,0 +1,75 @@
+package reputationfuzz
+
+// networkNode describes a single node in a network snapshot, along with the
+// contexts in which it may be attacked.
+type networkNode struct {
+	// revenue is the node's revenue over the revenue period, which is used
+	// to weight its contribution to the network's exposure.
+	revenue uint64
+
+	// honestPeers is the reputation of each of the node's peers, which is
+	// used to evaluate surge attacks against the node.
+	honestPeers []uint64
+
+	// ladders is the set of routes in which the node is the target of a
+	// laddering attack.
+	ladders []ladderScenario
+}
+
+// networkSnapshot describes the nodes in a network at a point in time.
+type networkSnapshot struct {
+	nodes []networkNode
+}
+
+// attackable returns a boolean indicating whether the node can be attacked by
+// an attacker with the budget provided, either by a surge attack or by any of
+// the laddering attacks that it is exposed to.
+func (n networkNode) attackable(budget uint64) (bool, error) {
+	for _, ladder := range n.ladders {
+		if ladder.attackerPayment > budget {
+			continue
+		}
+
+		effective, err := ladder.effective()
+		if err != nil {
+			return false, err
+		}
+
+		if effective {
+			return true, nil
+		}
+	}
+
+	target := surgeTarget{
+		honestPeers: n.honestPeers,
+	}
+
+	return target.attackable(budget)
+}
+
+// networkExposureScore summarizes the systemic exposure of a network to
+// attacks as the fraction of the network's revenue that is earned by nodes
+// that can be attacked within the budget provided.
+func networkExposureScore(snapshot networkSnapshot, budget uint64) (float64,
+	error) {
+
+	var totalRevenue, exposedRevenue uint64
+	for _, node := range snapshot.nodes {
+		totalRevenue += node.revenue
+
+		attackable, err := node.attackable(budget)
+		if err != nil {
+			return 0, err
+		}
+
+		if attackable {
+			exposedRevenue += node.revenue
+		}
+	}
+
+	if totalRevenue == 0 {
+		return 0, nil
+	}
+
+	return float64(exposedRevenue) / float64(totalRevenue), nil
+}
@@ -0,0 +1,77 @@
+package reputationfuzz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestNetworkExposureScore tests aggregation of attack exposure across a small
+// synthetic network.
+func TestNetworkExposureScore(t *testing.T) {
+	// The first node has no peer that stands out, so a surge attacker
+	// can cut all of its peers off for 2e9 and it is only exposed to
+	// budgets at least that large.
+	surgePeers := equalPeers(10, 12_000_000_000)
+
+	ladder := newScenario(
+		1_000_000, []uint8{100, 50, 50, 25}, 1_000_000, 1000,
+	)
+	ladder.cfg.lastHopWeightPercent = 50
+
+	snapshot := networkSnapshot{
+		nodes: []networkNode{
+			{
+				revenue:     10_000_000_000,
+				honestPeers: surgePeers,
+			},
+			{
+				revenue: 10_000_000_000,
+				ladders: []ladderScenario{ladder},
+			},
+			{
+				// The seed peer set can't be surge attacked.
+				revenue: 20_000_000_000,
+				honestPeers: []uint64{
+					2000, 995735184, 172248607, 186257710,
+					121153119, 794542970, 438050891,
+					372484894, 306771541, 271374988,
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		budget uint64
+		score  float64
+	}{
+		{
+			name:   "no budget",
+			budget: 0,
+			score:  0,
+		},
+		{
+			name:   "ladder only",
+			budget: 1_000_000,
+			score:  0.25,
+		},
+		{
+			name:   "ladder and surge",
+			budget: 2_000_000_000,
+			score:  0.5,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			score, err := networkExposureScore(snapshot, test.budget)
+			require.NoError(t, err)
+			require.Equal(t, test.score, score)
+		})
+	}
+
+	score, err := networkExposureScore(networkSnapshot{}, 1)
+	require.NoError(t, err)
+	require.Zero(t, score)
+}
@@ -0,0 +1,223 @@
+package reputationfuzz
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// errBranchTraffic is returned when the incoming branches of a fan-in attack
+// forward more traffic to the target than the target forwards itself.
+var errBranchTraffic = errors.New("branches exceed target traffic")
+
+// fanInBranch describes one of the incoming paths that an attacker ladders up
+// to the target over.
+type fanInBranch struct {
+	// firstNodeTraffic is the amount of payment traffic that is forwarded
+	// by the node that the attacker connects to in this branch, as for a
+	// single ladder.
+	firstNodeTraffic uint64
+
+	// trafficFlows describes each node in the branch, starting with the
+	// node that the attacker connects to and ending with the target's
+	// incoming peer. The last node forwards all of its traffic to the
+	// target, which is the portion of the target's traffic that the branch
+	// provides.
+	trafficFlows []trafficFlow
+}
+
+// fanInCfg describes a fan-in attack, where the attacker builds several
+// incoming branches that converge on a single target.
+type fanInCfg struct {
+	// shared holds the settings that apply to every branch. Its first
+	// node traffic and traffic flows are ignored, because the route is
+	// described by the fields below.
+	shared ladderingAttackCfg
+
+	// targetTraffic is the amount of payment traffic that is forwarded
+	// by the target, expressed over the same period as each branch's
+	// first node traffic.
+	targetTraffic uint64
+
+	// target describes the target node. Its portion is ignored, because
+	// the target's traffic is set directly and each branch provides its
+	// own portion of it.
+	target trafficFlow
+
+	// final describes the final node, which the target's reputation is
+	// measured with.
+	final trafficFlow
+
+	// branches describes each incoming path to the target. Together they
+	// can provide at most all of the target's traffic.
+	branches []fanInBranch
+}
+
+// FanInAttack is a laddering attack where the attacker builds several
+// incoming paths that converge on the target. The target tracks reputation
+// separately for each of its incoming peers, so the attacker can get HTLCs
+// endorsed on the target's outgoing link over each path and the endorsed
+// amounts accumulate.
+type FanInAttack struct {
+	// paths holds a ladder for each incoming branch, each of which ends
+	// with the target and final node that are shared by every branch.
+	paths []*LadderingAttack
+}
+
+// newFanInAttack creates a laddering attack over the incoming branches of the
+// config provided. The target and final node are modeled once and shared by
+// every branch, and the branches must not forward more traffic to the target
+// than it forwards in total.
+func newFanInAttack(cfg fanInCfg) (*FanInAttack, error) {
+	if len(cfg.branches) == 0 {
+		return nil, fmt.Errorf("at least one branch required")
+	}
+
+	// Model the target and final node from the target's own traffic, by
+	// feeding it with a single node that provides all of its traffic.
+	shared := cfg.shared
+	shared.firstNodeTraffic = cfg.targetTraffic
+	shared.trafficFlows = []trafficFlow{
+		{portionBasisPoints: basisPoints},
+		cfg.target,
+		cfg.final,
+	}
+	shared.trafficFlows[1].portionBasisPoints = basisPoints
+
+	tail, err := newLadderingAttack(shared)
+	if err != nil {
+		return nil, fmt.Errorf("target: %w", err)
+	}
+	target, final := tail.targetChannels()
+
+	attack := &FanInAttack{
+		paths: make([]*LadderingAttack, len(cfg.branches)),
+	}
+
+	var branchTraffic uint64
+	for i, branch := range cfg.branches {
+		if len(branch.trafficFlows) == 0 {
+			return nil, fmt.Errorf("branch %v: no traffic flows", i)
+		}
+
+		// The branch's ladder includes the target and final node so
+		// that its last hop is built with the target's fees, but they
+		// are replaced with the shared channels.
+		pathCfg := cfg.shared
+		pathCfg.firstNodeTraffic = branch.firstNodeTraffic
+		pathCfg.trafficFlows = append(
+			append([]trafficFlow{}, branch.trafficFlows...),
+			shared.trafficFlows[1:]...,
+		)
+
+		ladder, err := newLadderingAttack(pathCfg)
+		if err != nil {
+			return nil, fmt.Errorf("branch %v: %w", i, err)
+		}
+
+		ladder.channels[len(ladder.channels)-2] = target
+		ladder.channels[len(ladder.channels)-1] = final
+		attack.paths[i] = ladder
+
+		traffic, err := branch.traffic()
+		if err != nil {
+			return nil, fmt.Errorf("branch %v: %w", i, err)
+		}
+
+		branchTraffic = saturatingAdd(branchTraffic, traffic)
+	}
+
+	if branchTraffic > cfg.targetTraffic {
+		return nil, fmt.Errorf("%w: branches forward %v, target "+
+			"forwards %v", errBranchTraffic, branchTraffic,
+			cfg.targetTraffic)
+	}
+
+	return attack, nil
+}
+
+// traffic returns the traffic that the last node in the branch forwards to
+// the target.
+func (b fanInBranch) traffic() (uint64, error) {
+	traffic := b.firstNodeTraffic
+	for i, flow := range b.trafficFlows {
+		hi, lo := bits.Mul64(traffic, basisPoints)
+		if hi != 0 {
+			return 0, fmt.Errorf("%w: hop %v traffic: %v * %v",
+				errTrafficOverflow, i, traffic, basisPoints)
+		}
+		traffic = lo / uint64(flow.portionBasisPoints)
+	}
+
+	return traffic, nil
+}
+
+// targetChannels returns the target's channel and the final node's channel.
+func (l *LadderingAttack) targetChannels() (channel, channel) {
+	return l.channels[len(l.channels)-2], l.channels[len(l.channels)-1]
+}
+
+// TotalEndorsedOnTarget calculates the total amount that an attacker can get
+// endorsed on the target node when it pays the amount provided to build
+// reputation on each path, in the same order as the paths, and holds its
+// HTLCs on the target's outgoing link for targetHold blocks. Paths have
+// different lengths, so each path's HTLCs have the total cltv that leaves the
+// same hold once they reach the target. The amounts that the attacker can get
+// endorsed over each path are combined, so several cheap paths can get more
+// endorsed than any single path allows. The attacker holds at least one HTLC
+// over each path, so the target's outgoing link must have a slot for each of
+// them.
+func (f *FanInAttack) TotalEndorsedOnTarget(attackerPayments []uint64,
+	targetHold uint64) (uint64, error) {
+
+	if len(attackerPayments) != len(f.paths) {
+		return 0, fmt.Errorf("payments: %v != path count: %v",
+			len(attackerPayments), len(f.paths))
+	}
+
+	var (
+		target, _     = f.paths[0].targetChannels()
+		totalEndorsed uint64
+		attackerSlots uint64
+	)
+
+	for i, path := range f.paths {
+		attackerSlots += uint64(path.attackerSlots)
+		if attackerSlots > uint64(target.slotCapacity) {
+			return 0, fmt.Errorf("%w: target has %v slots, attacker "+
+				"needs %v for %v paths", errInsufficientSlots,
+				target.slotCapacity, attackerSlots, i+1)
+		}
+
+		endorsed, err := path.TotalEndorsedOnTarget(
+			attackerPayments[i], path.totalCltv(targetHold),
+		)
+		if err != nil {
+			return 0, fmt.Errorf("path %v: %w", i, err)
+		}
+
+		totalEndorsed = saturatingAdd(totalEndorsed, endorsed)
+	}
+
+	return totalEndorsed, nil
+}
+
+// Outcome returns the outcome of an attack where the attacker holds the total
+// endorsed amount provided on the target node for targetHold blocks. Every
+// path shares the target and its HTLCs are held there for the same time, so
+// the outcome on the target is the same regardless of the path that the
+// amount was endorsed over.
+func (f *FanInAttack) Outcome(totalEndorsed, targetHold uint64) AttackOutcome {
+	path := f.paths[0]
+
+	return path.Outcome(totalEndorsed, path.totalCltv(targetHold))
+}
+
+func (f *FanInAttack) String() string {
+	str := fmt.Sprintf("Paths: %v", len(f.paths))
+	for i, path := range f.paths {
+		str = fmt.Sprintf("%s\nPath %v: %v", str, i, path)
+	}
+
+	return str
+}
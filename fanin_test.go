@@ -0,0 +1,151 @@
+package reputationfuzz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestFanInAttack tests that an attacker with several incoming paths to the
+// target can combine the amount that it gets endorsed over each of them.
+func TestFanInAttack(t *testing.T) {
+	// A target that forwards 2e9 of traffic, with two incoming branches
+	// that each provide 15% of it. The short branch's first node forwards
+	// its traffic straight to the target, and the long branch has an
+	// extra hop that doubles the traffic of its first node.
+	cfg := fanInCfg{
+		shared: ladderingAttackCfg{
+			lastHopWeightPercent: 25,
+		},
+		targetTraffic: 2_000_000_000,
+		final:         trafficFlow{portionBasisPoints: 5_000},
+		branches: []fanInBranch{
+			{
+				firstNodeTraffic: 300_000_000,
+				trafficFlows: []trafficFlow{
+					{portionBasisPoints: 10_000},
+				},
+			},
+			{
+				firstNodeTraffic: 150_000_000,
+				trafficFlows: []trafficFlow{
+					{portionBasisPoints: 10_000},
+					{portionBasisPoints: 5_000},
+				},
+			},
+		},
+	}
+
+	attack, err := newFanInAttack(cfg)
+	require.NoError(t, err)
+
+	// Both branches share the same target and final node.
+	target, final := attack.paths[0].targetChannels()
+	pathTarget, pathFinal := attack.paths[1].targetChannels()
+	require.Equal(t, target, pathTarget)
+	require.Equal(t, final, pathFinal)
+
+	// The attacker holds its HTLCs on the target for 1000 blocks, so the
+	// longer path's HTLCs need a larger total cltv to cover its extra
+	// hop's delta.
+	targetHold := uint64(1000)
+	require.EqualValues(t, 1080, attack.paths[0].totalCltv(targetHold))
+	require.EqualValues(t, 1160, attack.paths[1].totalCltv(targetHold))
+
+	// Neither path gets enough endorsed to jam the target by itself, even
+	// when the attacker pays far more to build reputation.
+	for i, maxEndorsed := range []uint64{20_000, 17_361} {
+		var (
+			path      = attack.paths[i]
+			totalCltv = path.totalCltv(targetHold)
+		)
+
+		endorsed, err := path.TotalEndorsedOnTarget(
+			200_000_000, totalCltv,
+		)
+		require.NoError(t, err)
+		require.Equal(t, maxEndorsed, endorsed)
+		require.False(t, path.Outcome(endorsed, totalCltv).Effective(
+			200_000_000,
+		))
+
+		endorsed, err = path.TotalEndorsedOnTarget(1e11, totalCltv)
+		require.NoError(t, err)
+		require.Equal(t, maxEndorsed, endorsed)
+		require.False(t, path.Outcome(endorsed, totalCltv).jams())
+	}
+
+	// Combined, the paths jam the target for less than it would cost to
+	// build reputation with the target directly.
+	endorsed, err := attack.TotalEndorsedOnTarget(
+		[]uint64{200_000_000, 200_000_000}, targetHold,
+	)
+	require.NoError(t, err)
+	require.EqualValues(t, 37_361, endorsed)
+
+	outcome := attack.Outcome(endorsed, targetHold)
+	require.True(t, outcome.Effective(400_000_000))
+	require.EqualValues(t, 15_740_000, outcome.Margin(400_000_000))
+
+	// Each path needs a payment.
+	_, err = attack.TotalEndorsedOnTarget([]uint64{200_000_000}, targetHold)
+	require.Error(t, err)
+
+	// The attacker needs a slot on the target's outgoing link for each
+	// path.
+	cfg.target.slotCapacity = 1
+	attack, err = newFanInAttack(cfg)
+	require.NoError(t, err)
+
+	_, err = attack.TotalEndorsedOnTarget(
+		[]uint64{200_000_000, 200_000_000}, targetHold,
+	)
+	require.ErrorIs(t, err, errInsufficientSlots)
+}
+
+// TestFanInBranchTraffic tests that the branches of a fan-in attack can't
+// forward more traffic to the target than the target forwards itself.
+func TestFanInBranchTraffic(t *testing.T) {
+	branch := fanInBranch{
+		firstNodeTraffic: 600_000_000,
+		trafficFlows: []trafficFlow{
+			{portionBasisPoints: 10_000},
+			{portionBasisPoints: 5_000},
+		},
+	}
+
+	traffic, err := branch.traffic()
+	require.NoError(t, err)
+	require.EqualValues(t, 1_200_000_000, traffic)
+
+	cfg := fanInCfg{
+		targetTraffic: 2_000_000_000,
+		final:         trafficFlow{portionBasisPoints: 10_000},
+		branches:      []fanInBranch{branch},
+	}
+
+	_, err = newFanInAttack(cfg)
+	require.NoError(t, err)
+
+	// Two copies of the branch would provide 120% of the target's
+	// traffic.
+	cfg.branches = append(cfg.branches, branch)
+	_, err = newFanInAttack(cfg)
+	require.ErrorIs(t, err, errBranchTraffic)
+
+	// Together, the branches can provide all of the target's traffic.
+	cfg.targetTraffic = 2_400_000_000
+	_, err = newFanInAttack(cfg)
+	require.NoError(t, err)
+
+	// Every branch needs at least one node.
+	cfg.branches = append(cfg.branches, fanInBranch{
+		firstNodeTraffic: 1,
+	})
+	_, err = newFanInAttack(cfg)
+	require.Error(t, err)
+
+	cfg.branches = nil
+	_, err = newFanInAttack(cfg)
+	require.Error(t, err)
+}
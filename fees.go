@@ -0,0 +1,53 @@
+package reputationfuzz
+
+// FeePolicy describes the fees that a node charges to forward payments over
+// its outgoing link.
+type FeePolicy struct {
+	// BaseMsat is the fixed fee charged per forward, in msat.
+	BaseMsat uint64
+
+	// PPM is the proportional fee charged on the amount forwarded, in
+	// parts per million.
+	PPM uint64
+}
+
+// passThrough returns a boolean indicating whether the policy is unset, in
+// which case amounts are counted 1:1 rather than converted to fees.
+func (f FeePolicy) passThrough() bool {
+	return f.BaseMsat == 0 && f.PPM == 0
+}
+
+// Fee returns the fee charged to forward a single HTLC of the amount provided,
+// in msat. The base fee dominates for small HTLCs and the proportional fee
+// dominates for large ones. The fee saturates at math.MaxUint64 rather than
+// overflowing.
+func (f FeePolicy) Fee(amountMsat uint64) uint64 {
+	return saturatingAdd(f.BaseMsat, mulDiv(amountMsat, f.PPM, 1_000_000))
+}
+
+// feeOrPassThrough returns the fees earned on the amount provided. Since we
+// model traffic as a total volume rather than individual payments, the base
+// fee is charged once on the volume. If the policy is unset, the amount itself
+// is returned so that reputation and revenue are expressed in volume.
+func (f FeePolicy) feeOrPassThrough(amount uint64) uint64 {
+	if f.passThrough() {
+		return amount
+	}
+
+	return f.Fee(amount)
+}
+
+// htlcFees returns the fees charged on the amount provided when it is split
+// evenly across the number of HTLCs provided, so the base fee is charged on
+// each HTLC. If the policy is unset, the amount itself is returned.
+func (f FeePolicy) htlcFees(amount, htlcCount uint64) uint64 {
+	if f.passThrough() {
+		return amount
+	}
+
+	if htlcCount == 0 {
+		return 0
+	}
+
+	return saturatingMul(f.Fee(amount/htlcCount), htlcCount)
+}
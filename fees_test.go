@@ -0,0 +1,96 @@
+package reputationfuzz
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestFeePolicy tests calculation of fees, including pass-through of amounts
+// when no policy is set.
+func TestFeePolicy(t *testing.T) {
+	require.EqualValues(
+		t, 1_000_000, FeePolicy{}.feeOrPassThrough(1_000_000),
+	)
+
+	policy := FeePolicy{
+		BaseMsat: 1_000,
+		PPM:      500,
+	}
+	require.EqualValues(t, 1_500, policy.feeOrPassThrough(1_000_000))
+	require.EqualValues(t, 1_000, policy.feeOrPassThrough(0))
+}
+
+// TestFee tests the fee charged to forward a single HTLC, which is dominated
+// by the base fee for small HTLCs and the proportional fee for large ones.
+func TestFee(t *testing.T) {
+	policy := FeePolicy{
+		BaseMsat: 1_000,
+		PPM:      100,
+	}
+
+	// A small HTLC pays almost entirely base fee.
+	require.EqualValues(t, 1_001, policy.Fee(10_000))
+
+	// A large HTLC pays almost entirely proportional fee.
+	require.EqualValues(t, 10_001_000, policy.Fee(100_000_000_000))
+
+	// An unset policy charges nothing, rather than passing the amount
+	// through.
+	require.Zero(t, FeePolicy{}.Fee(1_000_000))
+
+	// Large amounts saturate rather than overflowing.
+	require.EqualValues(t, uint64(math.MaxUint64), FeePolicy{
+		BaseMsat: 1,
+		PPM:      2_000_000,
+	}.Fee(math.MaxUint64))
+}
+
+// TestHTLCFees tests that splitting an amount across many HTLCs pays the base
+// fee on each of them.
+func TestHTLCFees(t *testing.T) {
+	policy := FeePolicy{
+		BaseMsat: 1_000,
+		PPM:      100,
+	}
+
+	require.EqualValues(t, 1_100, policy.htlcFees(1_000_000, 1))
+	require.EqualValues(t, 100_100, policy.htlcFees(1_000_000, 100))
+	require.Zero(t, policy.htlcFees(1_000_000, 0))
+
+	// Amounts are passed through if no policy is set.
+	require.EqualValues(t, 1_000_000, FeePolicy{}.htlcFees(1_000_000, 100))
+}
+
+// TestEndorsedSlotsBaseFee tests that a base fee on the target's outgoing link
+// limits the number of minimum sized HTLCs that an attacker can afford to
+// hold, making slot exhaustion uneconomical.
+func TestEndorsedSlotsBaseFee(t *testing.T) {
+	attack := &LadderingAttack{
+		channels: []channel{
+			{slotCapacity: maxHTLCSlots},
+			{slotCapacity: maxHTLCSlots},
+			{slotCapacity: maxHTLCSlots},
+		},
+		attackerSlots: 1,
+	}
+
+	// Without fees, the attacker splits its endorsed amount into as many
+	// minimum sized HTLCs as it covers.
+	require.EqualValues(t, 100, attack.endorsedSlots(100_000))
+
+	// With a base fee, the fees on the amount as a single HTLC only cover
+	// the base fee of one minimum sized HTLC.
+	attack.channels[2].fees = FeePolicy{
+		BaseMsat: 1_000,
+		PPM:      100,
+	}
+	require.EqualValues(t, 1, attack.endorsedSlots(100_000))
+
+	// A purely proportional fee doesn't penalize small HTLCs.
+	attack.channels[2].fees = FeePolicy{
+		PPM: 100,
+	}
+	require.EqualValues(t, 100, attack.endorsedSlots(100_000))
+}
@@ -0,0 +1,53 @@
+package reputationfuzz
+
+import "fmt"
+
+// msatPerDollar is the approximate number of msat that are worth one US dollar
+// at the time of writing.
+const msatPerDollar = 17_00_000
+
+// fiatConverter converts msat amounts into US dollars for reporting.
+type fiatConverter struct {
+	// msatPerUSD is the number of msat that are worth one US dollar. If
+	// zero, msatPerDollar is used.
+	msatPerUSD uint64
+}
+
+// rate returns the number of msat per US dollar used for conversion.
+func (f fiatConverter) rate() uint64 {
+	if f.msatPerUSD == 0 {
+		return msatPerDollar
+	}
+
+	return f.msatPerUSD
+}
+
+// usd converts the msat amount provided into US dollars.
+func (f fiatConverter) usd(msat uint64) float64 {
+	return float64(msat) / float64(f.rate())
+}
+
+// format renders the msat amount provided as a US dollar string.
+func (f fiatConverter) format(msat uint64) string {
+	return fmt.Sprintf("$%.2f", f.usd(msat))
+}
+
+// ladderCosts renders the costs and damages of a laddering attack in US
+// dollars.
+func (f fiatConverter) ladderCosts(outcome AttackOutcome,
+	attackerPayment uint64) string {
+
+	return fmt.Sprintf("Attacker paid: %v, target reputation lost: %v, "+
+		"cost to attack target directly: %v",
+		f.format(attackerPayment), f.format(outcome.reputationChange),
+		f.format(outcome.targetCost))
+}
+
+// surgeCosts renders the costs and damages of a surge attack in US dollars.
+func (f fiatConverter) surgeCosts(outcome *SurgeAttackOutcome) string {
+	return fmt.Sprintf("Attacker paid: %v, node revenue in peace: %v, "+
+		"honest revenue under attack: %v",
+		f.format(outcome.attackerPays()),
+		f.format(outcome.peaceRevenue),
+		f.format(outcome.attackRevenue))
+}
@@ -0,0 +1,38 @@
+package reputationfuzz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestFiatConverter tests conversion of known msat costs into US dollars.
+func TestFiatConverter(t *testing.T) {
+	// Our minimum HTLC is defined as one dollar at the default rate.
+	converter := fiatConverter{}
+	require.Equal(t, 1.0, converter.usd(minimumHTLCReputation))
+	require.Equal(t, "$1.00", converter.format(minimumHTLCReputation))
+
+	// At a fixed rate of 2_000_000 msat per dollar.
+	converter = fiatConverter{
+		msatPerUSD: 2_000_000,
+	}
+	require.Equal(t, 2.5, converter.usd(5_000_000))
+
+	outcome := AttackOutcome{
+		reputationChange: 3_000_000,
+		targetCost:       10_000_000,
+	}
+	require.Equal(t, "Attacker paid: $0.50, target reputation lost: "+
+		"$1.50, cost to attack target directly: $5.00",
+		converter.ladderCosts(outcome, 1_000_000))
+
+	surge := &SurgeAttackOutcome{
+		cutoffReputation: 6_000_000,
+		peaceRevenue:     4_000_000,
+		attackRevenue:    1_000_000,
+	}
+	require.Equal(t, "Attacker paid: $1.00, node revenue in peace: "+
+		"$2.00, honest revenue under attack: $0.50",
+		converter.surgeCosts(surge))
+}
@@ -0,0 +1,204 @@
+// Package findings collects the successful attacks that are found over the
+// course of a fuzz run and groups them into distinct classes, so that a long
+// run produces a handful of actionable categories rather than a wall of
+// failures that differ only slightly in their numbers.
+package findings
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// lossBucketPercent is the granularity that loss percentages are rounded to
+// when normalizing an outcome's signature.
+const lossBucketPercent = 10
+
+// Outcome describes a single successful attack found by a fuzz test.
+type Outcome struct {
+	// Attack is the name of the attack that was successful.
+	Attack string
+
+	// NetworkLength is the number of nodes or peers in the network that
+	// the attack was performed on.
+	NetworkLength int
+
+	// Cutoff is the index of the best peer that is cut off by the attack,
+	// zero if not applicable.
+	Cutoff int
+
+	// LossPercent is the percentage of reputation or revenue that the
+	// attack cost the target.
+	LossPercent uint64
+
+	// Description is a human readable description of the attack that is
+	// reported as an example of its class.
+	Description string
+}
+
+// Signature is the normalized signature of an outcome. Outcomes that share a
+// signature belong to the same class of attack.
+type Signature struct {
+	// Attack is the name of the attack that was successful.
+	Attack string
+
+	// NetworkLength is the number of nodes or peers in the network.
+	NetworkLength int
+
+	// Cutoff is the index of the best peer that is cut off by the attack.
+	Cutoff int
+
+	// LossPercent is the outcome's loss percentage, rounded to the nearest
+	// lossBucketPercent.
+	LossPercent uint64
+}
+
+// String returns a description of the class of attacks with the signature.
+func (s Signature) String() string {
+	return fmt.Sprintf("%v attack (network length: %v, cutoff: %v, "+
+		"loss: ~%v%%)", s.Attack, s.NetworkLength, s.Cutoff,
+		s.LossPercent)
+}
+
+// fileName returns the name of the file that the example of the class of
+// attacks with the signature is written to.
+func (s Signature) fileName() string {
+	return fmt.Sprintf("%v-%v-%v-%v", s.Attack, s.NetworkLength, s.Cutoff,
+		s.LossPercent)
+}
+
+// signature returns the normalized signature of the outcome.
+func (o Outcome) signature() Signature {
+	return Signature{
+		Attack:        o.Attack,
+		NetworkLength: o.NetworkLength,
+		Cutoff:        o.Cutoff,
+		LossPercent: (o.LossPercent + lossBucketPercent/2) /
+			lossBucketPercent * lossBucketPercent,
+	}
+}
+
+// class is a set of outcomes that share a signature.
+type class struct {
+	// count is the number of outcomes that have been added to the class.
+	count int
+
+	// example is the first outcome that was added to the class.
+	example Outcome
+}
+
+// Collector deduplicates successful attacks by their signature. It is safe
+// for concurrent use.
+type Collector struct {
+	// dir is the directory that an example of each class is written to,
+	// empty if classes are only held in memory.
+	dir string
+
+	mu      sync.Mutex
+	classes map[Signature]*class
+}
+
+// NewCollector creates an empty collector. If a directory is provided, the
+// example of each new class is also written to a file in the directory that
+// is named by its signature, so that findings from multiple processes are
+// deduplicated on disk.
+func NewCollector(dir string) *Collector {
+	return &Collector{
+		dir:     dir,
+		classes: make(map[Signature]*class),
+	}
+}
+
+// Add records a successful attack, keeping the first outcome that is added
+// for each signature as a representative example of its class.
+func (c *Collector) Add(outcome Outcome) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	signature := outcome.signature()
+
+	existing, ok := c.classes[signature]
+	if !ok {
+		if err := c.write(signature, outcome); err != nil {
+			return err
+		}
+
+		existing = &class{example: outcome}
+		c.classes[signature] = existing
+	}
+
+	existing.count++
+
+	return nil
+}
+
+// write writes the outcome to the collector's directory as the example for
+// its signature, leaving any example that was already written in place.
+func (c *Collector) write(signature Signature, outcome Outcome) error {
+	if c.dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return err
+	}
+
+	path := filepath.Join(c.dir, signature.fileName())
+	file, err := os.OpenFile(
+		path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600,
+	)
+	if errors.Is(err, os.ErrExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(file, "%v\n%v\n", signature, outcome.Description)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// Report returns a summary of the distinct classes of attack that have been
+// collected, with the most frequent classes first and a representative
+// example of each.
+func (c *Collector) Report() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	signatures := make([]Signature, 0, len(c.classes))
+
+	var total int
+	for signature, class := range c.classes {
+		signatures = append(signatures, signature)
+		total += class.count
+	}
+
+	sort.Slice(signatures, func(i, j int) bool {
+		ci, cj := c.classes[signatures[i]], c.classes[signatures[j]]
+		if ci.count != cj.count {
+			return ci.count > cj.count
+		}
+
+		return signatures[i].String() < signatures[j].String()
+	})
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "%v distinct attack classes from %v findings",
+		len(signatures), total)
+
+	for _, signature := range signatures {
+		class := c.classes[signature]
+		fmt.Fprintf(&report, "\n  - %v: %v findings, e.g. %v",
+			signature, class.count, class.example.Description)
+	}
+
+	return report.String()
+}
@@ -0,0 +1,93 @@
+package findings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSignature tests normalizing outcomes into signatures.
+func TestSignature(t *testing.T) {
+	outcome := Outcome{
+		Attack:        "surge",
+		NetworkLength: 10,
+		Cutoff:        4,
+		LossPercent:   34,
+	}
+	require.Equal(t, Signature{
+		Attack:        "surge",
+		NetworkLength: 10,
+		Cutoff:        4,
+		LossPercent:   30,
+	}, outcome.signature())
+
+	outcome.LossPercent = 35
+	require.EqualValues(t, 40, outcome.signature().LossPercent)
+
+	outcome.LossPercent = 0
+	require.EqualValues(t, 0, outcome.signature().LossPercent)
+}
+
+// TestCollector tests deduplicating outcomes into classes and reporting them.
+func TestCollector(t *testing.T) {
+	collector := NewCollector("")
+	require.Equal(t, "0 distinct attack classes from 0 findings",
+		collector.Report())
+
+	require.NoError(t, collector.Add(Outcome{
+		Attack:        "ladder",
+		NetworkLength: 4,
+		LossPercent:   51,
+		Description:   "first ladder",
+	}))
+	require.NoError(t, collector.Add(Outcome{
+		Attack:        "ladder",
+		NetworkLength: 4,
+		LossPercent:   48,
+		Description:   "second ladder",
+	}))
+	require.NoError(t, collector.Add(Outcome{
+		Attack:        "surge",
+		NetworkLength: 10,
+		Cutoff:        9,
+		LossPercent:   12,
+		Description:   "surge",
+	}))
+
+	// Outcomes with slightly different losses share a class, and the
+	// first outcome added is kept as its example.
+	require.Equal(t, "2 distinct attack classes from 3 findings\n"+
+		"  - ladder attack (network length: 4, cutoff: 0, loss: ~50%): "+
+		"2 findings, e.g. first ladder\n"+
+		"  - surge attack (network length: 10, cutoff: 9, loss: ~10%): "+
+		"1 findings, e.g. surge", collector.Report())
+}
+
+// TestCollectorDir tests writing an example of each class to a directory,
+// keeping examples that were written by another collector in place.
+func TestCollectorDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "findings")
+	outcome := Outcome{
+		Attack:        "ladder",
+		NetworkLength: 4,
+		LossPercent:   51,
+		Description:   "first ladder",
+	}
+
+	require.NoError(t, NewCollector(dir).Add(outcome))
+
+	outcome.Description = "second ladder"
+	require.NoError(t, NewCollector(dir).Add(outcome))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "ladder-4-0-50", entries[0].Name())
+
+	example, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	require.NoError(t, err)
+	require.Equal(t, "ladder attack (network length: 4, cutoff: 0, "+
+		"loss: ~50%)\nfirst ladder\n", string(example))
+}
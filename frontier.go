@@ -0,0 +1,73 @@
+package reputationfuzz
+
+import "sort"
+
+// FrontierPoint is an attack option that lies on the cost-damage frontier for
+// a surge attack against a target node.
+type FrontierPoint struct {
+	// CutoffIndex is the index in the sorted set of peers up to which the
+	// attacker cuts off reputation.
+	CutoffIndex int
+
+	// Cost is the amount that the attacker pays to cut off peers.
+	Cost uint64
+
+	// Damage is the honest revenue that the target node is denied over
+	// the course of the attack.
+	Damage uint64
+}
+
+// paretoFrontier returns the set of surge attacks against a target node with
+// the set of peers provided that are not dominated by any other attack, ie
+// there is no other attack that does at least as much damage at lower cost.
+// Only attacks that cut off peers with good reputation are considered. If the
+// config groups channels by peer, cutoff indices refer to the sorted set of
+// grouped peers. Points are returned in order of increasing cost (and
+// damage).
+func paretoFrontier(honestPeers []uint64, cfg surgeAttackCfg) ([]FrontierPoint,
+	error) {
+
+	outcomes, err := surgeAttackAllCutoffs(honestPeers, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]FrontierPoint, 0, len(outcomes))
+	for i, outcome := range outcomes {
+		// Cutoffs beneath the config's band aren't valid attacks.
+		if outcome == nil || !outcome.hadGoodReputation() {
+			continue
+		}
+
+		candidates = append(candidates, FrontierPoint{
+			CutoffIndex: i,
+			Cost:        outcome.attackerPays(),
+			Damage:      outcome.peaceRevenue - outcome.attackRevenue,
+		})
+	}
+
+	// Sort by increasing cost, breaking ties with the most damaging attack
+	// so that we only keep the best option at each cost.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Cost == candidates[j].Cost {
+			return candidates[i].Damage > candidates[j].Damage
+		}
+
+		return candidates[i].Cost < candidates[j].Cost
+	})
+
+	// A point is only on the frontier if it does more damage than every
+	// cheaper option.
+	var frontier []FrontierPoint
+	for _, candidate := range candidates {
+		if len(frontier) > 0 &&
+			candidate.Damage <= frontier[len(frontier)-1].Damage {
+
+			continue
+		}
+
+		frontier = append(frontier, candidate)
+	}
+
+	return frontier, nil
+}
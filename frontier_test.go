@@ -0,0 +1,86 @@
+package reputationfuzz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestParetoFrontier tests that the frontier for a crafted target is monotone
+// and that no point on it is dominated by any other attack.
+func TestParetoFrontier(t *testing.T) {
+	peers := []uint64{
+		36_000_000_000, 12_000_000_000, 24_000_000_000,
+		12_000_000_000, 2_000_000_000, 24_000_000_000,
+		12_000_000_000, 36_000_000_000, 12_000_000_000,
+	}
+
+	frontier, err := paretoFrontier(peers, surgeAttackCfg{})
+	require.NoError(t, err)
+	require.NotEmpty(t, frontier)
+
+	// The caller's peers should not be reordered.
+	require.EqualValues(t, 36_000_000_000, peers[0])
+
+	for i := 1; i < len(frontier); i++ {
+		require.Greater(t, frontier[i].Cost, frontier[i-1].Cost)
+		require.Greater(t, frontier[i].Damage, frontier[i-1].Damage)
+	}
+
+	// Check every possible attack, asserting that none dominates a point
+	// on the frontier.
+	for i := range peers {
+		outcome, err := surgeAttack(peers, i, surgeAttackCfg{})
+		require.NoError(t, err)
+
+		if !outcome.hadGoodReputation() {
+			continue
+		}
+
+		cost := outcome.attackerPays()
+		damage := outcome.peaceRevenue - outcome.attackRevenue
+
+		for _, point := range frontier {
+			dominates := cost <= point.Cost && damage >= point.Damage &&
+				(cost < point.Cost || damage > point.Damage)
+			require.False(t, dominates, "cutoff %v dominates %v", i,
+				point)
+		}
+	}
+}
+
+// TestParetoFrontierGrouped tests that the frontier is built over grouped
+// peers when the config groups channels by peer.
+func TestParetoFrontierGrouped(t *testing.T) {
+	// The last two channels belong to the same peer, so there are nine
+	// peers that can be cut off.
+	peers := equalPeers(10, 12_000_000_000)
+	cfg := surgeAttackCfg{
+		peerGroups: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 8},
+	}
+
+	frontier, err := paretoFrontier(peers, cfg)
+	require.NoError(t, err)
+	require.NotEmpty(t, frontier)
+
+	// Every point matches modeling its cutoff separately.
+	for _, point := range frontier {
+		require.Less(t, point.CutoffIndex, 9)
+
+		outcome, err := surgeAttack(peers, point.CutoffIndex, cfg)
+		require.NoError(t, err)
+		require.Equal(t, outcome.attackerPays(), point.Cost)
+		require.Equal(t, outcome.peaceRevenue-outcome.attackRevenue,
+			point.Damage)
+	}
+
+	// Cutoffs beneath the band aren't on the frontier.
+	cfg.bandLowIndex = 2
+	frontier, err = paretoFrontier(peers, cfg)
+	require.NoError(t, err)
+	require.NotEmpty(t, frontier)
+
+	for _, point := range frontier {
+		require.GreaterOrEqual(t, point.CutoffIndex, 2)
+	}
+}
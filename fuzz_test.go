@@ -1,186 +1,459 @@
 package reputationfuzz
 
 import (
-	"encoding/binary"
-	"errors"
+	"bytes"
+	"encoding/json"
+	"flag"
 	"fmt"
-	"math/rand"
+	"os"
 	"testing"
+
+	"reputation-fuzz/findings"
+	"reputation-fuzz/stats"
 )
 
+// nearMissCloseness is the closeness above which fuzz scenarios that are not
+// successful attacks are logged as near misses.
+const nearMissCloseness = 0.9
+
+// minFindingMargin is the margin that a successful attack must exceed to be
+// reported as a fuzz failure. Successful attacks with smaller margins are only
+// logged, to cut down on noise from attacks that barely succeed.
+var minFindingMargin = flag.Int64(
+	"minmargin", 0, "minimum margin for a successful attack to fail the "+
+		"fuzz test",
+)
+
+// collectFindings enables collecting successful attacks into distinct classes
+// that are reported at the end of the run, rather than failing on each one.
+var collectFindings = flag.Bool(
+	"findings", false, "collect successful attacks into classes and "+
+		"report them at the end of the run rather than failing",
+)
+
+// findingsDirEnv is an environment variable that enables collecting findings
+// when set, naming a directory that an example of each class of attack is
+// written to. Fuzzing workers run in separate processes that don't report at
+// the end of the run, so this is required to collect findings with -fuzz.
+const findingsDirEnv = "REPUTATION_FUZZ_FINDINGS"
+
+// findingsCollector returns a collector for the fuzz test's successful attacks
+// that reports its findings when the test completes, or nil if findings are
+// not being collected.
+func findingsCollector(f *testing.F) *findings.Collector {
+	dir := os.Getenv(findingsDirEnv)
+	if !*collectFindings && dir == "" {
+		return nil
+	}
+
+	collector := findings.NewCollector(dir)
+	f.Cleanup(func() {
+		f.Log(collector.Report())
+	})
+
+	return collector
+}
+
+// statsFileEnv is an environment variable that names a CSV file that a row of
+// statistics is appended to for every scenario that the fuzz tests analyze,
+// whether or not the attack is successful.
+const statsFileEnv = "REPUTATION_FUZZ_STATS"
+
+// statsWriter returns a writer for the fuzz test's per-scenario statistics
+// that is closed when the test completes, or nil if statistics are not being
+// recorded.
+func statsWriter(f *testing.F) *stats.Writer {
+	path := os.Getenv(statsFileEnv)
+	if path == "" {
+		return nil
+	}
+
+	writer, err := stats.Open(path)
+	if err != nil {
+		f.Fatalf("Could not open stats file: %v", err)
+	}
+	f.Cleanup(func() {
+		if err := writer.Close(); err != nil {
+			f.Errorf("Could not close stats file: %v", err)
+		}
+	})
+
+	return writer
+}
+
+// paramsEnv is an environment variable that sets the reputation params that
+// the fuzz tests run with, as a JSON encoded Params such as
+// {"RevenuePeriodWeeks":1}. The defaults are used when it is unset. Fuzzing
+// workers run in separate processes that don't see test flags, so params are
+// set in the environment.
+const paramsEnv = "REPUTATION_FUZZ_PARAMS"
+
+// fuzzParams returns the reputation params that the fuzz test runs with.
+func fuzzParams(f *testing.F) Params {
+	var params Params
+
+	encoded := os.Getenv(paramsEnv)
+	if encoded == "" {
+		return params
+	}
+
+	decoder := json.NewDecoder(bytes.NewBufferString(encoded))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&params); err != nil {
+		f.Fatalf("Could not decode %v: %v", paramsEnv, err)
+	}
+
+	return params
+}
+
+// writeStats records a row of statistics for a scenario if statistics are
+// being recorded.
+func writeStats(t *testing.T, writer *stats.Writer, row stats.Row) {
+	if writer == nil {
+		return
+	}
+
+	if err := writer.Write(row); err != nil {
+		t.Fatalf("Could not write stats: %v", err)
+	}
+}
+
+// ladderSeedDefault is the ladder fuzz test's seed.
+var ladderSeedDefault = ladderSeed{
+	firstNodeTraffic:   120_000,
+	attackerPayment:    20_667,
+	cltvTotal:          300,
+	networkLength:      4,
+	networkDescription: encodePortions(10_000, 1_000, 2_500, 5_000),
+}
+
 // FuzzLadderAttack tests for scenarios where a fuzzing attack is economical
 // for an attacker, setting up various network patterns from the fuzzer's input.
 func FuzzLadderAttack(f *testing.F) {
 	f.Add(
-		uint64(120_000), uint64(20_667), uint64(300), uint8(4),
-		[]byte{100, 10, 25, 50},
+		ladderSeedDefault.firstNodeTraffic,
+		ladderSeedDefault.attackerPayment, ladderSeedDefault.cltvTotal,
+		ladderSeedDefault.networkLength,
+		ladderSeedDefault.networkDescription,
 	)
+	addLadderSeeds(f)
+
+	collector := findingsCollector(f)
+	statsFile := statsWriter(f)
+	params := fuzzParams(f)
 
 	f.Fuzz(func(t *testing.T, firstNodeTraffic, attackerPayment uint64,
 		cltvTotal uint64, networkLength uint8, networkDescription []byte) {
 
-		// We need to have at least 3 nodes in our network to run a
-		// meaningful test, and the current network diameter is 10 so
-		// we don't bother with more than that.
-		if networkLength < 3 || networkLength > 10 {
-			return
-		}
-
-		// Restrict hold time to protocol maximum.
-		if cltvTotal > 2016 {
+		scenario, err := decodeLadderInputs(
+			firstNodeTraffic, attackerPayment, cltvTotal,
+			networkLength, networkDescription,
+		)
+		if err != nil {
 			return
 		}
+		cfg := scenario.cfg
+		cfg.params = params
 
-		// We need at least one byte per node in the network to
-		// determine its traffic flow.
-		if len(networkDescription) < int(networkLength) {
+		// Configs that are invalid, amplify traffic beyond what we can
+		// represent or don't describe an interesting ladder and target
+		// are skipped.
+		result, err := runScenario(cfg, attackerPayment, cltvTotal)
+		if err != nil {
 			return
 		}
 
-		cfg := ladderingAttackCfg{
-			firstNodeTraffic: firstNodeTraffic,
-			trafficFlows:     make([]trafficFlow, networkLength),
-		}
-
-		for i := 0; i < int(networkLength); i++ {
-			// Make sure we have a value that's sane for a
-			// percentage.
-			portion := networkDescription[i]
-			if portion == 0 || portion > 100 {
-				return
-			}
+		var (
+			ladder        = result.Ladder
+			totalEndorsed = result.TotalEndorsed
+			outcome       = result.Outcome
+		)
 
-			cfg.trafficFlows[i] = trafficFlow{
-				trafficPortion: portion,
-			}
+		closeness := outcome.Closeness(attackerPayment)
+		if closeness >= nearMissCloseness && closeness <= 1 {
+			t.Logf("Near miss laddering attack (closeness: %.3f): "+
+				"%v with attacker payment: %v, outcome: %v",
+				closeness, ladder, attackerPayment, outcome)
 		}
 
-		ladder, err := newLadderingAttack(cfg)
-		if err != nil {
-			return
-		}
+		margin := outcome.Margin(attackerPayment)
+		writeStats(t, statsFile, stats.Row{
+			Attack:          "ladder",
+			NetworkLength:   len(cfg.trafficFlows),
+			AttackerPayment: attackerPayment,
+			CltvTotal:       cltvTotal,
+			Success:         outcome.Effective(attackerPayment),
+			LossPercent:     outcome.lossPercent(),
+			Margin:          margin,
+		})
 
-		// We want the revenue threshold for nodes along the ladder to
-		// be increasing, otherwise we're not actually testing a ladder
-		// of nodes (connecting to a big node to attack a small node is
-		// not a cost saving.
-		var preRevenue uint64
-		for _, channel := range ladder.channels {
-			if channel.outgoingRevenue < preRevenue {
-				return
-			}
+		if outcome.Effective(attackerPayment) &&
+			margin <= *minFindingMargin {
 
-			preRevenue = channel.outgoingRevenue
-		}
+			t.Logf("Low margin laddering attack (margin: %v): %v "+
+				"with attacker payment: %v, outcome: %v",
+				margin, ladder, attackerPayment, outcome)
 
-		// We need to have a cltv that's big enough for our route.
-		finalCltv, err := ladder.finalCLTV(cltvTotal)
-		if err != nil {
 			return
 		}
 
-		// Check that the target node can get at least 1000 msat
-		// endorsed with their peer, otherwise they're not a very
-		// interesting node to target.
-		channelCount := len(ladder.channels)
-		targetReputation := ladder.channels[channelCount-2].incomingReputation
-		peerThreshold := ladder.channels[channelCount-1].outgoingRevenue
-		minimumHTLC := htlcReputationCost(minimumHTLCReputation, finalCltv)
-
-		if targetReputation < peerThreshold+minimumHTLC {
-			return
-		}
+		if outcome.Effective(attackerPayment) && collector != nil {
+			err := collector.Add(findings.Outcome{
+				Attack:        "ladder",
+				NetworkLength: len(cfg.trafficFlows),
+				LossPercent:   outcome.lossPercent(),
+				Description: fmt.Sprintf("%v with attacker "+
+					"payment: %v, outcome: %v\nseed: %v",
+					ladder, attackerPayment, outcome,
+					scenario.SeedCorpus()),
+			})
+			if err != nil {
+				t.Fatalf("Could not collect finding: %v", err)
+			}
 
-		totalEndorsed, err := ladder.totalEndorsedOnTarget(
-			attackerPayment, cltvTotal,
-		)
-		if errors.Is(err, errInsufficientCltv) {
 			return
 		}
 
-		outcome := ladder.attackOutcome(totalEndorsed, cltvTotal)
-		if outcome.effective(attackerPayment) {
+		if outcome.Effective(attackerPayment) {
 			t.Errorf("Successful laddering attack: %v\n%v\n with "+
 				"first node: %v, attacker payment: %v, %v "+
-				"endorsed (height: %v) with outcome: %v", ladder,
+				"endorsed (height: %v, total capital: %v) with "+
+				"outcome: %v\nseed: %v", ladder,
 				cfg.trafficFlows, firstNodeTraffic,
 				attackerPayment, totalEndorsed, cltvTotal,
-				outcome)
+				outcome.TotalCapital(attackerPayment), outcome,
+				scenario.SeedCorpus())
 		}
 	})
 }
 
+// surgeSeedPeers is the encoded peer traffic of the surge fuzz test's seed.
+//
+// ChatGPT.
+var surgeSeedPeers = []byte{
+	0xD0, 0x07, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // 20000
+	0x90, 0xB6, 0x59, 0x3B, 0x00, 0x00, 0x00, 0x00, // 1000000000
+	0x1F, 0x4E, 0x44, 0x0A, 0x00, 0x00, 0x00, 0x00, // 172145567
+	0x2E, 0x11, 0x1A, 0x0B, 0x00, 0x00, 0x00, 0x00, // 184831534
+	0x5F, 0xA6, 0x38, 0x07, 0x00, 0x00, 0x00, 0x00, // 123435487
+	0x7A, 0xC3, 0x5B, 0x2F, 0x00, 0x00, 0x00, 0x00, // 796435450
+	0x4B, 0x20, 0x1C, 0x1A, 0x00, 0x00, 0x00, 0x00, // 437569355
+	0x1E, 0xAB, 0x33, 0x16, 0x00, 0x00, 0x00, 0x00, // 372389150
+	0x55, 0xF6, 0x48, 0x12, 0x00, 0x00, 0x00, 0x00, // 306875861
+	0x8C, 0xDA, 0x2C, 0x10, 0x00, 0x00, 0x00, 0x00, // 271043852
+}
+
 // FuzzSurgeAttack tests for scenarios where inflating the value of an outgoing
 // link so that honest peers lose reputation and then general jamming is a
 // successful strategy.
 func FuzzSurgeAttack(f *testing.F) {
-	// ChatGPT.
-	honestPeers := []byte{
-		0xD0, 0x07, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // 20000
-		0x90, 0xB6, 0x59, 0x3B, 0x00, 0x00, 0x00, 0x00, // 1000000000
-		0x1F, 0x4E, 0x44, 0x0A, 0x00, 0x00, 0x00, 0x00, // 172145567
-		0x2E, 0x11, 0x1A, 0x0B, 0x00, 0x00, 0x00, 0x00, // 184831534
-		0x5F, 0xA6, 0x38, 0x07, 0x00, 0x00, 0x00, 0x00, // 123435487
-		0x7A, 0xC3, 0x5B, 0x2F, 0x00, 0x00, 0x00, 0x00, // 796435450
-		0x4B, 0x20, 0x1C, 0x1A, 0x00, 0x00, 0x00, 0x00, // 437569355
-		0x1E, 0xAB, 0x33, 0x16, 0x00, 0x00, 0x00, 0x00, // 372389150
-		0x55, 0xF6, 0x48, 0x12, 0x00, 0x00, 0x00, 0x00, // 306875861
-		0x8C, 0xDA, 0x2C, 0x10, 0x00, 0x00, 0x00, 0x00, // 271043852
-	}
-	f.Add(uint32(10), honestPeers)
+	f.Add(uint32(10), uint32(9), surgeSeedPeers)
+
+	collector := findingsCollector(f)
+	statsFile := statsWriter(f)
+	params := fuzzParams(f)
+
+	f.Fuzz(func(t *testing.T, peerCount, cutoffIndex uint32,
+		peerTraffic []byte) {
 
-	f.Fuzz(func(t *testing.T, peerCount uint32, peerTraffic []byte) {
 		// Attacks are only interesting with 2+ nodes.
-		if peerCount < 2 || peerCount > 1000 {
+		if peerCount < 2 {
 			return
 		}
 
-		cutoff := int(rand.Intn(int(peerCount)))
+		// Derive our cutoff from the fuzzer's input so that failures
+		// can be reproduced, clamping it into [0, peerCount).
+		cutoff := int(cutoffIndex % peerCount)
 
-		// Cutoff must be a valid index in the peer count slice.
-		if cutoff >= int(peerCount) {
+		scenario, err := decodeSurgeInputs(
+			peerCount, cutoff, peerTraffic,
+		)
+		if err != nil {
 			return
 		}
+		honestPeers := scenario.honestPeers
 
-		// We need traffic flows expressed as uint64 for each node.
-		if len(peerTraffic) < int(peerCount)*8 {
+		outcome, err := SurgeAttack(
+			honestPeers, scenario.cutoffIndex,
+			SurgeConfig{Params: params},
+		)
+		if err != nil {
 			return
 		}
 
-		honestPeers := make([]uint64, peerCount)
-		for i := 0; i < int(peerCount); i++ {
-			fees := binary.LittleEndian.Uint64(peerTraffic[i*8 : (i+1)*8])
-			if fees == 0 {
-				return
-			}
+		networkStr := fmt.Sprintf("Peer count: %v, cutoff: %v:\n",
+			len(honestPeers), cutoff)
 
-			// Cut off fee around 1 btc in msat, reasonable ballpark.
-			if fees > 1_000_000_00_000 {
-				return
-			}
-			honestPeers[i] = fees
+		for _, peer := range honestPeers {
+			revenue := revenueFromReputation(peer, params)
+			networkStr = fmt.Sprintf("%v  - %v reputation (%vw) "+
+				"contributes %v revenue (%vw)\n", networkStr,
+				peer, params.reputationPeriod(), revenue,
+				params.revenuePeriod(),
+			)
 		}
 
-		outcome, err := surgeAttack(
-			honestPeers, int(cutoff),
+		// Include plain general jamming as a baseline, so that each
+		// finding shows whether the surge does better than brute force.
+		baseline, err := GeneralJam(
+			honestPeers, SurgeConfig{Params: params},
 		)
 		if err != nil {
 			return
 		}
+		networkStr = fmt.Sprintf("%v%v\nseed: %v\n", networkStr,
+			baseline, scenario.SeedCorpus())
 
-		networkStr := fmt.Sprintf("Peer count: %v, cutoff: %v:\n",
-			len(honestPeers), cutoff)
+		closeness := outcome.Closeness()
+		if closeness >= nearMissCloseness && closeness <= 1 {
+			t.Logf("Near miss surge attack (closeness: %.3f): %v "+
+				"with outcome: %v", closeness, networkStr, outcome)
+		}
 
-		for _, peer := range honestPeers {
-			networkStr = fmt.Sprintf("%v  - %v reputation (6m) "+
-				"contributes %v revenue (2w)\n", networkStr,
-				peer, revenueFromReputation(peer),
-			)
+		success, err := outcome.Success()
+		writeStats(t, statsFile, stats.Row{
+			Attack:        "surge",
+			NetworkLength: len(honestPeers),
+			Cutoff:        cutoff,
+			Success:       success && err == nil,
+			LossPercent:   outcome.lossPercent(),
+			Margin:        outcome.Margin(),
+		})
 
+		if success && err == nil && outcome.Margin() <= *minFindingMargin {
+			t.Logf("Low margin surge attack (margin: %v): %v with "+
+				"outcome: %v", outcome.Margin(), networkStr, outcome)
+
+			return
 		}
-		if success, err := outcome.success(); success || err != nil {
+
+		if success && err == nil && collector != nil {
+			err := collector.Add(findings.Outcome{
+				Attack:        "surge",
+				NetworkLength: len(honestPeers),
+				Cutoff:        cutoff,
+				LossPercent:   outcome.lossPercent(),
+				Description: fmt.Sprintf("%v with outcome: %v",
+					networkStr, outcome),
+			})
+			if err != nil {
+				t.Fatalf("Could not collect finding: %v", err)
+			}
+
+			return
+		}
+
+		if success || err != nil {
 			t.Errorf("Successful attack: %v with outcome: %v, %v",
 				networkStr, outcome, err)
 		}
 	})
 }
+
+// FuzzCombinedAttack tests for scenarios where an attacker that ladders up
+// reputation with a target node can surge the target's peers more cheaply than
+// with the best standalone attack. A single input is split into a ladder and a
+// surge scenario, see decodeCombinedInputs.
+func FuzzCombinedAttack(f *testing.F) {
+	// Start from each of the ladder seeds composed with the surge seed.
+	f.Add(encodeCombinedInputs(ladderSeedDefault, 10, 9, surgeSeedPeers))
+
+	seeds, err := loadLadderSeeds(ladderSeedDir, f.Logf)
+	if err != nil {
+		f.Fatalf("Could not load ladder seeds: %v", err)
+	}
+	for _, seed := range seeds {
+		f.Add(encodeCombinedInputs(seed, 10, 9, surgeSeedPeers))
+	}
+
+	collector := findingsCollector(f)
+	statsFile := statsWriter(f)
+	params := fuzzParams(f)
+	surgeCfg := surgeAttackCfg{params: params}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		scenario, err := decodeCombinedInputs(data)
+		if err != nil {
+			return
+		}
+
+		var (
+			ladderScenario = scenario.ladder
+			honestPeers    = scenario.surge.honestPeers
+			cutoff         = scenario.surge.cutoffIndex
+			ladderCfg      = ladderScenario.cfg
+		)
+		ladderCfg.params = params
+
+		// Ladders that aren't meaningful are skipped, as in the
+		// ladder fuzz test.
+		result, err := runScenario(
+			ladderCfg, ladderScenario.attackerPayment,
+			ladderScenario.cltvTotal,
+		)
+		if err != nil {
+			return
+		}
+
+		outcome, err := ladderThenSurge(
+			result.Ladder, ladderScenario.attackerPayment,
+			ladderScenario.cltvTotal, honestPeers, cutoff,
+			surgeCfg,
+		)
+		if err != nil {
+			return
+		}
+
+		_, bestSurge, _, err := bestSurgeCutoff(honestPeers, surgeCfg)
+		if err != nil {
+			return
+		}
+
+		success, err := outcome.surge.Success()
+		if err != nil {
+			return
+		}
+
+		effective := success && outcome.cheaperThanBest(bestSurge)
+		writeStats(t, statsFile, stats.Row{
+			Attack:          "combined",
+			NetworkLength:   len(ladderScenario.cfg.trafficFlows),
+			Cutoff:          cutoff,
+			AttackerPayment: outcome.cost(),
+			CltvTotal:       ladderScenario.cltvTotal,
+			Success:         effective,
+			LossPercent:     outcome.surge.lossPercent(),
+			Margin:          outcome.surge.Margin(),
+		})
+
+		// Only attacks that succeed and beat every standalone attack
+		// are meaningful findings.
+		if !effective {
+			return
+		}
+
+		description := fmt.Sprintf("%v with ladder: %v, honest peers: "+
+			"%v, cutoff: %v, combined cost: %v (%v ladder + %v "+
+			"surge), best standalone surge: %v, surge outcome: %v",
+			ladderScenario, result.Ladder, honestPeers, cutoff,
+			outcome.cost(), outcome.ladderPayment,
+			outcome.surge.attackerPays(), bestSurge, outcome.surge)
+
+		if collector != nil {
+			err := collector.Add(findings.Outcome{
+				Attack:        "combined",
+				NetworkLength: len(ladderScenario.cfg.trafficFlows),
+				Cutoff:        cutoff,
+				LossPercent:   outcome.surge.lossPercent(),
+				Description:   description,
+			})
+			if err != nil {
+				t.Fatalf("Could not collect finding: %v", err)
+			}
+
+			return
+		}
+
+		t.Errorf("Successful combined attack: %v", description)
+	})
+}
@@ -0,0 +1,153 @@
+package reputationfuzz
+
+import "fmt"
+
+// GeneralJamOutcome describes the impact of plainly general jamming a node for
+// its revenue period, without manipulating any reputation. It is a baseline
+// that the laddering and surge attacks can be measured against: an attack is
+// only interesting if it does better than brute force jamming.
+type GeneralJamOutcome struct {
+	// peaceRevenue is the revenue that the node earns over the revenue
+	// period when it isn't under attack.
+	peaceRevenue uint64
+
+	// revenueDenied is the revenue from peers that don't have good
+	// reputation, which rely on the general bucket that the attacker
+	// jams. Peers with good reputation can still use the protected
+	// bucket, so their revenue isn't denied.
+	revenueDenied uint64
+
+	// jamLiquidity is the liquidity that the attacker locks up in its
+	// general jamming HTLCs for the attack.
+	jamLiquidity uint64
+
+	// jamBlocks is the number of blocks that the attacker holds its
+	// jamming HTLCs for.
+	jamBlocks uint64
+
+	// capitalCost is the opportunity cost of the liquidity that the
+	// attacker locks up for the attack.
+	capitalCost uint64
+
+	// endorsedCost is the reputation that the attacker's jamming HTLCs
+	// would cost if they were endorsed, which is what the attacker would
+	// need to jam the protected bucket in the same way.
+	endorsedCost uint64
+}
+
+func (g *GeneralJamOutcome) String() string {
+	return fmt.Sprintf("General jam denied: %v of %v revenue (%v %%), "+
+		"attacker locked: %v for %v blocks at capital cost: %v, "+
+		"endorsed cost: %v", g.revenueDenied, g.peaceRevenue,
+		g.lossPercent(), g.jamLiquidity, g.jamBlocks, g.capitalCost,
+		g.endorsedCost)
+}
+
+// lossPercent returns the percentage of its peace time revenue that the node
+// loses to the jam.
+func (g *GeneralJamOutcome) lossPercent() uint64 {
+	if g.peaceRevenue == 0 {
+		return 0
+	}
+
+	return mulDiv(g.revenueDenied, 100, g.peaceRevenue)
+}
+
+// TotalCapital returns the total capital that the attacker needs to marshal
+// for the jam, which is the liquidity that it locks up in its HTLCs.
+func (g *GeneralJamOutcome) TotalCapital() uint64 {
+	return g.jamLiquidity
+}
+
+// Improvement returns the signed amount by which an attack that costs the
+// node the revenue provided improves on plainly general jamming it, which is
+// negative if the attack does worse than the baseline.
+func (g *GeneralJamOutcome) Improvement(revenueLost uint64) int64 {
+	return signedDiff(revenueLost, g.revenueDenied)
+}
+
+// generalJam models an attacker that fills the general bucket of a node with
+// the honest peers provided for its revenue period, without surging or
+// laddering any reputation. The node's threshold stays at its peace time
+// revenue, so only the peers that don't have good reputation lose access to
+// the node, and peers that are protected by the config are never denied. If
+// the config marks a peer as the attacker, its revenue is denied as well. The
+// attacker's HTLCs are held and failed back, so it pays nothing but the cost
+// of locking up the liquidity that it needs to fill the general bucket.
+func generalJam(honestPeers []uint64,
+	cfg surgeAttackCfg) (*GeneralJamOutcome, error) {
+
+	peers, err := cfg.sortedPeers(outgoingPeers(honestPeers))
+	if err != nil {
+		return nil, err
+	}
+
+	var peaceRevenue uint64
+	for _, peer := range peers {
+		peaceRevenue = saturatingAdd(
+			peaceRevenue, peer.revenue(cfg.params),
+		)
+	}
+
+	// If the node doesn't earn any revenue, there's nothing for the
+	// jam to deny it.
+	if peaceRevenue == 0 {
+		return nil, fmt.Errorf("%w: %v peers", errZeroRevenue,
+			len(peers))
+	}
+
+	// Reuse the surge outcome's threshold so that peers need the same
+	// reputation to be considered good with and without a surge.
+	threshold := (&SurgeAttackOutcome{
+		peaceRevenue:     peaceRevenue,
+		thresholdPercent: cfg.thresholdPercent,
+	}).threshold()
+
+	var (
+		htlcEndorsed = budgetForEndorsedValue(
+			cfg.params.minimumHTLC(), 100, cfg.params,
+		)
+		goodReputation = saturatingAdd(threshold, htlcEndorsed)
+
+		revenueDenied uint64
+	)
+
+	for _, peer := range peers {
+		// An insider attacker stops forwarding its own traffic.
+		if peer.insider {
+			revenueDenied = saturatingAdd(
+				revenueDenied, peer.revenue(cfg.params),
+			)
+			continue
+		}
+
+		if cfg.protected(peer) || peer.reputation() >= goodReputation {
+			continue
+		}
+
+		revenueDenied = saturatingAdd(
+			revenueDenied, peer.revenue(cfg.params),
+		)
+	}
+
+	var (
+		jamLiquidity = cfg.generalJamLiquidity()
+		jamBlocks    = saturatingMul(
+			cfg.params.revenuePeriod(), cfg.params.blocksPerWeek(),
+		)
+	)
+
+	return &GeneralJamOutcome{
+		peaceRevenue:  peaceRevenue,
+		revenueDenied: revenueDenied,
+		jamLiquidity:  jamLiquidity,
+		jamBlocks:     jamBlocks,
+		capitalCost: capitalCost(
+			jamLiquidity, jamBlocks, cfg.capitalRateBasisPoints,
+			cfg.params,
+		),
+		endorsedCost: budgetForEndorsedValue(
+			jamLiquidity, jamBlocks, cfg.params,
+		),
+	}, nil
+}
@@ -0,0 +1,85 @@
+package reputationfuzz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestGeneralJam tests the baseline of plainly general jamming a node, which
+// only denies the node revenue from peers that don't have good reputation.
+func TestGeneralJam(t *testing.T) {
+	// Two small peers that don't have good reputation, and eight large
+	// peers that can still use the protected bucket.
+	peers := []uint64{
+		3_000_000_000, 6_000_000_000, 60_000_000_000, 60_000_000_000,
+		60_000_000_000, 60_000_000_000, 60_000_000_000, 60_000_000_000,
+		60_000_000_000, 60_000_000_000,
+	}
+
+	cfg := surgeAttackCfg{
+		jamLiquidity:           1_000_000_000,
+		capitalRateBasisPoints: 500,
+	}
+
+	jam, err := generalJam(peers, cfg)
+	require.NoError(t, err)
+	require.Equal(t, &GeneralJamOutcome{
+		peaceRevenue:  40_750_000_000,
+		revenueDenied: 750_000_000,
+		jamLiquidity:  1_000_000_000,
+		jamBlocks:     2016,
+		capitalCost:   1_917_808,
+		endorsedCost:  13_440_000_000_000,
+	}, jam)
+	require.EqualValues(t, 1, jam.lossPercent())
+	require.EqualValues(t, 1_000_000_000, jam.TotalCapital())
+
+	// Cutting off every peer with a surge costs the node far more than
+	// the baseline.
+	surge, err := surgeAttack(peers, 9, cfg)
+	require.NoError(t, err)
+
+	success, err := surge.Success()
+	require.NoError(t, err)
+	require.True(t, success)
+	require.EqualValues(t, surge.Margin(), surge.revenueLost())
+	require.Positive(t, jam.Improvement(surge.revenueLost()))
+	require.Negative(t, jam.Improvement(0))
+
+	// Peers that are protected from the surge aren't denied by the jam
+	// either.
+	cfg.allowlist = make([]bool, len(peers))
+	cfg.allowlist[1] = true
+
+	jam, err = generalJam(peers, cfg)
+	require.NoError(t, err)
+	require.EqualValues(t, 250_000_000, jam.revenueDenied)
+
+	// The exported function matches the internal model.
+	exported, err := GeneralJam(peers, SurgeConfig{
+		Allowlist:              cfg.allowlist,
+		JamLiquidity:           1_000_000_000,
+		CapitalRateBasisPoints: 500,
+	})
+	require.NoError(t, err)
+	require.Equal(t, jam, exported)
+
+	// An insider attacker stops forwarding its own traffic.
+	cfg.insider = make([]bool, len(peers))
+	cfg.insider[9] = true
+
+	jam, err = generalJam(peers, cfg)
+	require.NoError(t, err)
+	require.EqualValues(t, 5_250_000_000, jam.revenueDenied)
+
+	// Without a configured liquidity, the attacker fills every slot on
+	// the link with a minimum sized HTLC.
+	jam, err = generalJam(peers, surgeAttackCfg{})
+	require.NoError(t, err)
+	require.EqualValues(t, 483_000, jam.TotalCapital())
+	require.EqualValues(t, 6_491_520_000, jam.endorsedCost)
+
+	_, err = generalJam([]uint64{5, 11}, surgeAttackCfg{})
+	require.ErrorIs(t, err, errZeroRevenue)
+}
@@ -0,0 +1,74 @@
+package reputationfuzz
+
+// holdCost describes the cheapest effective laddering attack when the
+// attacker holds its HTLCs for a given number of blocks.
+type holdCost struct {
+	// htlcHold is the total cltv that the attacker holds its HTLCs for.
+	htlcHold uint64
+
+	// effective indicates whether any payment results in an effective
+	// attack at this hold time. The remaining fields are only set if the
+	// attack is effective.
+	effective bool
+
+	// payment is the smallest payment for which the attack is effective.
+	payment uint64
+
+	// cost is the total cost of the ladder at the smallest payment,
+	// including the opportunity cost of the capital that it locks up.
+	cost uint64
+
+	// outcome is the outcome of the attack at the smallest payment.
+	outcome AttackOutcome
+}
+
+// cheapestHold sweeps every hold time from the smallest that covers the
+// route's cltv delta up to the protocol maximum, and returns the hold time at
+// which an effective attack is cheapest for the attacker. Longer holds burn
+// more of the target's reputation per msat endorsed, but each msat that is
+// endorsed also costs the attacker more reputation to acquire and locks up its
+// capital for longer, so the cost isn't monotonic in hold time and every hold
+// is evaluated. If several holds are equally cheap, the shortest is returned.
+// A false boolean is returned if the attack isn't effective at any hold time.
+//
+// If withCurve is set, the cost at every hold time is also returned in order
+// of increasing hold time, so that the relationship can be inspected.
+func (l *LadderingAttack) cheapestHold(withCurve bool) (holdCost, bool,
+	[]holdCost, error) {
+
+	var (
+		best  holdCost
+		curve []holdCost
+	)
+
+	for hold := l.requiredCltv(); hold <= maxCltvTotal; hold++ {
+		payment, outcome, ok, err := l.minEffectivePayment(hold)
+		if err != nil {
+			return holdCost{}, false, nil, err
+		}
+
+		point := holdCost{
+			htlcHold: hold,
+		}
+		if ok {
+			point.effective = true
+			point.payment = payment
+			point.cost = outcome.ladderCost(payment)
+			point.outcome = outcome
+		}
+
+		if withCurve {
+			curve = append(curve, point)
+		}
+
+		if !point.effective {
+			continue
+		}
+
+		if !best.effective || point.cost < best.cost {
+			best = point
+		}
+	}
+
+	return best, best.effective, curve, nil
+}
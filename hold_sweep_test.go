@@ -0,0 +1,77 @@
+package reputationfuzz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestCheapestHold tests finding the hold time at which a laddering attack is
+// cheapest for the attacker.
+func TestCheapestHold(t *testing.T) {
+	scenario := newScenario(
+		1_000_000_000, []uint8{100, 50, 50, 25}, 1_000_000_000, 1000,
+	)
+	scenario.cfg.lastHopWeightPercent = 50
+	scenario.cfg.capitalRateBasisPoints = 500
+
+	attack, err := newLadderingAttack(scenario.cfg)
+	require.NoError(t, err)
+
+	best, ok, curve, err := attack.cheapestHold(true)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.True(t, best.outcome.Effective(best.payment))
+
+	// The best hold's payment is the smallest that is effective at that
+	// hold, and its cost is the cost of the ladder at that payment.
+	payment, outcome, ok, err := attack.minEffectivePayment(best.htlcHold)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, payment, best.payment)
+	require.Equal(t, outcome.ladderCost(payment), best.cost)
+
+	endorsed, err := attack.TotalEndorsedOnTarget(
+		best.payment-1, best.htlcHold,
+	)
+	require.NoError(t, err)
+	require.False(t, attack.Outcome(endorsed, best.htlcHold).Effective(
+		best.payment-1,
+	))
+
+	// The curve covers every hold from the route's cltv delta up to the
+	// protocol maximum. Short holds leave too little time on the target
+	// for the attack to be effective.
+	require.Len(t, curve, maxCltvTotal-280+1)
+	require.EqualValues(t, 280, curve[0].htlcHold)
+	require.False(t, curve[0].effective)
+
+	// The best hold is the shortest of the cheapest points on the curve.
+	for _, point := range curve {
+		if !point.effective {
+			continue
+		}
+
+		require.GreaterOrEqual(t, point.cost, best.cost)
+		if point.htlcHold < best.htlcHold {
+			require.Greater(t, point.cost, best.cost)
+		}
+	}
+	require.Equal(t, best, curve[best.htlcHold-280])
+
+	// The curve is only returned when it's requested.
+	withoutCurve, ok, curve, err := attack.cheapestHold(false)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Nil(t, curve)
+	require.Equal(t, best, withoutCurve)
+
+	// The network from TestLadderAttackSetup is never effective.
+	scenario = newScenario(120_000, []uint8{100, 10, 25, 50}, 30_000, 300)
+	attack, err = newLadderingAttack(scenario.cfg)
+	require.NoError(t, err)
+
+	_, ok, _, err = attack.cheapestHold(false)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
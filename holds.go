@@ -0,0 +1,80 @@
+package reputationfuzz
+
+// holdBucket describes a number of HTLCs that an attacker holds for the same
+// number of blocks.
+type holdBucket struct {
+	// count is the number of HTLCs in the bucket.
+	count uint64
+
+	// hold is the number of blocks that each HTLC is held for.
+	hold uint64
+}
+
+// endorsedForHolds returns the total value that can be endorsed with the
+// reputation surplus provided when it is spread across a distribution of
+// HTLCs with different hold times, which must be sorted from longest to
+// shortest hold.
+//
+// Each HTLC is the same size, so the surplus pays for HTLCs at the sum of
+// their hold times. If the surplus can't afford every HTLC, the attacker
+// prefers the HTLCs with the longest hold times because they burn the most
+// reputation per msat endorsed, so HTLCs are dropped from the shortest holds
+// first. Zero is returned if the surplus can't afford a single HTLC at the
+// longest hold time.
+func endorsedForHolds(reputationSurplus uint64, holds []holdBucket,
+	level uint8, params Params) uint64 {
+
+	// totalHold returns the sum of the hold times of the longest count
+	// HTLCs in the distribution.
+	totalHold := func(count uint64) uint64 {
+		var total uint64
+		for _, bucket := range holds {
+			if count == 0 {
+				break
+			}
+
+			htlcs := bucket.count
+			if htlcs > count {
+				htlcs = count
+			}
+
+			total = saturatingAdd(
+				total, saturatingMul(htlcs, bucket.hold),
+			)
+			count -= htlcs
+		}
+
+		return total
+	}
+
+	htlcSize := func(count uint64) uint64 {
+		return htlcSizeAtLevel(
+			reputationSurplus, totalHold(count), level, params,
+		)
+	}
+
+	if htlcSize(1) == 0 {
+		return 0
+	}
+
+	var htlcCount uint64
+	for _, bucket := range holds {
+		htlcCount = saturatingAdd(htlcCount, bucket.count)
+	}
+
+	// The size that we can afford per HTLC only decreases as we add more
+	// HTLCs, so we search for the largest number of HTLCs that we can
+	// afford.
+	low, high := uint64(1), htlcCount
+	for low < high {
+		mid := low + (high-low+1)/2
+
+		if htlcSize(mid) == 0 {
+			high = mid - 1
+		} else {
+			low = mid
+		}
+	}
+
+	return saturatingMul(htlcSize(low), low)
+}
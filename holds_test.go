@@ -0,0 +1,102 @@
+package reputationfuzz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestEndorsedForHolds tests spreading a reputation surplus across HTLCs with
+// different hold times.
+func TestEndorsedForHolds(t *testing.T) {
+	// A single HTLC gets the full surplus endorsed at its hold time.
+	require.EqualValues(t, 3000, endorsedForHolds(
+		6_000_000, []holdBucket{{count: 1, hold: 300}},
+		maxEndorsementLevel, Params{},
+	))
+
+	// Mixing in shorter holds stretches the surplus further: 4 HTLCs
+	// held for a total of 800 blocks can each be 1125 msat.
+	require.EqualValues(t, 4500, endorsedForHolds(
+		6_000_000, []holdBucket{
+			{count: 2, hold: 300},
+			{count: 2, hold: 100},
+		}, maxEndorsementLevel, Params{},
+	))
+
+	// When the surplus can't afford every HTLC, the shortest holds are
+	// dropped first. We can afford HTLCs for a total of 900,000 blocks,
+	// which is the long hold plus 8997 of the short ones.
+	require.EqualValues(t, 8998, endorsedForHolds(
+		6_000_000, []holdBucket{
+			{count: 1, hold: 300},
+			{count: 10_000, hold: 100},
+		}, maxEndorsementLevel, Params{},
+	))
+
+	// If we can't afford a single HTLC at the longest hold, nothing is
+	// endorsed even though the shorter hold would be affordable.
+	require.Zero(t, endorsedForHolds(
+		1000, []holdBucket{
+			{count: 1, hold: 2016},
+			{count: 5, hold: 40},
+		}, maxEndorsementLevel, Params{},
+	))
+	require.NotZero(t, endorsedForHolds(
+		1000, []holdBucket{{count: 1, hold: 40}},
+		maxEndorsementLevel, Params{},
+	))
+}
+
+// TestTotalEndorsedForHolds tests that a single hold time is the degenerate
+// case of a hold time distribution, and that distributions are validated.
+func TestTotalEndorsedForHolds(t *testing.T) {
+	scenario := newScenario(
+		1_000_000, []uint8{100, 50, 100, 100}, 1_000_000, 300,
+	)
+	attack, err := newLadderingAttack(scenario.cfg)
+	require.NoError(t, err)
+
+	single, err := attack.TotalEndorsedOnTarget(1_000_000, 300)
+	require.NoError(t, err)
+
+	endorsed, err := attack.totalEndorsedForHolds(
+		1_000_000, []holdBucket{{count: 1, hold: 300}},
+	)
+	require.NoError(t, err)
+	require.Equal(t, single, endorsed)
+
+	// Spreading the payment across more HTLCs with shorter holds gets more
+	// value endorsed on the target.
+	endorsed, err = attack.totalEndorsedForHolds(
+		1_000_000, []holdBucket{
+			{count: 1, hold: 300},
+			{count: 3, hold: 290},
+		},
+	)
+	require.NoError(t, err)
+	require.Greater(t, endorsed, single)
+
+	_, err = attack.totalEndorsedForHolds(1_000_000, nil)
+	require.ErrorIs(t, err, errNoHolds)
+
+	_, err = attack.totalEndorsedForHolds(
+		1_000_000, []holdBucket{{count: 0, hold: 300}},
+	)
+	require.ErrorIs(t, err, errNoHolds)
+
+	// Every hold needs to cover the route's cltv delta.
+	_, err = attack.totalEndorsedForHolds(
+		1_000_000, []holdBucket{
+			{count: 1, hold: 300},
+			{count: 1, hold: 100},
+		},
+	)
+	require.ErrorIs(t, err, errInsufficientCltv)
+
+	// Each HTLC occupies a slot on every hop.
+	_, err = attack.totalEndorsedForHolds(
+		1_000_000, []holdBucket{{count: 500, hold: 300}},
+	)
+	require.ErrorIs(t, err, errInsufficientSlots)
+}
@@ -0,0 +1,35 @@
+package reputationfuzz
+
+// successCurve maps a node's reputation to the estimated success rate of the
+// payments that it sends, expressed as a probability in [0, 1].
+type successCurve func(reputation uint64) float64
+
+// stepSuccessCurve returns a success curve where payments from a node with
+// reputation at or above the threshold succeed at the reputable rate, because
+// they can access protected resources, and payments from nodes below it
+// succeed at the lower unreputable rate.
+func stepSuccessCurve(threshold uint64, reputable,
+	unreputable float64) successCurve {
+
+	return func(reputation uint64) float64 {
+		if reputation >= threshold {
+			return reputable
+		}
+
+		return unreputable
+	}
+}
+
+// failureRateIncrease estimates the increase in the target's payment failure
+// rate caused by the reputation that it loses in an attack, using the success
+// curve provided.
+func failureRateIncrease(outcome AttackOutcome, curve successCurve) float64 {
+	before := outcome.targetReputation
+
+	var after uint64
+	if outcome.reputationChange < before {
+		after = before - outcome.reputationChange
+	}
+
+	return curve(before) - curve(after)
+}
@@ -0,0 +1,34 @@
+package reputationfuzz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestFailureRateIncrease tests that a large loss of reputation produces a
+// meaningful increase in the target's payment failure rate, while a small
+// loss does not.
+func TestFailureRateIncrease(t *testing.T) {
+	outcome := AttackOutcome{
+		targetReputation: 1_000_000,
+		targetThreshold:  800_000,
+	}
+
+	// Payments succeed 95% of the time when the target has reputation
+	// above its threshold, and 60% of the time otherwise.
+	curve := stepSuccessCurve(outcome.targetThreshold, 0.95, 0.6)
+
+	// A small change leaves the target above its threshold.
+	outcome.reputationChange = 100_000
+	require.Zero(t, failureRateIncrease(outcome, curve))
+
+	// A large change pushes the target below its threshold.
+	outcome.reputationChange = 300_000
+	require.InDelta(t, 0.35, failureRateIncrease(outcome, curve), 1e-9)
+
+	// Losing more reputation than the target had saturates at zero
+	// reputation.
+	outcome.reputationChange = 2_000_000
+	require.InDelta(t, 0.35, failureRateIncrease(outcome, curve), 1e-9)
+}
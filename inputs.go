@@ -0,0 +1,244 @@
+package reputationfuzz
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	// maxNetworkLength is the largest number of nodes that we'll fuzz in a
+	// ladder, based on the current network diameter.
+	maxNetworkLength = 10
+
+	// portionBytes is the number of bytes used to describe each node's
+	// traffic portion in the ladder fuzz test's network description.
+	portionBytes = 2
+
+	// maxCltvTotal is the protocol maximum for the total cltv of a route.
+	maxCltvTotal = 2016
+
+	// maxPeerCount is the largest number of peers that we'll fuzz for a
+	// surge attack.
+	maxPeerCount = 1000
+
+	// maxPeerFees is the largest fee revenue that we'll accept for a peer,
+	// around 1 btc in msat which is a reasonable ballpark.
+	maxPeerFees = 1_000_000_00_000
+)
+
+var (
+	// errSkipInput is returned when fuzz inputs don't describe a
+	// meaningful scenario, and should be skipped.
+	errSkipInput = errors.New("skip input")
+)
+
+// decodeLadderInputs validates the raw inputs to the ladder fuzz test and
+// converts them into a scenario, returning an error wrapping errSkipInput if
+// they don't describe a meaningful scenario.
+func decodeLadderInputs(firstNodeTraffic, attackerPayment, cltvTotal uint64,
+	networkLength uint8, networkDescription []byte) (ladderScenario, error) {
+
+	// We need to have at least 3 nodes in our network to run a meaningful
+	// test, and the current network diameter is 10 so we don't bother with
+	// more than that.
+	if networkLength < 3 || networkLength > maxNetworkLength {
+		return ladderScenario{}, fmt.Errorf("%w: network length: %v "+
+			"not in [3, %v]", errSkipInput, networkLength,
+			maxNetworkLength)
+	}
+
+	// Restrict hold time to protocol maximum.
+	if cltvTotal > maxCltvTotal {
+		return ladderScenario{}, fmt.Errorf("%w: cltv total: %v > %v",
+			errSkipInput, cltvTotal, maxCltvTotal)
+	}
+
+	// We need two bytes per node in the network to determine its traffic
+	// flow, expressed in basis points.
+	if len(networkDescription) < int(networkLength)*portionBytes {
+		return ladderScenario{}, fmt.Errorf("%w: network description "+
+			"length: %v < %v bytes per node * network length: %v",
+			errSkipInput, len(networkDescription), portionBytes,
+			networkLength)
+	}
+
+	cfg := ladderingAttackCfg{
+		firstNodeTraffic: firstNodeTraffic,
+		trafficFlows:     make([]trafficFlow, networkLength),
+	}
+
+	for i := 0; i < int(networkLength); i++ {
+		// Make sure we have a value that's sane for basis points.
+		portion := binary.LittleEndian.Uint16(
+			networkDescription[i*portionBytes : (i+1)*portionBytes],
+		)
+		if portion == 0 || portion > basisPoints {
+			return ladderScenario{}, fmt.Errorf("%w: traffic "+
+				"portion: %v not in [1, %v]", errSkipInput,
+				portion, basisPoints)
+		}
+
+		cfg.trafficFlows[i] = trafficFlow{
+			portionBasisPoints: portion,
+		}
+	}
+
+	return ladderScenario{
+		cfg:             cfg,
+		attackerPayment: attackerPayment,
+		cltvTotal:       cltvTotal,
+	}, nil
+}
+
+// surgeScenario describes a single surge attack to be evaluated.
+type surgeScenario struct {
+	honestPeers []uint64
+
+	cutoffIndex int
+}
+
+// SeedCorpus returns the scenario as a call that adds it to the surge fuzz
+// test's seed corpus, in Go source form, so that a finding can be pasted back
+// into the fuzz test to reproduce it. Each peer's fees are encoded as little
+// endian uint64s, and the cutoff is already within the peer count so the fuzz
+// test derives the same cutoff from it.
+func (s surgeScenario) SeedCorpus() string {
+	peerTraffic := make([]byte, 0, len(s.honestPeers)*8)
+	for _, peer := range s.honestPeers {
+		peerTraffic = binary.LittleEndian.AppendUint64(
+			peerTraffic, peer,
+		)
+	}
+
+	return fmt.Sprintf("f.Add(uint32(%v), uint32(%v), %#v)",
+		len(s.honestPeers), s.cutoffIndex, peerTraffic)
+}
+
+// decodeSurgeInputs validates the raw inputs to the surge fuzz test and
+// converts them into a scenario, returning an error wrapping errSkipInput if
+// they don't describe a meaningful scenario.
+func decodeSurgeInputs(peerCount uint32, cutoff int,
+	peerTraffic []byte) (surgeScenario, error) {
+
+	// Attacks are only interesting with 2+ nodes.
+	if peerCount < 2 || peerCount > maxPeerCount {
+		return surgeScenario{}, fmt.Errorf("%w: peer count: %v not in "+
+			"[2, %v]", errSkipInput, peerCount, maxPeerCount)
+	}
+
+	// Cutoff must be a valid index in the peer count slice.
+	if cutoff < 0 || cutoff >= int(peerCount) {
+		return surgeScenario{}, fmt.Errorf("%w: cutoff: %v not in "+
+			"[0, %v)", errSkipInput, cutoff, peerCount)
+	}
+
+	// We need traffic flows expressed as uint64 for each node.
+	if len(peerTraffic) < int(peerCount)*8 {
+		return surgeScenario{}, fmt.Errorf("%w: peer traffic length: "+
+			"%v < %v", errSkipInput, len(peerTraffic), peerCount*8)
+	}
+
+	honestPeers := make([]uint64, peerCount)
+	for i := 0; i < int(peerCount); i++ {
+		fees := binary.LittleEndian.Uint64(peerTraffic[i*8 : (i+1)*8])
+		if fees == 0 || fees > maxPeerFees {
+			return surgeScenario{}, fmt.Errorf("%w: peer fees: %v "+
+				"not in [1, %v]", errSkipInput, fees,
+				maxPeerFees)
+		}
+
+		honestPeers[i] = fees
+	}
+
+	return surgeScenario{
+		honestPeers: honestPeers,
+		cutoffIndex: cutoff,
+	}, nil
+}
+
+const (
+	// combinedLadderHeaderLen is the length of the fixed size fields at
+	// the start of the ladder half of a combined fuzz input:
+	// firstNodeTraffic, attackerPayment and cltvTotal as little endian
+	// uint64s, followed by a single networkLength byte.
+	combinedLadderHeaderLen = 8*3 + 1
+
+	// combinedSurgeHeaderLen is the length of the fixed size fields at the
+	// start of the surge half of a combined fuzz input: peerCount and
+	// cutoffIndex as little endian uint32s.
+	combinedSurgeHeaderLen = 4 * 2
+)
+
+// combinedScenario describes a laddering attack on a target node and the
+// peers of that node that the attacker surges once it has laddered.
+type combinedScenario struct {
+	ladder ladderScenario
+
+	surge surgeScenario
+}
+
+// decodeCombinedInputs splits the raw input to the combined fuzz test into a
+// ladder and a surge scenario, returning an error wrapping errSkipInput if
+// either half doesn't describe a meaningful scenario.
+//
+// The ladder half comes first, with a fixed size header followed by two bytes
+// per node in the network, so its length is set by the network length that it
+// encodes. The surge half takes up the remainder of the input, with a fixed
+// size header followed by eight bytes per peer. Each half is laid out in the
+// argument order of its standalone fuzz test, so the fuzzer can mutate one
+// half without shifting the fields of the other.
+func decodeCombinedInputs(data []byte) (combinedScenario, error) {
+	if len(data) < combinedLadderHeaderLen {
+		return combinedScenario{}, fmt.Errorf("%w: input length: %v < "+
+			"%v", errSkipInput, len(data), combinedLadderHeaderLen)
+	}
+
+	var (
+		networkLength = data[24]
+		ladderLen     = combinedLadderHeaderLen +
+			int(networkLength)*portionBytes
+	)
+
+	if len(data) < ladderLen+combinedSurgeHeaderLen {
+		return combinedScenario{}, fmt.Errorf("%w: input length: %v < "+
+			"ladder: %v + surge header: %v", errSkipInput,
+			len(data), ladderLen, combinedSurgeHeaderLen)
+	}
+
+	ladder, err := decodeLadderInputs(
+		binary.LittleEndian.Uint64(data[0:8]),
+		binary.LittleEndian.Uint64(data[8:16]),
+		binary.LittleEndian.Uint64(data[16:24]), networkLength,
+		data[combinedLadderHeaderLen:ladderLen],
+	)
+	if err != nil {
+		return combinedScenario{}, err
+	}
+
+	var (
+		surgeData   = data[ladderLen:]
+		peerCount   = binary.LittleEndian.Uint32(surgeData[0:4])
+		cutoffIndex = binary.LittleEndian.Uint32(surgeData[4:8])
+	)
+
+	// Derive the cutoff in the same way as the surge fuzz test so that
+	// its inputs can be reused, clamping it into [0, peerCount).
+	if peerCount < 2 {
+		return combinedScenario{}, fmt.Errorf("%w: peer count: %v < 2",
+			errSkipInput, peerCount)
+	}
+
+	surge, err := decodeSurgeInputs(
+		peerCount, int(cutoffIndex%peerCount),
+		surgeData[combinedSurgeHeaderLen:],
+	)
+	if err != nil {
+		return combinedScenario{}, err
+	}
+
+	return combinedScenario{
+		ladder: ladder,
+		surge:  surge,
+	}, nil
+}
@@ -0,0 +1,402 @@
+package reputationfuzz
+
+import (
+	"encoding/binary"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestDecodeLadderInputs tests validation of raw ladder fuzz inputs.
+func TestDecodeLadderInputs(t *testing.T) {
+	tests := []struct {
+		name          string
+		cltvTotal     uint64
+		networkLength uint8
+		description   []byte
+		err           error
+	}{
+		{
+			name:          "valid",
+			cltvTotal:     300,
+			networkLength: 4,
+			description:   encodePortions(10_000, 1_000, 2_500, 5_000),
+		},
+		{
+			name:          "extra description ignored",
+			cltvTotal:     300,
+			networkLength: 3,
+			description:   encodePortions(10_000, 1_000, 2_500, 0),
+		},
+		{
+			name:          "network too short",
+			cltvTotal:     300,
+			networkLength: 2,
+			description:   encodePortions(10_000, 1_000),
+			err:           errSkipInput,
+		},
+		{
+			name:          "network too long",
+			cltvTotal:     300,
+			networkLength: 11,
+			description:   make([]byte, 22),
+			err:           errSkipInput,
+		},
+		{
+			name:          "cltv above protocol max",
+			cltvTotal:     2017,
+			networkLength: 4,
+			description:   encodePortions(10_000, 1_000, 2_500, 5_000),
+			err:           errSkipInput,
+		},
+		{
+			name:          "description too short",
+			cltvTotal:     300,
+			networkLength: 4,
+			description:   encodePortions(10_000, 1_000, 2_500),
+			err:           errSkipInput,
+		},
+		{
+			name:          "sub-percent portion",
+			cltvTotal:     300,
+			networkLength: 4,
+			description:   encodePortions(10_000, 50, 2_500, 5_000),
+		},
+		{
+			name:          "description odd length",
+			cltvTotal:     300,
+			networkLength: 4,
+			description: encodePortions(
+				10_000, 1_000, 2_500, 5_000,
+			)[:7],
+			err: errSkipInput,
+		},
+		{
+			name:          "zero portion",
+			cltvTotal:     300,
+			networkLength: 4,
+			description:   encodePortions(10_000, 0, 2_500, 5_000),
+			err:           errSkipInput,
+		},
+		{
+			name:          "portion over 10000",
+			cltvTotal:     300,
+			networkLength: 4,
+			description:   encodePortions(10_000, 1_000, 10_100, 5_000),
+			err:           errSkipInput,
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			scenario, err := decodeLadderInputs(
+				120_000, 20_000, testCase.cltvTotal,
+				testCase.networkLength, testCase.description,
+			)
+			require.ErrorIs(t, err, testCase.err)
+			if testCase.err != nil {
+				return
+			}
+
+			require.EqualValues(t, 120_000,
+				scenario.cfg.firstNodeTraffic)
+			require.EqualValues(t, 20_000, scenario.attackerPayment)
+			require.Equal(t, testCase.cltvTotal, scenario.cltvTotal)
+			require.Len(t, scenario.cfg.trafficFlows,
+				int(testCase.networkLength))
+
+			for i, flow := range scenario.cfg.trafficFlows {
+				require.Equal(t,
+					binary.LittleEndian.Uint16(
+						testCase.description[i*2:],
+					), flow.portionBasisPoints)
+			}
+		})
+	}
+}
+
+// encodePortions encodes traffic portions in basis points as little endian
+// uint16s.
+func encodePortions(portions ...uint16) []byte {
+	encoded := make([]byte, 0, len(portions)*portionBytes)
+	for _, portion := range portions {
+		encoded = binary.LittleEndian.AppendUint16(encoded, portion)
+	}
+
+	return encoded
+}
+
+// encodePeers encodes peer fees as little endian uint64s.
+func encodePeers(peers ...uint64) []byte {
+	encoded := make([]byte, 0, len(peers)*8)
+	for _, peer := range peers {
+		encoded = binary.LittleEndian.AppendUint64(encoded, peer)
+	}
+
+	return encoded
+}
+
+// TestDecodeSurgeInputs tests validation of raw surge fuzz inputs.
+func TestDecodeSurgeInputs(t *testing.T) {
+	tests := []struct {
+		name      string
+		peerCount uint32
+		cutoff    int
+		traffic   []byte
+		err       error
+	}{
+		{
+			name:      "valid",
+			peerCount: 3,
+			cutoff:    1,
+			traffic:   encodePeers(100, 200, 300),
+		},
+		{
+			name:      "too few peers",
+			peerCount: 1,
+			cutoff:    0,
+			traffic:   encodePeers(100),
+			err:       errSkipInput,
+		},
+		{
+			name:      "too many peers",
+			peerCount: 1001,
+			cutoff:    0,
+			traffic:   make([]byte, 1001*8),
+			err:       errSkipInput,
+		},
+		{
+			name:      "cutoff out of range",
+			peerCount: 3,
+			cutoff:    3,
+			traffic:   encodePeers(100, 200, 300),
+			err:       errSkipInput,
+		},
+		{
+			name:      "negative cutoff",
+			peerCount: 3,
+			cutoff:    -1,
+			traffic:   encodePeers(100, 200, 300),
+			err:       errSkipInput,
+		},
+		{
+			name:      "traffic too short",
+			peerCount: 3,
+			cutoff:    1,
+			traffic:   encodePeers(100, 200),
+			err:       errSkipInput,
+		},
+		{
+			name:      "zero fees",
+			peerCount: 3,
+			cutoff:    1,
+			traffic:   encodePeers(100, 0, 300),
+			err:       errSkipInput,
+		},
+		{
+			name:      "fees too large",
+			peerCount: 3,
+			cutoff:    1,
+			traffic:   encodePeers(100, maxPeerFees+1, 300),
+			err:       errSkipInput,
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			scenario, err := decodeSurgeInputs(
+				testCase.peerCount, testCase.cutoff,
+				testCase.traffic,
+			)
+			require.ErrorIs(t, err, testCase.err)
+			if testCase.err != nil {
+				return
+			}
+
+			require.Equal(t, []uint64{100, 200, 300},
+				scenario.honestPeers)
+			require.Equal(t, testCase.cutoff, scenario.cutoffIndex)
+		})
+	}
+}
+
+// encodeCombinedInputs encodes a ladder and a surge scenario as a single
+// combined fuzz input. Only the portions of the ladder's network description
+// that are covered by its network length are included.
+func encodeCombinedInputs(ladder ladderSeed, peerCount, cutoffIndex uint32,
+	peerTraffic []byte) []byte {
+
+	covered := int(ladder.networkLength) * portionBytes
+	if len(ladder.networkDescription) > covered {
+		ladder.networkDescription = ladder.networkDescription[:covered]
+	}
+
+	encoded := ladder.encode()
+	encoded = binary.LittleEndian.AppendUint32(encoded, peerCount)
+	encoded = binary.LittleEndian.AppendUint32(encoded, cutoffIndex)
+
+	return append(encoded, peerTraffic...)
+}
+
+// TestDecodeCombinedInputs tests splitting a combined fuzz input into its
+// ladder and surge halves.
+func TestDecodeCombinedInputs(t *testing.T) {
+	data := encodeCombinedInputs(ladderSeedDefault, 10, 19, surgeSeedPeers)
+
+	scenario, err := decodeCombinedInputs(data)
+	require.NoError(t, err)
+
+	ladder, err := decodeLadderInputs(
+		ladderSeedDefault.firstNodeTraffic,
+		ladderSeedDefault.attackerPayment, ladderSeedDefault.cltvTotal,
+		ladderSeedDefault.networkLength,
+		ladderSeedDefault.networkDescription,
+	)
+	require.NoError(t, err)
+	require.Equal(t, ladder, scenario.ladder)
+
+	// The cutoff is clamped into the peer count.
+	surge, err := decodeSurgeInputs(10, 9, surgeSeedPeers)
+	require.NoError(t, err)
+	require.Equal(t, surge, scenario.surge)
+
+	// Peers in the surge half are validated as in the surge fuzz test.
+	data[len(data)-1] = 0xff
+	_, err = decodeCombinedInputs(data)
+	require.ErrorIs(t, err, errSkipInput)
+
+	// Changing the surge half doesn't change the ladder half.
+	data = encodeCombinedInputs(
+		ladderSeedDefault, 2, 0, encodePeers(1_000, 2_000),
+	)
+	scenario, err = decodeCombinedInputs(data)
+	require.NoError(t, err)
+	require.Equal(t, ladder, scenario.ladder)
+	require.Equal(t, []uint64{1_000, 2_000}, scenario.surge.honestPeers)
+
+	// Inputs that are too short for either half are skipped.
+	_, err = decodeCombinedInputs(data[:combinedLadderHeaderLen-1])
+	require.ErrorIs(t, err, errSkipInput)
+
+	ladderLen := combinedLadderHeaderLen + 4*portionBytes
+	_, err = decodeCombinedInputs(data[:ladderLen+combinedSurgeHeaderLen-1])
+	require.ErrorIs(t, err, errSkipInput)
+
+	// A single peer can't be surged.
+	data = encodeCombinedInputs(ladderSeedDefault, 1, 0, encodePeers(1_000))
+	_, err = decodeCombinedInputs(data)
+	require.ErrorIs(t, err, errSkipInput)
+}
+
+// parseSeedCorpus parses a call that adds a seed to a fuzz test's corpus, as
+// returned by SeedCorpus, into the arguments that it passes.
+func parseSeedCorpus(t *testing.T, seed string) []any {
+	expr, err := parser.ParseExpr(seed)
+	require.NoError(t, err)
+
+	call, ok := expr.(*ast.CallExpr)
+	require.True(t, ok, "not a call: %v", seed)
+
+	fun, ok := call.Fun.(*ast.SelectorExpr)
+	require.True(t, ok, "not a method call: %v", seed)
+	require.Equal(t, "Add", fun.Sel.Name)
+
+	// parseUint parses an integer literal that fits in the bit size
+	// provided.
+	parseUint := func(expr ast.Expr, bitSize int) uint64 {
+		lit, ok := expr.(*ast.BasicLit)
+		require.True(t, ok, "not a literal: %v", expr)
+		require.Equal(t, token.INT, lit.Kind)
+
+		value, err := strconv.ParseUint(lit.Value, 0, bitSize)
+		require.NoError(t, err)
+
+		return value
+	}
+
+	args := make([]any, len(call.Args))
+	for i, arg := range call.Args {
+		switch arg := arg.(type) {
+		// Integers are converted to their type, eg uint64(1).
+		case *ast.CallExpr:
+			conversion, ok := arg.Fun.(*ast.Ident)
+			require.True(t, ok, "not a conversion: %v", arg)
+			require.Len(t, arg.Args, 1)
+
+			switch conversion.Name {
+			case "uint64":
+				args[i] = parseUint(arg.Args[0], 64)
+
+			case "uint32":
+				args[i] = uint32(parseUint(arg.Args[0], 32))
+
+			case "uint8":
+				args[i] = uint8(parseUint(arg.Args[0], 8))
+
+			default:
+				t.Fatalf("Unexpected conversion: %v",
+					conversion.Name)
+			}
+
+		// Byte slices are composite literals, eg []byte{0x1}.
+		case *ast.CompositeLit:
+			encoded := make([]byte, len(arg.Elts))
+			for j, elt := range arg.Elts {
+				encoded[j] = byte(parseUint(elt, 8))
+			}
+			args[i] = encoded
+
+		default:
+			t.Fatalf("Unexpected argument: %T", arg)
+		}
+	}
+
+	return args
+}
+
+// TestSurgeSeedCorpus tests that a surge scenario's seed corpus entry
+// reproduces the same attack when it is fed back through the fuzz test's
+// inputs.
+func TestSurgeSeedCorpus(t *testing.T) {
+	scenario, err := decodeSurgeInputs(10, 3, surgeSeedPeers)
+	require.NoError(t, err)
+
+	seed := scenario.SeedCorpus()
+	require.True(t, strings.HasPrefix(
+		seed, "f.Add(uint32(10), uint32(3), []byte{0xd0, 0x7, 0x0,",
+	), seed)
+
+	args := parseSeedCorpus(t, seed)
+	require.Len(t, args, 3)
+
+	var (
+		peerCount   = args[0].(uint32)
+		cutoffIndex = args[1].(uint32)
+		peerTraffic = args[2].([]byte)
+	)
+	require.Equal(t, surgeSeedPeers, peerTraffic)
+
+	// Feed the seed through the fuzz test's inputs, deriving the cutoff
+	// in the same way.
+	reproduced, err := decodeSurgeInputs(
+		peerCount, int(cutoffIndex%peerCount), peerTraffic,
+	)
+	require.NoError(t, err)
+	require.Equal(t, scenario, reproduced)
+
+	expected, err := SurgeAttack(
+		scenario.honestPeers, scenario.cutoffIndex, SurgeConfig{},
+	)
+	require.NoError(t, err)
+
+	outcome, err := SurgeAttack(
+		reproduced.honestPeers, reproduced.cutoffIndex, SurgeConfig{},
+	)
+	require.NoError(t, err)
+	require.Equal(t, expected, outcome)
+}
@@ -3,25 +3,115 @@ package reputationfuzz
 import (
 	"errors"
 	"fmt"
+	"math"
+	"math/bits"
+	"sort"
 )
 
 const (
-	revenuePeriodWeeks = 2
+	cltvDelta uint64 = 80
 
-	reputationPeriodWeeks = 24
+	// finalCltvDelta is the cltv delta required by the final node in the
+	// route when per-hop deltas are not specified.
+	finalCltvDelta uint64 = 40
 
-	cltvDelta uint64 = 80
+	// defaultMinHTLCSize is the default size of the smallest HTLC that
+	// the attacker can use to occupy a slot, expressed in msat.
+	defaultMinHTLCSize uint64 = 1000
+
+	// basisPoints is the number of basis points in a whole.
+	basisPoints = 10_000
+
+	// maxHTLCSlots is the maximum number of HTLCs that can be in flight
+	// on a channel in one direction, as set by the protocol.
+	maxHTLCSlots uint16 = 483
 )
 
 var (
 	errInsufficientCltv = errors.New("insufficient cltv")
+
+	errZeroFinalCltv = errors.New("final cltv delta must be non-zero")
+
+	errThresholdUnreachable = errors.New("threshold unreachable")
+
+	errNoRecovery = errors.New("reputation can't recover")
+
+	errInsufficientSlots = errors.New("insufficient slots")
+
+	errNoHolds = errors.New("no hold times")
+
+	errTrafficOverflow = errors.New("traffic overflow")
+
+	errZeroRevenue = errors.New("zero revenue")
 )
 
-type ladderingAttack struct {
+// LadderingAttack models an attacker that builds reputation along a ladder of
+// nodes with increasing revenue to sabotage the reputation of a target node.
+type LadderingAttack struct {
 	channels []channel
+
+	// timeAveraged indicates that the target's reputation is evaluated as
+	// an average over the revenue period rather than at a single point in
+	// time.
+	timeAveraged bool
+
+	// recencyWeighting is applied to traffic when calculating reputation,
+	// nil if all traffic in the reputation period is counted uniformly.
+	recencyWeighting reputationWeighting
+
+	// hopBuildWeeks is the number of weeks that the attacker spends
+	// building each hop deeper than the first.
+	hopBuildWeeks uint64
+
+	// weeklyDecayPercent is the percentage of reputation that the attacker
+	// loses with the first node each week that they aren't routing there.
+	weeklyDecayPercent uint8
+
+	// attackerOnProbation indicates that the attacker's reputation is
+	// ignored by the first node.
+	attackerOnProbation bool
+
+	// endorsementLevel is the level of endorsement that the attacker
+	// requires on each hop, from 1 to maxEndorsementLevel.
+	endorsementLevel uint8
+
+	// weeklyGrowthCap is the maximum amount of reputation that can be
+	// gained per week, zero if uncapped.
+	weeklyGrowthCap uint64
+
+	// attackerSlots is the number of endorsed slots that the attacker
+	// occupies on each hop to hold its HTLCs.
+	attackerSlots uint16
+
+	// channelPurchaseCost is the price of buying a channel that is
+	// already reputable with the target, zero if channels can't be bought.
+	channelPurchaseCost uint64
+
+	// params holds the parameters of the reputation algorithm.
+	params Params
+
+	// minHTLCSize is the size of the smallest HTLC that the attacker can
+	// use to occupy a slot, zero if the default is used.
+	minHTLCSize uint64
+
+	// protectedSplit indicates that channel slots are split into protected
+	// and general buckets, so the attack must occupy the protected bucket.
+	protectedSplit bool
+
+	// protectedLiquidity is the liquidity reserved for the protected
+	// bucket on the target's outgoing link, zero if it isn't limited.
+	protectedLiquidity uint64
+
+	// capitalRateBasisPoints is the annualized rate of return that the
+	// attacker forgoes on capital locked in its HTLCs, in basis points.
+	capitalRateBasisPoints uint16
+
+	// settlePercent is the percentage of the attacker's payments that
+	// settle.
+	settlePercent uint8
 }
 
-func (l *ladderingAttack) String() string {
+func (l *LadderingAttack) String() string {
 	str := fmt.Sprintf("Channels: %v", len(l.channels))
 
 	for _, channel := range l.channels {
@@ -35,6 +125,33 @@ func (l *ladderingAttack) String() string {
 type channel struct {
 	incomingReputation uint64
 	outgoingRevenue    uint64
+
+	// roundTripPercent is the percentage of reputation that the incoming
+	// peer builds with this node that counts towards its reputation, zero
+	// if round trips are not required.
+	roundTripPercent uint8
+
+	// slotCapacity is the number of endorsed slots available on the
+	// channel's outgoing link.
+	slotCapacity uint16
+
+	// fees is the fee policy that the node charges on its outgoing link.
+	fees FeePolicy
+
+	// cltvDelta is the number of blocks that the node subtracts from the
+	// HTLC's expiry when it forwards it. For the final channel in the
+	// route, this is the final cltv delta required by the recipient.
+	cltvDelta uint64
+}
+
+// peerReputation returns the reputation that an incoming peer has with the
+// channel, accounting for any round trip requirement.
+func (c channel) peerReputation(reputation uint64) uint64 {
+	if c.roundTripPercent == 0 {
+		return reputation
+	}
+
+	return mulDiv(reputation, uint64(c.roundTripPercent), 100)
 }
 
 type ladderingAttackCfg struct {
@@ -60,13 +177,231 @@ type ladderingAttackCfg struct {
 	// laddering - eg in A --- B --- C --- D, we're trying to target C's
 	// reputation with D.
 	trafficFlows []trafficFlow
+
+	// timeAveraged evaluates the target's reputation as an average over
+	// the revenue period, rather than at the point in time that the jam is
+	// in effect. This smooths out the impact of jams that are short
+	// relative to the period over which reputation is assessed.
+	timeAveraged bool
+
+	// recencyWeighting optionally weights the traffic that contributes to
+	// reputation by how recently it was forwarded, so that recent forwards
+	// count for more. If nil, the weighting set by the reputation params
+	// is used, which counts all traffic in the reputation period uniformly
+	// by default.
+	recencyWeighting reputationWeighting
+
+	// hopBuildWeeks models the attacker building the ladder sequentially,
+	// spending this number of weeks on each hop after the first. While the
+	// attacker is busy building deeper in the ladder, they stop routing
+	// through the first node so their reputation there decays. A zero
+	// value assumes that all reputation is built simultaneously.
+	hopBuildWeeks uint64
+
+	// weeklyDecayPercent is the percentage of the attacker's reputation
+	// with the first node that decays for each week that they are not
+	// routing through it.
+	weeklyDecayPercent uint8
+
+	// lastHopWeightPercent scales the reputation that the target earns
+	// with the final node, reflecting that delivering the last hop of a
+	// payment may earn reputation differently to intermediate forwarding.
+	// A zero value weights the last hop the same as every other hop.
+	lastHopWeightPercent uint16
+
+	// attackerOnProbation places the attacker on probation with the first
+	// node in the ladder, as it may do for a peer that has recently behaved
+	// suspiciously. The reputation of a peer on probation is ignored for
+	// the purpose of endorsement.
+	attackerOnProbation bool
+
+	// endorsementLevel models a graded endorsement scheme, where HTLCs can
+	// be endorsed at levels 0-7 rather than just endorsed or unendorsed.
+	// The attacker requires this level of endorsement on each hop, which
+	// requires a proportional share of the reputation needed for full
+	// endorsement. Level 0 is not endorsed, so a zero value requires full
+	// endorsement.
+	endorsementLevel uint8
+
+	// weeklyGrowthCap caps the amount of reputation that a node can gain
+	// in each week (epoch), regardless of the traffic it forwards. Any
+	// traffic beyond the cap in a week is wasted, which slows down an
+	// attacker that tries to build reputation quickly. A zero value does
+	// not cap reputation growth.
+	weeklyGrowthCap uint64
+
+	// targetUptimeLoss is the number of percentage points of uptime that
+	// the attacker is able to knock off the target, for example through
+	// resource exhaustion. This compounds a reputation attack in designs
+	// where reputation depends on uptime.
+	targetUptimeLoss uint8
+
+	// attackerSlots is the number of HTLCs that the attacker splits its
+	// endorsed amount across, each of which occupies an endorsed slot on
+	// every hop in the ladder. A zero value assumes that the attacker uses
+	// a single HTLC.
+	attackerSlots uint16
+
+	// channelPurchaseCost is the price that an attacker would pay to buy
+	// a channel (and the reputation that it has built) with the target
+	// that is reputable enough to perform the jam directly. This models a
+	// secondary market for channels that allows an attacker to skip
+	// laddering altogether. A zero value indicates that channels can't be
+	// bought.
+	channelPurchaseCost uint64
+
+	// params holds the parameters of the reputation algorithm, using the
+	// defaults if unset.
+	params Params
+
+	// minHTLCSize is the size of the smallest HTLC that the attacker can
+	// use to occupy a slot, expressed in msat. An attacker that can't
+	// jam a channel's liquidity may still be able to exhaust its slots by
+	// splitting its endorsed amount into HTLCs of this size. A zero value
+	// uses a default of 1000 msat.
+	minHTLCSize uint64
+
+	// protectedSlotPercent splits each channel's slots into a protected
+	// bucket that is reserved for endorsed HTLCs from reputable peers and
+	// a general bucket for everything else. The protected bucket holds
+	// this percentage of each channel's slot capacity. When the split is
+	// set, the attacker can fill the general bucket freely, so the attack
+	// is only harmful if the attacker's endorsed HTLCs can occupy the
+	// whole protected bucket on the target's outgoing link. A zero value
+	// doesn't split the channel's resources.
+	protectedSlotPercent uint8
+
+	// protectedLiquidity is the amount of liquidity in msat that is
+	// reserved for the protected bucket on the target's outgoing link. The
+	// attacker occupies the protected bucket if it fills either its slots
+	// or its liquidity. A zero value doesn't limit protected liquidity.
+	protectedLiquidity uint64
+
+	// capitalRateBasisPoints is the annualized rate of return that the
+	// attacker could earn on its capital elsewhere, in basis points. The
+	// endorsed amount that the attacker locks up in its jamming HTLCs
+	// forgoes this return for the duration of the hold, which is added to
+	// the cost of the ladder and of attacking the target directly. A zero
+	// value ignores the opportunity cost of locked capital.
+	capitalRateBasisPoints uint16
+
+	// settlePercent optionally sets the percentage of the attacker's
+	// payments that settle. Reputation only accrues from HTLCs that
+	// resolve successfully, and the attacker burns the first node's fees
+	// on the payments that settle, so an attacker that only probes the
+	// ladder with payments that fail earns no reputation. A nil value
+	// settles every payment, so that zero can model a pure prober.
+	settlePercent *uint8
 }
 
 type trafficFlow struct {
-	trafficPortion uint8
+	// portionBasisPoints is the portion of the node's outgoing traffic
+	// that is provided by the node that precedes it, expressed in basis
+	// points (1-10,000) so that nodes which contribute a small fraction of
+	// their downstream's traffic can be modeled.
+	portionBasisPoints uint16
+
+	// roundTripPercent is the percentage of the traffic that the incoming
+	// peer forwards to this node that is routed in both directions. Some
+	// designs only count bidirectional traffic towards reputation to
+	// prevent one-way reputation farming, so only this portion of the
+	// peer's reputation counts. A zero value indicates that round trips are
+	// not required, so all of the peer's reputation counts.
+	roundTripPercent uint8
+
+	// uptimePercent is the percentage of time that the node is online. In
+	// some designs a node that is frequently offline earns less
+	// reputation, so the reputation it builds with its outgoing peer is
+	// scaled by its uptime. A zero value indicates full uptime.
+	uptimePercent uint8
+
+	// slotCapacity is the number of slots that the node makes available
+	// for endorsed HTLCs on its outgoing link. A zero value indicates that
+	// the protocol maximum of 483 slots is available.
+	slotCapacity uint16
+
+	// feePolicy is the fee policy that the node charges on its outgoing
+	// link. Reputation and revenue are expressed in the fees that nodes
+	// earn, so the revenue on the node's outgoing link depends on its own
+	// fees and the reputation that its incoming peer builds depends on
+	// this node's fees. A zero value counts traffic volume 1:1.
+	feePolicy FeePolicy
+
+	// weeklyProfile optionally describes how the node's traffic is
+	// distributed over the reputation period, with each entry holding the
+	// relative weight of a week's traffic, starting with the most recent
+	// week. Bursty traffic changes how much of the node's traffic falls in
+	// the revenue period and how it's weighted for reputation, for
+	// example a node whose traffic was front-loaded months ago has low
+	// recent revenue and, under a decaying reputation, low reputation.
+	// Weeks beyond the end of the profile have no traffic. If empty, the
+	// node's traffic is assumed to be constant.
+	weeklyProfile []uint64
+
+	// cltvDelta is the cltv delta that the node requires to forward
+	// HTLCs, or the final cltv delta for the last flow in the route. If
+	// zero, the node requires the default delta of 80 blocks. The final
+	// delta defaults to 40 blocks, unless any flow in the route sets a
+	// delta, in which case the final delta must be set as well.
+	cltvDelta uint64
+}
+
+// volumes returns the portion of the total traffic provided that falls in the
+// revenue period, and the reputation volume that the traffic represents once
+// weighted, using the node's weekly profile if it has one. Otherwise, a
+// constant rate of traffic is assumed, which allows us to move between time
+// horizons by scaling.
+func (t trafficFlow) volumes(total, revenuePeriod, reputationPeriod uint64,
+	weighting reputationWeighting) (uint64, uint64) {
+
+	if len(t.weeklyProfile) == 0 {
+		return mulDiv(total, revenuePeriod, reputationPeriod),
+			weightedReputation(total, reputationPeriod, weighting)
+	}
+
+	weekly := distributeTraffic(total, t.weeklyProfile, reputationPeriod)
+
+	var revenue uint64
+	for weeksAgo := 0; weeksAgo < len(weekly); weeksAgo++ {
+		if uint64(weeksAgo) >= revenuePeriod {
+			break
+		}
+
+		revenue = saturatingAdd(revenue, weekly[weeksAgo])
+	}
+
+	return revenue, profileReputation(weekly, reputationPeriod, weighting)
 }
 
-func newLadderingAttack(cfg ladderingAttackCfg) (*ladderingAttack, error) {
+// slots returns the number of endorsed slots available on the outgoing link.
+func (t trafficFlow) slots() uint16 {
+	if t.slotCapacity == 0 {
+		return maxHTLCSlots
+	}
+
+	return t.slotCapacity
+}
+
+// uptime returns the node's uptime as a percentage.
+func (t trafficFlow) uptime() uint64 {
+	if t.uptimePercent == 0 || t.uptimePercent > 100 {
+		return 100
+	}
+
+	return uint64(t.uptimePercent)
+}
+
+// degradeUptime reduces an uptime percentage by the number of percentage
+// points provided, saturating at zero.
+func degradeUptime(uptime uint64, loss uint8) uint64 {
+	if uint64(loss) >= uptime {
+		return 0
+	}
+
+	return uptime - uint64(loss)
+}
+
+func newLadderingAttack(cfg ladderingAttackCfg) (*LadderingAttack, error) {
 	incomingTraffic := cfg.firstNodeTraffic
 
 	if len(cfg.trafficFlows) < 3 {
@@ -74,67 +409,511 @@ func newLadderingAttack(cfg ladderingAttackCfg) (*ladderingAttack, error) {
 			len(cfg.trafficFlows))
 	}
 
+	var perHopCltv bool
+	for _, traffic := range cfg.trafficFlows {
+		if traffic.cltvDelta != 0 {
+			perHopCltv = true
+			break
+		}
+	}
+
+	if cfg.protectedSlotPercent > 100 {
+		return nil, fmt.Errorf("protected slot percent: %v > 100",
+			cfg.protectedSlotPercent)
+	}
+
+	settlePercent := uint8(100)
+	if cfg.settlePercent != nil {
+		settlePercent = *cfg.settlePercent
+	}
+
+	if settlePercent > 100 {
+		return nil, fmt.Errorf("settle percent: %v > 100",
+			settlePercent)
+	}
+
+	finalFlow := cfg.trafficFlows[len(cfg.trafficFlows)-1]
+	if perHopCltv && finalFlow.cltvDelta == 0 {
+		return nil, errZeroFinalCltv
+	}
+
+	var (
+		revenuePeriod    = cfg.params.revenuePeriod()
+		reputationPeriod = cfg.params.reputationPeriod()
+
+		// If no recency weighting is provided, we use the weighting
+		// set by our reputation params (if any).
+		weighting = cfg.recencyWeighting
+	)
+
+	if weighting == nil {
+		weighting = cfg.params.weighting()
+	}
+
 	channels := make([]channel, 0, len(cfg.trafficFlows))
 
-	for _, traffic := range cfg.trafficFlows {
-		// Our traffic portion indicates the percentage of our traffic
-		// over the outgoing link that the incoming traffic contributes
-		// to. We use this value to calculate the total traffic that we
-		// have flowing over out outgoing link.
+	for i, traffic := range cfg.trafficFlows {
+		// Our traffic portion indicates the basis points of our
+		// traffic over the outgoing link that the incoming traffic
+		// contributes to. We use this value to calculate the total
+		// traffic that we have flowing over our outgoing link.
 		//
 		// This is expressed over a 6 month period, as that's the period
 		// that our incoming traffic is expressed over.
-		incomingTraffic = incomingTraffic * 100 / uint64(traffic.trafficPortion)
+		//
+		// Small portions amplify our traffic at each hop, so we fail
+		// rather than wrapping around if the traffic overflows.
+		portion := traffic.portionBasisPoints
+		if portion == 0 || portion > basisPoints {
+			return nil, fmt.Errorf("hop %v traffic portion: %v "+
+				"not in [1, %v]", i, portion, basisPoints)
+		}
+
+		hi, lo := bits.Mul64(incomingTraffic, basisPoints)
+		if hi != 0 {
+			return nil, fmt.Errorf("%w: hop %v traffic: %v * %v",
+				errTrafficOverflow, i, incomingTraffic,
+				basisPoints)
+		}
+		incomingTraffic = lo / uint64(portion)
 
 		// The revenue score that we assign our outgoing link is tracked
-		// over a 2 week period, so we adjust this period to get our
-		// total. Note that this assumes a constant rate of traffic,
-		// which allows us to move between time horizons.
-		outgoingRevenue := incomingTraffic * revenuePeriodWeeks / reputationPeriodWeeks
+		// over a 2 week period, so we find the traffic in this period
+		// to get our total. Revenue is the fees that the current node
+		// earns on this traffic.
+		revenueVolume, reputationVolume := traffic.volumes(
+			incomingTraffic, revenuePeriod, reputationPeriod,
+			weighting,
+		)
+		outgoingRevenue := traffic.feePolicy.feeOrPassThrough(
+			revenueVolume,
+		)
+
+		// The reputation that the node builds with its outgoing peer
+		// is the fees that the *next* node earns on the traffic that
+		// it forwards. The final node's peer isn't part of our route,
+		// so we count its traffic 1:1.
+		var nextFees FeePolicy
+		if i < len(cfg.trafficFlows)-1 {
+			nextFees = cfg.trafficFlows[i+1].feePolicy
+		}
+
+		// The target is the penultimate node in the route, and the
+		// attacker may be able to reduce its uptime.
+		uptime := traffic.uptime()
+		if i == len(cfg.trafficFlows)-2 {
+			uptime = degradeUptime(uptime, cfg.targetUptimeLoss)
+		}
+
+		// Nodes that don't set a delta require the default, and the
+		// final delta only defaults when no flow sets a delta.
+		delta := traffic.cltvDelta
+		switch {
+		case delta != 0:
+
+		case i != len(cfg.trafficFlows)-1:
+			delta = cltvDelta
+
+		case !perHopCltv:
+			delta = finalCltvDelta
+		}
+
 		channels = append(channels, channel{
-			// TODO: reputation depends on the *next* node's fees.
-			incomingReputation: incomingTraffic,
-			// TODO: revenue depends on the *current* node's fees.
-			outgoingRevenue: outgoingRevenue,
+			incomingReputation: mulDiv(
+				capReputation(
+					nextFees.feeOrPassThrough(
+						reputationVolume,
+					),
+					reputationPeriod, cfg.weeklyGrowthCap,
+				), uptime, 100,
+			),
+			outgoingRevenue:  outgoingRevenue,
+			roundTripPercent: traffic.roundTripPercent,
+			slotCapacity: protectedSlots(
+				traffic.slots(), cfg.protectedSlotPercent,
+			),
+			fees:      traffic.feePolicy,
+			cltvDelta: delta,
 		})
 	}
 
-	return &ladderingAttack{
-		channels: channels,
+	// The final node's revenue is the threshold that the target's
+	// reputation is measured against. If the traffic is too small for it
+	// to earn any fees, any reputation clears the threshold and the
+	// target's good reputation is meaningless.
+	if final := channels[len(channels)-1]; final.outgoingRevenue == 0 {
+		return nil, fmt.Errorf("%w: final node", errZeroRevenue)
+	}
+
+	// The target is the penultimate node in the route, so its reputation
+	// with the final node is earned on the last hop.
+	if cfg.lastHopWeightPercent != 0 {
+		target := &channels[len(channels)-2]
+		target.incomingReputation = mulDiv(
+			target.incomingReputation,
+			uint64(cfg.lastHopWeightPercent), 100,
+		)
+	}
+
+	attackerSlots := cfg.attackerSlots
+	if attackerSlots == 0 {
+		attackerSlots = 1
+	}
+
+	// A HTLC at level zero is not endorsed, so the config's zero value
+	// requires full endorsement.
+	endorsementLevel := cfg.endorsementLevel
+	if endorsementLevel == 0 {
+		endorsementLevel = maxEndorsementLevel
+	}
+
+	return &LadderingAttack{
+		channels:            channels,
+		timeAveraged:        cfg.timeAveraged,
+		recencyWeighting:    weighting,
+		hopBuildWeeks:       cfg.hopBuildWeeks,
+		weeklyDecayPercent:  cfg.weeklyDecayPercent,
+		attackerOnProbation: cfg.attackerOnProbation,
+		endorsementLevel:    endorsementLevel,
+		weeklyGrowthCap:     cfg.weeklyGrowthCap,
+		attackerSlots:       attackerSlots,
+		channelPurchaseCost: cfg.channelPurchaseCost,
+		params:              cfg.params,
+		minHTLCSize:         cfg.minHTLCSize,
+		protectedSplit:      cfg.protectedSlotPercent != 0,
+		protectedLiquidity:  cfg.protectedLiquidity,
+
+		capitalRateBasisPoints: cfg.capitalRateBasisPoints,
+		settlePercent:          settlePercent,
 	}, nil
 }
 
-func (l *ladderingAttack) finalCLTV(totalCltv uint64) (uint64, error) {
-	routeDelta := uint64(len(l.channels)-1) * cltvDelta
-	if totalCltv < routeDelta {
-		return 0, fmt.Errorf("total: %v < delta: %v", totalCltv,
-			routeDelta)
+// attackerReputation returns the reputation that an attacker has with the
+// first node in the ladder when the attack is launched, given the amount that
+// they have paid.
+func (l *LadderingAttack) attackerReputation(attackerPayment uint64) uint64 {
+	// If the attacker is on probation, the first node ignores their
+	// reputation entirely.
+	if l.attackerOnProbation {
+		return 0
+	}
+
+	// The attacker's payment is assumed to be made over the revenue
+	// period so that they can meet the first node's threshold. The
+	// reputation that it earns is the fees that the first node charges
+	// to forward the portion of it that settles.
+	settled := settledPayment(attackerPayment, l.settlePercent)
+	reputation := capReputation(
+		weightedReputation(
+			l.channels[0].fees.feeOrPassThrough(settled),
+			l.params.revenuePeriod(), l.recencyWeighting,
+		),
+		l.params.revenuePeriod(), l.weeklyGrowthCap,
+	)
+
+	// If the attacker builds the ladder sequentially, their reputation
+	// with the first node decays while they build each of the remaining
+	// hops up to the target.
+	return decayReputation(
+		reputation, l.buildWeeks(), l.weeklyDecayPercent,
+	)
+}
+
+// buildWeeks returns the number of weeks that the attacker spends building
+// the hops of the ladder after the first, during which its reputation with the
+// first node decays.
+func (l *LadderingAttack) buildWeeks() uint64 {
+	return saturatingMul(l.hopBuildWeeks, uint64(len(l.channels)-2))
+}
+
+// maxAttackerReputation returns the most reputation that the attacker can
+// sustain with the first node by the time that it has built the rest of the
+// ladder, regardless of how much it pays. If reputation growth is capped, the
+// attacker can only build a limited amount of reputation with the first node,
+// which then decays while it builds each of the remaining hops. A long ladder
+// may decay this reputation faster than the attacker can climb to the target.
+// If growth isn't capped and reputation doesn't fully decay, a larger payment
+// always makes up for decay so math.MaxUint64 is returned.
+func (l *LadderingAttack) maxAttackerReputation() uint64 {
+	if l.weeklyGrowthCap == 0 && (l.weeklyDecayPercent < 100 ||
+		l.buildWeeks() == 0) {
+
+		return math.MaxUint64
+	}
+
+	reputation := capReputation(
+		math.MaxUint64, l.params.revenuePeriod(), l.weeklyGrowthCap,
+	)
+
+	return decayReputation(
+		reputation, l.buildWeeks(), l.weeklyDecayPercent,
+	)
+}
+
+// capReputation limits the reputation built over the number of weeks provided
+// to the weekly growth cap, if any.
+func capReputation(reputation, weeks, weeklyGrowthCap uint64) uint64 {
+	if weeklyGrowthCap == 0 {
+		return reputation
+	}
+
+	maxReputation := saturatingMul(weeklyGrowthCap, weeks)
+	if reputation > maxReputation {
+		return maxReputation
+	}
+
+	return reputation
+}
+
+// decayReputation returns the reputation remaining after decaying at the
+// weekly percentage provided for the number of weeks provided.
+func decayReputation(reputation, weeks uint64, weeklyDecayPercent uint8) uint64 {
+	if weeklyDecayPercent >= 100 && weeks > 0 {
+		return 0
+	}
+
+	for i := uint64(0); i < weeks && reputation > 0; i++ {
+		reputation = mulDiv(
+			reputation, uint64(100-weeklyDecayPercent), 100,
+		)
 	}
 
-	return totalCltv - routeDelta, nil
+	return reputation
 }
 
-// totalEndorsedOnTarget calculates the total amount that an attacker can get
-// endorsed on the target node given some payment amount and htlc hold time.
-func (l *ladderingAttack) totalEndorsedOnTarget(attackerPayment uint64,
+// maxLadderHops is the largest number of hops that we'll consider when
+// searching for the number of hops required to reach a threshold.
+const maxLadderHops = 64
+
+// MinHopsToReach returns the smallest number of hops in a ladder where each
+// node contributes the same portion of its peer's traffic, in basis points,
+// for which the final hop's outgoing revenue reaches the target threshold.
+// Traffic grows geometrically along the ladder, so the number of hops is
+// calculated analytically and then adjusted to account for integer rounding
+// in the channel construction.
+func MinHopsToReach(firstNodeTraffic uint64, portionBasisPoints uint16,
+	targetThreshold uint64, params Params) (int, error) {
+
+	portion := portionBasisPoints
+	if portion == 0 || portion > basisPoints {
+		return 0, fmt.Errorf("traffic portion: %v not in [1, %v]",
+			portion, basisPoints)
+	}
+
+	if firstNodeTraffic == 0 {
+		return 0, fmt.Errorf("%w: no traffic at first node",
+			errThresholdUnreachable)
+	}
+
+	// hopRevenue returns the outgoing revenue on the nth hop, matching the
+	// calculation in newLadderingAttack. Traffic saturates rather than
+	// overflowing, so a ladder that amplifies traffic beyond what we can
+	// represent is treated as reaching any threshold.
+	hopRevenue := func(hops int) uint64 {
+		traffic := firstNodeTraffic
+		for i := 0; i < hops; i++ {
+			traffic = mulDiv(traffic, basisPoints, uint64(portion))
+		}
+
+		return mulDiv(
+			traffic, params.revenuePeriod(), params.reputationPeriod(),
+		)
+	}
+
+	if hopRevenue(1) >= targetThreshold {
+		return 1, nil
+	}
+
+	// If there's no amplification of traffic along the ladder, we'll
+	// never reach the threshold.
+	if portion == basisPoints {
+		return 0, fmt.Errorf("%w: no traffic growth with portion: %v",
+			errThresholdUnreachable, portion)
+	}
+
+	// The revenue on hop k is the first node's revenue multiplied by
+	// (basisPoints/portion)^k, so we can solve for k using logarithms.
+	var (
+		growth = basisPoints / float64(portion)
+		base   = float64(firstNodeTraffic) *
+			float64(params.revenuePeriod()) /
+			float64(params.reputationPeriod())
+	)
+
+	hops := int(math.Ceil(
+		math.Log(float64(targetThreshold)/base) / math.Log(growth),
+	))
+	if hops < 1 {
+		hops = 1
+	}
+
+	// Adjust for any difference between our floating point estimate and
+	// the integer arithmetic used to construct channels.
+	for hops > 1 && hopRevenue(hops-1) >= targetThreshold {
+		hops--
+	}
+
+	for hopRevenue(hops) < targetThreshold {
+		hops++
+
+		if hops > maxLadderHops {
+			return 0, fmt.Errorf("%w: more than %v hops required",
+				errThresholdUnreachable, maxLadderHops)
+		}
+	}
+
+	return hops, nil
+}
+
+// routeDelta returns the sum of the cltv deltas of the nodes that forward the
+// HTLC along the route, excluding the final cltv delta.
+func (l *LadderingAttack) routeDelta() uint64 {
+	var delta uint64
+	for _, channel := range l.channels[:len(l.channels)-1] {
+		delta = saturatingAdd(delta, channel.cltvDelta)
+	}
+
+	return delta
+}
+
+// upstreamDelta returns the sum of the cltv deltas of the nodes that forward
+// the HTLC before it reaches the target.
+func (l *LadderingAttack) upstreamDelta() uint64 {
+	var delta uint64
+	for _, channel := range l.channels[:len(l.channels)-2] {
+		delta = saturatingAdd(delta, channel.cltvDelta)
+	}
+
+	return delta
+}
+
+// targetHold returns the hold time of a HTLC with the total cltv provided on
+// the target's outgoing link, once each node before the target has subtracted
+// its delta. Zero is returned if the total doesn't cover those deltas.
+func (l *LadderingAttack) targetHold(totalCltv uint64) uint64 {
+	delta := l.upstreamDelta()
+	if totalCltv < delta {
+		return 0
+	}
+
+	return totalCltv - delta
+}
+
+// totalCltv returns the total cltv that a HTLC needs to be held on the
+// target's outgoing link for the number of blocks provided.
+func (l *LadderingAttack) totalCltv(targetHold uint64) uint64 {
+	return saturatingAdd(targetHold, l.upstreamDelta())
+}
+
+// requiredCltv returns the smallest total cltv that the attacker's HTLCs can
+// have on the route, which must cover each forwarding node's delta and the
+// final node's delta.
+func (l *LadderingAttack) requiredCltv() uint64 {
+	_, final := l.targetChannels()
+
+	return l.routeDelta() + final.cltvDelta
+}
+
+// FinalCLTV returns the hold time remaining once the HTLC reaches the final
+// node in the route, after each forwarding node has subtracted its delta from
+// the total. The total must also leave the final node with at least its own
+// delta, otherwise errInsufficientCltv is returned with the cltv required.
+func (l *LadderingAttack) FinalCLTV(totalCltv uint64) (uint64, error) {
+	if required := l.requiredCltv(); totalCltv < required {
+		_, final := l.targetChannels()
+
+		return 0, fmt.Errorf("%w: total: %v < required: %v (route "+
+			"delta: %v over %v hops + final delta: %v)",
+			errInsufficientCltv, totalCltv, required, l.routeDelta(),
+			len(l.channels)-1, final.cltvDelta)
+	}
+
+	return totalCltv - l.routeDelta(), nil
+}
+
+// TotalEndorsedOnTarget calculates the total amount that an attacker can get
+// endorsed on the target node given some payment amount and htlc hold time. If
+// any hop doesn't have enough endorsed slots to hold the attacker's HTLCs, the
+// attack is infeasible regardless of the amount that could be endorsed.
+func (l *LadderingAttack) TotalEndorsedOnTarget(attackerPayment uint64,
 	totalCltv uint64) (uint64, error) {
 
+	return l.totalEndorsedForHolds(attackerPayment, []holdBucket{
+		{
+			count: 1,
+			hold:  totalCltv,
+		},
+	})
+}
+
+// totalEndorsedForHolds calculates the total amount that an attacker can get
+// endorsed on the target node given some payment amount, when it holds HTLCs
+// with the distribution of hold times provided. The hold time of each bucket
+// is the total cltv of its HTLCs.
+func (l *LadderingAttack) totalEndorsedForHolds(attackerPayment uint64,
+	holds []holdBucket) (uint64, error) {
+
+	if len(holds) == 0 {
+		return 0, errNoHolds
+	}
+
 	var (
-		// The reputation total for the attacker is the amount that
+		// The reputation total for the attacker is based on the
+		// fees that the first (smaller) node earns on the amount that
 		// they have paid.
-		// TODO: multiplied by fee policy of smaller node.
-		candidateReputation = attackerPayment
+		candidateReputation = l.attackerReputation(attackerPayment)
 
 		totalEndorsed uint64
 
-		// Get total cltv delta for the route, assuming 40 block final
-		// cltv.
-		totalCltvDelta = cltvDelta*uint64(len(l.channels)-1) + 40
+		// Get total cltv delta for the route, including the final
+		// cltv delta.
+		totalCltvDelta = l.requiredCltv()
+
+		// The attacker holds an HTLC on each hop for every HTLC in the
+		// distribution.
+		htlcCount uint64
+
+		// We work with a copy of our holds sorted from longest to
+		// shortest, because we reduce the hold at each hop.
+		sorted = make([]holdBucket, len(holds))
 	)
 
-	if totalCltv < totalCltvDelta {
-		return 0, fmt.Errorf("%w: total cltv: %v < delta: %v",
-			errInsufficientCltv, totalCltv, totalCltvDelta)
+	copy(sorted, holds)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].hold > sorted[j].hold
+	})
+
+	for _, bucket := range sorted {
+		if bucket.count == 0 {
+			return 0, fmt.Errorf("%w: hold: %v has no HTLCs",
+				errNoHolds, bucket.hold)
+		}
+
+		if bucket.hold < totalCltvDelta {
+			return 0, fmt.Errorf("%w: total cltv: %v < delta: %v",
+				errInsufficientCltv, bucket.hold, totalCltvDelta)
+		}
+
+		htlcCount = saturatingAdd(htlcCount, bucket.count)
+	}
+
+	// If the attacker's reputation with the first node decays faster
+	// than it can build it, no payment is large enough to launch the
+	// attack.
+	var (
+		maxReputation = l.maxAttackerReputation()
+		threshold     = l.channels[0].outgoingRevenue
+	)
+	if maxReputation < threshold {
+		return 0, fmt.Errorf("%w: attacker can sustain at most %v "+
+			"reputation over %v build weeks, first node "+
+			"threshold: %v", errThresholdUnreachable, maxReputation,
+			l.buildWeeks(), threshold)
+	}
+
+	requiredSlots := uint64(l.attackerSlots)
+	if htlcCount > requiredSlots {
+		requiredSlots = htlcCount
 	}
 
 	// Based on the amount that the attacker gave us, run through our route
@@ -143,6 +922,16 @@ func (l *ladderingAttack) totalEndorsedOnTarget(attackerPayment uint64,
 	for i := 0; i < len(l.channels)-1; i++ {
 		channel := l.channels[i]
 
+		if uint64(channel.slotCapacity) < requiredSlots {
+			return 0, fmt.Errorf("%w: hop %v has %v slots, attacker "+
+				"needs %v", errInsufficientSlots, i,
+				channel.slotCapacity, requiredSlots)
+		}
+
+		// Only the portion of the reputation that counts with this
+		// channel can be used to get HTLCs endorsed.
+		candidateReputation = channel.peerReputation(candidateReputation)
+
 		// If the node doesn't even have sufficient reputation to meet
 		// the threshold, it won't get any HTLCs endorsed.
 		if candidateReputation < channel.outgoingRevenue {
@@ -153,8 +942,9 @@ func (l *ladderingAttack) totalEndorsedOnTarget(attackerPayment uint64,
 		// reputation threshold is the amount that we have available
 		// for in-flight HTLCs to be endorsed on this hop.
 		reputationSurplus := candidateReputation - channel.outgoingRevenue
-		currentHopEndorsed := htlcSizeFromReputation(
-			reputationSurplus, totalCltv,
+		currentHopEndorsed := endorsedForHolds(
+			reputationSurplus, sorted, l.endorsementLevel,
+			l.params,
 		)
 		if currentHopEndorsed == 0 {
 			return 0, nil
@@ -172,13 +962,16 @@ func (l *ladderingAttack) totalEndorsedOnTarget(attackerPayment uint64,
 		// to try get endorsed by its peer, so we update our candidate
 		// reputation accordingly.
 		candidateReputation = channel.incomingReputation
-		totalCltv -= cltvDelta
+		for j := range sorted {
+			sorted[j].hold -= channel.cltvDelta
+		}
 	}
 
 	return totalEndorsed, nil
 }
 
-type attackOutcome struct {
+// AttackOutcome describes the impact of a laddering attack on the target.
+type AttackOutcome struct {
 	// The amount of reputation that the target node had to start with.
 	targetReputation uint64
 
@@ -192,67 +985,656 @@ type attackOutcome struct {
 	// The cost of getting this reputation directly from the target node
 	// rather than performing a ladder attack.
 	targetCost uint64
+
+	// The cost of buying a channel that is already reputable with the
+	// target, zero if channels can't be bought.
+	purchaseCost uint64
+
+	// The number of minimum sized HTLCs that the attacker can hold
+	// endorsed on the target's outgoing link.
+	endorsedSlots uint64
+
+	// The number of endorsed slots on the target's outgoing link.
+	targetSlots uint64
+
+	// Whether the target's resources are split into protected and general
+	// buckets, in which case the attack is only harmful if it occupies the
+	// protected bucket.
+	protectedSplit bool
+
+	// The additional endorsed value that the attacker would need to fully
+	// occupy the protected bucket on the target's outgoing link, zero if
+	// the attacker already occupies it.
+	protectedShortfall uint64
+
+	// The opportunity cost of the capital that the attacker locks up in
+	// its jamming HTLCs, zero if it isn't modeled.
+	capitalCost uint64
+
+	// The percentage of the attacker's payments that settled.
+	settlePercent uint8
+
+	// The fee policy of the first node in the ladder, which the attacker
+	// burns on the payments that settle.
+	firstHopFees FeePolicy
+
+	// The total value that the attacker holds endorsed on the target,
+	// which locks up the attacker's liquidity for the hold.
+	endorsedValue uint64
+}
+
+// attackStrategy describes the way that an attacker acquires the reputation
+// that it needs to jam the target.
+type attackStrategy uint8
+
+const (
+	// strategyLadder builds reputation along a ladder of nodes.
+	strategyLadder attackStrategy = iota
+
+	// strategyDirect builds reputation directly with the target.
+	strategyDirect
+
+	// strategyPurchase buys a channel that is already reputable with the
+	// target.
+	strategyPurchase
+)
+
+func (a attackStrategy) String() string {
+	switch a {
+	case strategyLadder:
+		return "ladder"
+
+	case strategyDirect:
+		return "direct"
+
+	case strategyPurchase:
+		return "purchase"
+
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(a))
+	}
+}
+
+// cheapestAttack returns the cheapest way for an attacker to acquire the
+// reputation it needs to jam the target, given the amount that it pays to
+// ladder, along with the cost of that strategy.
+func (a AttackOutcome) cheapestAttack(attackerPayment uint64) (attackStrategy,
+	uint64) {
+
+	strategy, cost := strategyLadder, a.ladderCost(attackerPayment)
+	if a.targetCost < cost {
+		strategy, cost = strategyDirect, a.targetCost
+	}
+
+	if a.purchaseCost != 0 && a.purchaseCost < cost {
+		strategy, cost = strategyPurchase, a.purchaseCost
+	}
+
+	return strategy, cost
+}
+
+// ladderCost returns the total cost of the laddering attack, which is the
+// attacker's payment plus the fees that it burns on the portion that settled
+// to build reputation, and the opportunity cost of the capital that it locks
+// up to jam the target.
+func (a AttackOutcome) ladderCost(attackerPayment uint64) uint64 {
+	return saturatingAdd(
+		saturatingAdd(attackerPayment, a.burnedFees(attackerPayment)),
+		a.capitalCost,
+	)
+}
+
+// burnedFees returns the fees that the attacker pays the first node on the
+// portion of the payment provided that settles. Payments that fail don't pay
+// any fees.
+func (a AttackOutcome) burnedFees(attackerPayment uint64) uint64 {
+	settled := settledPayment(attackerPayment, a.settlePercent)
+	if settled == 0 {
+		return 0
+	}
+
+	return a.firstHopFees.Fee(settled)
+}
+
+// settledPayment returns the portion of the payment provided that settles at
+// the percentage provided.
+func settledPayment(payment uint64, settlePercent uint8) uint64 {
+	return mulDiv(payment, uint64(settlePercent), 100)
 }
 
-func (a attackOutcome) ladderCheaper(attackerPayment uint64) bool {
-	return a.targetCost > attackerPayment
+func (a AttackOutcome) ladderCheaper(attackerPayment uint64) bool {
+	return a.targetCost > a.ladderCost(attackerPayment)
 }
 
-func (a attackOutcome) lostReputation() bool {
-	return a.targetReputation < a.targetThreshold+a.reputationChange
+func (a AttackOutcome) lostReputation() bool {
+	return a.targetReputation < saturatingAdd(
+		a.targetThreshold, a.reputationChange,
+	)
+}
+
+// hadGoodReputation returns a boolean indicating whether the target had good
+// reputation with the final node before the attack, without which it has no
+// access to the protected bucket for the attacker to deny it.
+func (a AttackOutcome) hadGoodReputation() bool {
+	return a.targetReputation >= a.targetThreshold
+}
+
+// slotsExhausted returns a boolean indicating whether the attacker can hold
+// enough minimum sized HTLCs endorsed to occupy all of the endorsed slots on
+// the target's outgoing link.
+func (a AttackOutcome) slotsExhausted() bool {
+	return a.targetSlots != 0 && a.endorsedSlots >= a.targetSlots
+}
+
+// Effective returns a boolean indicating whether the ladder is cheaper than
+// attacking the target directly, and the attacker can jam the target either
+// by value or by exhausting its slots. If the target's resources are split
+// into protected and general buckets, the attacker must occupy the protected
+// bucket for the attack to be harmful, regardless of value. The protected
+// bucket is only available to the target if it had good reputation with the
+// final node to begin with.
+func (a AttackOutcome) Effective(attackerPayment uint64) bool {
+	return a.ladderCheaper(attackerPayment) && a.jams()
+}
+
+// jams returns a boolean indicating whether the attacker can jam the target,
+// regardless of whether laddering is cheaper than attacking it directly.
+func (a AttackOutcome) jams() bool {
+	if a.protectedSplit {
+		return a.hadGoodReputation() && a.protectedOccupied()
+	}
+
+	return a.lostReputation() || a.slotsExhausted()
+}
+
+// RecoveryTime returns the number of weeks that it takes the target to
+// rebuild the reputation it lost in the attack, given the rate at which it
+// builds reputation with its normal traffic (expressed per week).
+func (a AttackOutcome) RecoveryTime(weeklyReputation uint64) (uint64, error) {
+	if a.reputationChange == 0 {
+		return 0, nil
+	}
+
+	if weeklyReputation == 0 {
+		return 0, fmt.Errorf("%w: no reputation built per week",
+			errNoRecovery)
+	}
+
+	// Round up, because the target hasn't recovered until it has rebuilt
+	// all of its lost reputation.
+	return mulDivRoundUp(a.reputationChange, 1, weeklyReputation), nil
+}
+
+// Closeness returns a continuous measure of how close the outcome is to being
+// an effective attack, where 1.0 is exactly at the boundary of success and
+// values above 1.0 are effective attacks. This allows near-miss scenarios to
+// be identified even when the attack itself isn't effective.
+func (a AttackOutcome) Closeness(attackerPayment uint64) float64 {
+	// The ladder needs to be cheaper than attacking the target directly,
+	// and the attacker needs to jam the target. Both need to hold, so
+	// we're only as close as the furthest of the two.
+	cheaper := ratio(a.targetCost, a.ladderCost(attackerPayment))
+
+	return math.Min(cheaper, a.jamCloseness())
+}
+
+// jamCloseness returns a continuous measure of how close the attacker is to
+// jamming the target, using the same criteria as jams. If resources are
+// split, the target must have had good reputation and the attacker must
+// occupy the protected bucket, so we're only as close as the furthest of the
+// two. Otherwise, the attacker jams the target by value or by exhausting its
+// slots, so we're as close as the nearest of the two.
+func (a AttackOutcome) jamCloseness() float64 {
+	if a.protectedSplit {
+		good := ratio(a.targetReputation, a.targetThreshold)
+
+		return math.Min(good, a.protectedCloseness())
+	}
+
+	lost := ratio(
+		saturatingAdd(a.targetThreshold, a.reputationChange),
+		a.targetReputation,
+	)
+
+	if a.targetSlots == 0 {
+		return lost
+	}
+
+	return math.Max(lost, ratio(a.endorsedSlots, a.targetSlots))
+}
+
+// protectedCloseness returns the portion of the value that the attacker
+// needs to occupy the protected bucket that it holds endorsed, which is 1.0
+// once the bucket is occupied.
+func (a AttackOutcome) protectedCloseness() float64 {
+	if a.protectedOccupied() {
+		return 1
+	}
+
+	needed := saturatingAdd(a.endorsedValue, a.protectedShortfall)
+	if needed == 0 {
+		return 0
+	}
+
+	return ratio(a.endorsedValue, needed)
+}
+
+// ratio returns numerator / denominator as a float, returning positive
+// infinity if the denominator is zero.
+func ratio(numerator, denominator uint64) float64 {
+	if denominator == 0 {
+		return math.Inf(1)
+	}
+
+	return float64(numerator) / float64(denominator)
+}
+
+// reputationMargin returns the amount by which the attack pushes the target
+// below its threshold, which is negative if the target keeps its reputation.
+func (a AttackOutcome) reputationMargin() int64 {
+	return signedDiff(
+		saturatingAdd(a.targetThreshold, a.reputationChange),
+		a.targetReputation,
+	)
+}
+
+// lossPercent returns the percentage of the target's reputation that the
+// attack costs it, capped at 100.
+func (a AttackOutcome) lossPercent() uint64 {
+	if a.targetReputation == 0 {
+		return 0
+	}
+
+	if a.reputationChange >= a.targetReputation {
+		return 100
+	}
+
+	return mulDiv(a.reputationChange, 100, a.targetReputation)
+}
+
+// Margin returns the signed margin by which the attack is effective for the
+// attacker payment provided, which is positive if the attack is effective and
+// zero or negative if it fails. If the attacker can jam the target, this is
+// the amount that the ladder saves over attacking the target directly.
+// Otherwise, it is the amount of reputation that the target has left over its
+// threshold, or the endorsed value that the attacker is short of occupying
+// the protected bucket if resources are split, as a negative value. If the
+// target never had good reputation, it is the reputation that the target is
+// short of its threshold.
+func (a AttackOutcome) Margin(attackerPayment uint64) int64 {
+	if a.protectedSplit && !a.hadGoodReputation() {
+		return signedDiff(a.targetReputation, a.targetThreshold)
+	}
+
+	if a.protectedSplit && !a.protectedOccupied() {
+		return signedDiff(0, a.protectedShortfall)
+	}
+
+	if !a.protectedSplit && !a.lostReputation() && !a.slotsExhausted() {
+		return a.reputationMargin()
+	}
+
+	return signedDiff(a.targetCost, a.ladderCost(attackerPayment))
 }
 
-func (a attackOutcome) effective(attackerPayment uint64) bool {
-	return a.ladderCheaper(attackerPayment) && a.lostReputation()
+// TotalCapital returns the total capital that the attacker needs to marshal
+// for the attack, given the amount that it pays to ladder. This is the
+// attacker's payments to build reputation plus the liquidity that it locks up
+// in its jamming HTLCs for the duration of the hold, which is the figure to
+// compare to an attacker's budget rather than the cost of the attack.
+func (a AttackOutcome) TotalCapital(attackerPayment uint64) uint64 {
+	return saturatingAdd(attackerPayment, a.endorsedValue)
 }
 
-func (a attackOutcome) String() string {
+func (a AttackOutcome) String() string {
 	return fmt.Sprintf("Target has reputation: %v vs threshold: %v "+
-		"reputation changed by %v which would have cost %v to "+
-		"acquire with the target directly", a.targetReputation,
-		a.targetThreshold, a.reputationChange, a.targetCost)
+		"reputation changed by %v (margin: %v) which would have cost "+
+		"%v to acquire with the target directly, attacker locked: %v "+
+		"in jamming htlcs", a.targetReputation, a.targetThreshold,
+		a.reputationChange, a.reputationMargin(), a.targetCost,
+		a.endorsedValue)
+}
+
+// minEffectivePayment returns the smallest payment for which the attack is
+// effective when the attacker holds its HTLCs for htlcHold blocks, along with
+// the outcome of the attack at that payment. A false boolean is returned if
+// no payment results in an effective attack.
+//
+// The attacker's payment only builds reputation with the first hop, so the
+// amount that it can get endorsed on the target grows with its payment until
+// it is capped by the reputation of a later hop. Once capped, a larger payment
+// only costs the attacker more, so there's no reason to pay more than the
+// capping payment. Below the cap, both the cost of attacking the target
+// directly and the cost of the ladder grow with the payment, so laddering may
+// not be cheaper at the smallest payment that jams the target but become
+// cheaper with a larger one. We find the smallest payment that jams the target
+// and the payment that reaches the cap, and if the attack is only effective at
+// the cap we search between the two for the point where it becomes effective.
+func (l *LadderingAttack) minEffectivePayment(htlcHold uint64) (uint64,
+	AttackOutcome, bool, error) {
+
+	endorsed := func(payment uint64) (uint64, error) {
+		return l.TotalEndorsedOnTarget(payment, htlcHold)
+	}
+
+	outcome := func(payment uint64) (AttackOutcome, error) {
+		endorsed, err := endorsed(payment)
+		if err != nil {
+			return AttackOutcome{}, err
+		}
+
+		return l.Outcome(endorsed, htlcHold), nil
+	}
+
+	// Find the most that the attacker can get endorsed on the target. If
+	// it doesn't jam the target, no payment does.
+	maxEndorsed, err := endorsed(math.MaxUint64)
+	if errors.Is(err, errThresholdUnreachable) {
+		return 0, AttackOutcome{}, false, nil
+	}
+	if err != nil {
+		return 0, AttackOutcome{}, false, err
+	}
+
+	if !l.Outcome(maxEndorsed, htlcHold).jams() {
+		return 0, AttackOutcome{}, false, nil
+	}
+
+	capPayment, err := smallestPayment(0, math.MaxUint64,
+		func(payment uint64) (bool, error) {
+			endorsed, err := endorsed(payment)
+
+			return endorsed >= maxEndorsed, err
+		},
+	)
+	if err != nil {
+		return 0, AttackOutcome{}, false, err
+	}
+
+	jamPayment, err := smallestPayment(0, capPayment,
+		func(payment uint64) (bool, error) {
+			outcome, err := outcome(payment)
+
+			return outcome.jams(), err
+		},
+	)
+	if err != nil {
+		return 0, AttackOutcome{}, false, err
+	}
+
+	effective := func(payment uint64) (bool, error) {
+		outcome, err := outcome(payment)
+
+		return outcome.Effective(payment), err
+	}
+
+	// If laddering isn't cheaper at either end of the range, it isn't
+	// cheaper anywhere in between.
+	payment := jamPayment
+	ok, err := effective(jamPayment)
+	if err != nil {
+		return 0, AttackOutcome{}, false, err
+	}
+
+	if !ok {
+		ok, err = effective(capPayment)
+		if err != nil {
+			return 0, AttackOutcome{}, false, err
+		}
+
+		if !ok {
+			return 0, AttackOutcome{}, false, nil
+		}
+
+		payment, err = smallestPayment(
+			jamPayment, capPayment, effective,
+		)
+		if err != nil {
+			return 0, AttackOutcome{}, false, err
+		}
+	}
+
+	result, err := outcome(payment)
+	if err != nil {
+		return 0, AttackOutcome{}, false, err
+	}
+
+	return payment, result, true, nil
 }
 
-// runAttack takes the amount that an attacker is willing to pay and returns a
-// bool indicating whether the attacker is able to sabotage the reputation of
-// the target node.
-func (l *ladderingAttack) attackOutcome(totalEndorsed,
-	htlcHold uint64) attackOutcome {
+// smallestPayment returns the smallest payment in [low, high] for which the
+// predicate provided holds, assuming that it holds for high and that once it
+// holds for a payment, it holds for all larger payments in the range.
+func smallestPayment(low, high uint64,
+	holds func(uint64) (bool, error)) (uint64, error) {
+
+	ok, err := holds(low)
+	if err != nil || ok {
+		return low, err
+	}
+
+	for low+1 < high {
+		mid := low + (high-low)/2
+
+		ok, err := holds(mid)
+		if err != nil {
+			return 0, err
+		}
+
+		if ok {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+
+	return high, nil
+}
 
+// minEffectiveLength returns the shortest ladder, built from a prefix of the
+// config's traffic flows, for which the attack is effective with the payment
+// and cltv provided, along with the outcome of the attack on that ladder. A
+// false boolean is returned if no ladder of at most maxNetworkLength nodes is
+// effective.
+//
+// A longer ladder isn't necessarily more effective, because each length
+// targets a different node and an intermediate hop with low reputation can
+// limit the amount that the attacker gets endorsed, so every length is
+// evaluated in turn. Lengths that can't be evaluated because the cltv or slots
+// are insufficient, or the traffic overflows, are treated as ineffective.
+func (c ladderingAttackCfg) minEffectiveLength(attackerPayment,
+	cltvTotal uint64) (int, AttackOutcome, bool, error) {
+
+	maxLength := len(c.trafficFlows)
+	if maxLength > maxNetworkLength {
+		maxLength = maxNetworkLength
+	}
+
+	for length := 3; length <= maxLength; length++ {
+		cfg := c
+		cfg.trafficFlows = c.trafficFlows[:length]
+
+		ladder, err := newLadderingAttack(cfg)
+		if errors.Is(err, errTrafficOverflow) {
+			continue
+		}
+		if err != nil {
+			return 0, AttackOutcome{}, false, err
+		}
+
+		totalEndorsed, err := ladder.TotalEndorsedOnTarget(
+			attackerPayment, cltvTotal,
+		)
+		if errors.Is(err, errInsufficientCltv) ||
+			errors.Is(err, errInsufficientSlots) {
+
+			continue
+		}
+		if err != nil {
+			return 0, AttackOutcome{}, false, err
+		}
+
+		outcome := ladder.Outcome(totalEndorsed, cltvTotal)
+		if outcome.Effective(attackerPayment) {
+			return length, outcome, true, nil
+		}
+	}
+
+	return 0, AttackOutcome{}, false, nil
+}
+
+// targetReputation returns the reputation that the target has with the final
+// node, accounting for any round trip requirement that the final node has.
+func (l *LadderingAttack) targetReputation() uint64 {
+	target, final := l.targetChannels()
+
+	return final.peerReputation(target.incomingReputation)
+}
+
+// Outcome returns the outcome of an attack where the attacker holds the total
+// endorsed amount provided on the target node with HTLCs that have the total
+// cltv provided. Each node before the target subtracts its delta, so the HTLCs
+// are held on the target's outgoing link for the hold that remains at the
+// target.
+func (l *LadderingAttack) Outcome(totalEndorsed,
+	totalCltv uint64) AttackOutcome {
+
+	htlcHold := l.targetHold(totalCltv)
 	chanCount := len(l.channels)
-	finalNodeRevenue := l.channels[chanCount-1].outgoingRevenue
+	finalNode := l.channels[chanCount-1]
+	finalNodeRevenue := finalNode.outgoingRevenue
 	targetNode := l.channels[chanCount-2]
+	targetReputation := l.targetReputation()
+
+	// Calculate the total penalty for slowjamming. The target's
+	// reputation is expressed in the fees that the final node charges, so
+	// the penalty is based on the fees of the attacker's HTLCs.
+	slowJamCost := budgetForEndorsedValue(
+		finalNode.fees.htlcFees(
+			totalEndorsed, uint64(l.attackerSlots),
+		), htlcHold, l.params,
+	)
 
-	// Calculate the total penalty for slowjamming.
-	// TODO: totalEndorsed * fee for outgoing node!!
-	slowJamCost := htlcReputationCost(totalEndorsed, htlcHold)
+	// The attacker locks up the same liquidity for the same hold whether
+	// it ladders or attacks the target directly, so the opportunity cost
+	// of that capital applies to both.
+	lockedCost := capitalCost(
+		totalEndorsed, htlcHold, l.capitalRateBasisPoints, l.params,
+	)
 
-	outcome := attackOutcome{
-		targetReputation: targetNode.incomingReputation,
+	outcome := AttackOutcome{
+		targetReputation: targetReputation,
 		targetThreshold:  finalNodeRevenue,
 		// The cost of acquiring reputation directly with the target
-		// node is its revenue threshold plus the cost of HTLCs.
-		targetCost: targetNode.outgoingRevenue + slowJamCost,
+		// node is its revenue threshold plus the cost of HTLCs and the
+		// capital that they lock up.
+		targetCost: saturatingAdd(
+			saturatingAdd(targetNode.outgoingRevenue, slowJamCost),
+			lockedCost,
+		),
+		purchaseCost:       l.channelPurchaseCost,
+		protectedSplit:     l.protectedSplit,
+		protectedShortfall: l.protectedShortfall(totalEndorsed),
+		capitalCost:        lockedCost,
+		settlePercent:      l.settlePercent,
+		firstHopFees:       l.channels[0].fees,
+		endorsedValue:      totalEndorsed,
 	}
 
 	// If the targeted node didn't have good reputation with the last node
 	// anyway, then there was no attack to be had to begin with.
-	if targetNode.incomingReputation < finalNodeRevenue {
+	if targetReputation < finalNodeRevenue {
 		return outcome
 	}
 
+	outcome.endorsedSlots = l.endorsedSlots(totalEndorsed)
+	outcome.targetSlots = uint64(targetNode.slotCapacity)
+
 	outcome.reputationChange = slowJamCost
+	if l.timeAveraged {
+		outcome.reputationChange = averagedReputationChange(
+			slowJamCost, htlcHold, l.params,
+		)
+	}
+
 	return outcome
 }
 
-// htlcSizeFromReputation returns the size of htlc that a node can get endorsed
-// with the reputation amount provided.
-func htlcSizeFromReputation(reputation, htlcHold uint64) uint64 {
-	return reputation * 90 / (htlcHold * 10 * 60)
+// endorsedSlots returns the number of minimum sized HTLCs that the attacker
+// can hold endorsed with the total endorsed amount provided. The attacker
+// holds each HTLC on every hop up to the target's outgoing link, so the count
+// is limited by the smallest slot capacity along the route.
+//
+// Reputation on the target's outgoing link is expressed in the fees that the
+// final node charges, so the attacker can afford as many minimum sized HTLCs
+// as the fees of its endorsed HTLCs cover. When the final node charges a base
+// fee, many small HTLCs cost disproportionately more than a few large ones.
+func (l *LadderingAttack) endorsedSlots(totalEndorsed uint64) uint64 {
+	minHTLCSize := l.minHTLCSize
+	if minHTLCSize == 0 {
+		minHTLCSize = defaultMinHTLCSize
+	}
+
+	var (
+		fees   = l.channels[len(l.channels)-1].fees
+		budget = fees.htlcFees(totalEndorsed, uint64(l.attackerSlots))
+		slots  = totalEndorsed / minHTLCSize
+	)
+
+	if perSlot := fees.htlcFees(minHTLCSize, 1); perSlot != 0 &&
+		budget/perSlot < slots {
+
+		slots = budget / perSlot
+	}
+
+	for _, channel := range l.channels[:len(l.channels)-1] {
+		if capacity := uint64(channel.slotCapacity); slots > capacity {
+			slots = capacity
+		}
+	}
+
+	return slots
 }
 
-// htlcReputationCost is the cost of getting a htlc endorsed (and the penalty
-// for using it to slow jam).
-func htlcReputationCost(amount uint64, height uint64) uint64 {
-	return (amount * height * 10 * 60) / 90
+// sustainedJamBlocks returns the number of blocks that an attacker can keep
+// the endorsed amount provided jammed for, in a model where endorsing HTLCs
+// that are held maliciously burns the attacker's reputation rather than
+// releasing it once the HTLC resolves. Each jam held for htlcHold blocks
+// burns the reputation cost of the HTLC from the attacker's reputation surplus
+// with the first node, so the attacker can only repeat the jam until they run
+// out of surplus.
+func (l *LadderingAttack) sustainedJamBlocks(attackerPayment, totalEndorsed,
+	htlcHold uint64) uint64 {
+
+	reputation := l.attackerReputation(attackerPayment)
+	threshold := l.channels[0].outgoingRevenue
+	if totalEndorsed == 0 || reputation < threshold {
+		return 0
+	}
+
+	burn := budgetForEndorsedValue(totalEndorsed, htlcHold, l.params)
+	if burn == 0 {
+		return math.MaxUint64
+	}
+
+	jams := (reputation - threshold) / burn
+
+	return saturatingMul(jams, htlcHold)
+}
+
+// averagedReputationChange spreads the reputation change caused by a jam
+// held for htlcHold blocks over the revenue period, returning the average
+// change in reputation over that period. Jams that last for the full period
+// (or longer) have their full impact.
+func averagedReputationChange(reputationChange, htlcHold uint64,
+	params Params) uint64 {
+
+	windowBlocks := saturatingMul(
+		params.revenuePeriod(), params.blocksPerWeek(),
+	)
+	if htlcHold >= windowBlocks {
+		return reputationChange
+	}
+
+	return mulDiv(reputationChange, htlcHold, windowBlocks)
 }
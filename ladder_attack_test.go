@@ -1,6 +1,7 @@
 package reputationfuzz
 
 import (
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -13,16 +14,16 @@ func TestLadderAttackSetup(t *testing.T) {
 		firstNodeTraffic: 120_000,
 		trafficFlows: []trafficFlow{
 			{
-				trafficPortion: 100,
+				portionBasisPoints: 10_000,
 			},
 			{
-				trafficPortion: 10,
+				portionBasisPoints: 1_000,
 			},
 			{
-				trafficPortion: 25,
+				portionBasisPoints: 2_500,
 			},
 			{
-				trafficPortion: 50,
+				portionBasisPoints: 5_000,
 			},
 		},
 	}
@@ -48,10 +49,1485 @@ func TestLadderAttackSetup(t *testing.T) {
 		totalCltv uint64 = 300
 	)
 
-	endorsedTotal, err := attack.totalEndorsedOnTarget(attackAmt, totalCltv)
+	endorsedTotal, err := attack.TotalEndorsedOnTarget(attackAmt, totalCltv)
 	require.NoError(t, err)
 	require.EqualValues(t, 10, endorsedTotal)
 
-	outcome := attack.attackOutcome(endorsedTotal, totalCltv)
-	require.False(t, outcome.effective(attackAmt))
+	outcome := attack.Outcome(endorsedTotal, totalCltv)
+	require.False(t, outcome.Effective(attackAmt))
+}
+
+// TestTimeAveragedOutcome tests that evaluating the target's reputation as an
+// average over the revenue period protects a node that would lose reputation
+// under a point-in-time evaluation.
+func TestTimeAveragedOutcome(t *testing.T) {
+	channels := []channel{
+		{
+			incomingReputation: 120_000,
+			outgoingRevenue:    10_000,
+		},
+		{
+			incomingReputation: 1_000_000,
+			outgoingRevenue:    100_000,
+		},
+		{
+			incomingReputation: 9_600_000,
+			outgoingRevenue:    800_000,
+		},
+	}
+
+	var (
+		// An endorsed amount of 150 held for 300 blocks costs 300_000
+		// in reputation, which is enough to push the target below its
+		// threshold of 800_000.
+		totalEndorsed uint64 = 150
+		htlcHold      uint64 = 300
+	)
+
+	pointInTime := &LadderingAttack{channels: channels}
+	outcome := pointInTime.Outcome(totalEndorsed, htlcHold)
+	require.EqualValues(t, 300_000, outcome.reputationChange)
+	require.True(t, outcome.lostReputation())
+
+	averaged := &LadderingAttack{
+		channels:     channels,
+		timeAveraged: true,
+	}
+	outcome = averaged.Outcome(totalEndorsed, htlcHold)
+	require.EqualValues(t, 300_000*300/2016, outcome.reputationChange)
+	require.False(t, outcome.lostReputation())
+}
+
+// TestRecencyWeightedReputation contrasts uniform and recency weighted
+// reputation, asserting that nodes with constant traffic are unaffected while
+// an attacker who has recently paid builds reputation faster.
+func TestRecencyWeightedReputation(t *testing.T) {
+	cfg := newScenario(
+		120_000, []uint8{100, 10, 25, 50}, 30_000, 300,
+	).cfg
+
+	uniform, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	cfg.recencyWeighting = linearRecencyWeighting(Params{})
+	weighted, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	// Constant traffic over the full reputation period is not affected by
+	// the recency weighting.
+	require.Equal(t, uniform.channels, weighted.channels)
+
+	var (
+		attackAmt uint64 = 30_000
+		totalCltv uint64 = 300
+	)
+
+	uniformEndorsed, err := uniform.TotalEndorsedOnTarget(
+		attackAmt, totalCltv,
+	)
+	require.NoError(t, err)
+	require.EqualValues(t, 10, uniformEndorsed)
+
+	// The attacker's payment is recent, so it counts for more reputation
+	// and they can get more endorsed on the target.
+	weightedEndorsed, err := weighted.TotalEndorsedOnTarget(
+		attackAmt, totalCltv,
+	)
+	require.NoError(t, err)
+	require.EqualValues(t, 13, weightedEndorsed)
+}
+
+// TestAttackOutcomeCloseness tests that closeness increases monotonically as
+// the attacker's payment approaches the cost of attacking the target
+// directly, reaching 1.0 exactly at the boundary.
+func TestAttackOutcomeCloseness(t *testing.T) {
+	outcome := AttackOutcome{
+		targetReputation: 1_000_000,
+		targetThreshold:  800_000,
+		reputationChange: 300_000,
+		targetCost:       500_000,
+	}
+
+	previous := 0.0
+	for _, payment := range []uint64{
+		2_000_000, 1_000_000, 750_000, 600_000, 510_000, 500_000,
+	} {
+		closeness := outcome.Closeness(payment)
+		require.Greater(t, closeness, previous)
+		require.False(t, outcome.Effective(payment))
+
+		previous = closeness
+	}
+
+	require.Equal(t, 1.0, previous)
+	require.True(t, outcome.Effective(499_999))
+	require.Greater(t, outcome.Closeness(499_999), 1.0)
+}
+
+// TestRoundTripReputation tests that requiring round trip traffic reduces the
+// reputation that an attacker who only routes one-way can use.
+func TestRoundTripReputation(t *testing.T) {
+	scenario := newScenario(
+		120_000, []uint8{100, 10, 25, 50}, 30_000, 300,
+	)
+	cfg := scenario.cfg
+
+	attackAmt, totalCltv := scenario.attackerPayment, scenario.cltvTotal
+
+	attack, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	endorsed, err := attack.TotalEndorsedOnTarget(attackAmt, totalCltv)
+	require.NoError(t, err)
+	require.EqualValues(t, 10, endorsed)
+
+	// If only half of the attacker's traffic with the first node is
+	// round trip, they only have 15_000 reputation which leaves a surplus
+	// of 5_000 over the first node's threshold.
+	cfg.trafficFlows[0].roundTripPercent = 50
+	attack, err = newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	endorsed, err = attack.TotalEndorsedOnTarget(attackAmt, totalCltv)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, endorsed)
+
+	// If less than a third of their traffic is round trip, they don't
+	// meet the threshold at all.
+	cfg.trafficFlows[0].roundTripPercent = 30
+	attack, err = newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	endorsed, err = attack.TotalEndorsedOnTarget(attackAmt, totalCltv)
+	require.NoError(t, err)
+	require.Zero(t, endorsed)
+}
+
+// TestSequentialBuildDecay tests that building the ladder sequentially, with
+// the attacker's reputation at the first node decaying while deeper hops are
+// built, reduces the amount that the attacker can get endorsed.
+func TestSequentialBuildDecay(t *testing.T) {
+	scenario := newScenario(
+		120_000, []uint8{100, 10, 25, 50}, 30_000, 300,
+	)
+	cfg := scenario.cfg
+
+	attackAmt, totalCltv := scenario.attackerPayment, scenario.cltvTotal
+
+	simultaneous, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	endorsed, err := simultaneous.TotalEndorsedOnTarget(
+		attackAmt, totalCltv,
+	)
+	require.NoError(t, err)
+	require.EqualValues(t, 10, endorsed)
+
+	// Building the two hops after the first takes four weeks, over which
+	// the attacker's 30_000 reputation decays to 19_683.
+	cfg.hopBuildWeeks = 2
+	cfg.weeklyDecayPercent = 10
+
+	sequential, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+	require.EqualValues(t, 19_683, sequential.attackerReputation(attackAmt))
+
+	endorsed, err = sequential.TotalEndorsedOnTarget(attackAmt, totalCltv)
+	require.NoError(t, err)
+	require.EqualValues(t, 4, endorsed)
+}
+
+// TestBuildDecayUnreachable tests that a ladder that takes so long to build
+// that the attacker's capped reputation with the first node decays beneath its
+// threshold can't be attacked, regardless of the attacker's payment.
+func TestBuildDecayUnreachable(t *testing.T) {
+	cfg := newScenario(
+		120_000, []uint8{100, 10, 25, 50}, 30_000, 300,
+	).cfg
+	cfg.hopBuildWeeks = 1
+	cfg.weeklyDecayPercent = 10
+
+	// The attacker can build at most 12_000 reputation with the first
+	// node over the revenue period, which has a threshold of 10_000.
+	cfg.weeklyGrowthCap = 6_000
+
+	// Building a single hop after the first decays the attacker's
+	// reputation to 10_800, which still clears the threshold.
+	short := cfg
+	short.trafficFlows = cfg.trafficFlows[:3]
+
+	attack, err := newLadderingAttack(short)
+	require.NoError(t, err)
+	require.EqualValues(t, 10_000, attack.channels[0].outgoingRevenue)
+	require.EqualValues(t, 10_800, attack.maxAttackerReputation())
+
+	_, err = attack.TotalEndorsedOnTarget(1_000_000, 300)
+	require.NoError(t, err)
+
+	// Building two hops decays it to 9_720, so no payment is enough.
+	attack, err = newLadderingAttack(cfg)
+	require.NoError(t, err)
+	require.EqualValues(t, 9_720, attack.maxAttackerReputation())
+
+	_, err = attack.TotalEndorsedOnTarget(math.MaxUint64, 300)
+	require.ErrorIs(t, err, errThresholdUnreachable)
+
+	_, _, ok, err := attack.minEffectivePayment(300)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// Without a growth cap, a larger payment makes up for decay.
+	cfg.weeklyGrowthCap = 0
+
+	attack, err = newLadderingAttack(cfg)
+	require.NoError(t, err)
+	require.EqualValues(t, uint64(math.MaxUint64),
+		attack.maxAttackerReputation())
+
+	// Unless reputation decays entirely while the attacker builds.
+	cfg.weeklyDecayPercent = 100
+
+	attack, err = newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	_, err = attack.TotalEndorsedOnTarget(math.MaxUint64, 300)
+	require.ErrorIs(t, err, errThresholdUnreachable)
+}
+
+// TestLastHopWeight tests that weighting the reputation earned on the last hop
+// changes whether the target clears its threshold with the final node.
+func TestLastHopWeight(t *testing.T) {
+	cfg := newScenario(
+		120_000, []uint8{100, 10, 25, 50}, 30_000, 300,
+	).cfg
+
+	attack, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	outcome := attack.Outcome(0, 300)
+	require.EqualValues(t, 4_800_000, outcome.targetReputation)
+	require.Greater(t, outcome.targetReputation, outcome.targetThreshold)
+
+	// If the last hop only earns a tenth of the reputation, the target
+	// doesn't clear its threshold of 800_000.
+	cfg.lastHopWeightPercent = 10
+	attack, err = newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	outcome = attack.Outcome(0, 300)
+	require.EqualValues(t, 480_000, outcome.targetReputation)
+	require.Less(t, outcome.targetReputation, outcome.targetThreshold)
+
+	// Other hops are unaffected by the weighting.
+	require.EqualValues(t, 120_000, attack.channels[0].incomingReputation)
+	require.EqualValues(t, 9_600_000, attack.channels[3].incomingReputation)
+}
+
+// TestAttackerProbation tests that placing the attacker on probation with the
+// first node blocks an otherwise effective attack, regardless of how much the
+// attacker pays.
+func TestAttackerProbation(t *testing.T) {
+	cfg := ladderingAttackCfg{
+		firstNodeTraffic: 1_000_000,
+		trafficFlows: []trafficFlow{
+			{
+				portionBasisPoints: 10_000,
+			},
+			{
+				portionBasisPoints: 5_000,
+			},
+			{
+				portionBasisPoints: 5_000,
+			},
+			{
+				portionBasisPoints: 2_500,
+			},
+		},
+		lastHopWeightPercent: 50,
+	}
+
+	var totalCltv uint64 = 1000
+
+	attack, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	endorsed, err := attack.TotalEndorsedOnTarget(1_000_000, totalCltv)
+	require.NoError(t, err)
+	require.True(t, attack.Outcome(endorsed, totalCltv).Effective(
+		1_000_000,
+	))
+
+	cfg.attackerOnProbation = true
+	attack, err = newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	for _, payment := range []uint64{
+		1_000, 1_000_000, 1_000_000_000, 1_000_000_000_000,
+	} {
+		endorsed, err := attack.TotalEndorsedOnTarget(payment, totalCltv)
+		require.NoError(t, err)
+		require.Zero(t, endorsed)
+
+		outcome := attack.Outcome(endorsed, totalCltv)
+		require.False(t, outcome.Effective(payment))
+	}
+}
+
+// TestMinHopsToReach tests calculation of the number of hops required to
+// reach a revenue threshold against the growth pattern of our setup config.
+func TestMinHopsToReach(t *testing.T) {
+	tests := []struct {
+		name      string
+		portion   uint16
+		threshold uint64
+		hops      int
+		err       error
+	}{
+		{
+			// The first node has 1_200_000 in traffic, which is
+			// 100_000 of revenue.
+			name:      "first hop",
+			portion:   1_000,
+			threshold: 100_000,
+			hops:      1,
+		},
+		{
+			name:      "second hop",
+			portion:   1_000,
+			threshold: 100_001,
+			hops:      2,
+		},
+		{
+			name:      "second hop exact",
+			portion:   1_000,
+			threshold: 1_000_000,
+			hops:      2,
+		},
+		{
+			name:      "third hop",
+			portion:   1_000,
+			threshold: 1_000_001,
+			hops:      3,
+		},
+		{
+			name:      "slow growth",
+			portion:   5_000,
+			threshold: 800_000,
+			hops:      7,
+		},
+		{
+			// Each node contributes 0.5% of its peer's traffic, so
+			// the first hop only has 2_000_000 of revenue.
+			name:      "sub-percent portion",
+			portion:   50,
+			threshold: 2_000_001,
+			hops:      2,
+		},
+		{
+			name:      "no growth",
+			portion:   10_000,
+			threshold: 1_000_000,
+			err:       errThresholdUnreachable,
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			hops, err := MinHopsToReach(
+				120_000, testCase.portion, testCase.threshold,
+				Params{},
+			)
+			require.ErrorIs(t, err, testCase.err)
+			require.Equal(t, testCase.hops, hops)
+		})
+	}
+
+	_, err := MinHopsToReach(120_000, 0, 100, Params{})
+	require.Error(t, err)
+
+	_, err = MinHopsToReach(120_000, basisPoints+1, 100, Params{})
+	require.Error(t, err)
+
+	// A shorter reputation period gives each hop more revenue, so the
+	// threshold is reached sooner.
+	hops, err := MinHopsToReach(
+		120_000, 1_000, 1_000_001, Params{ReputationPeriodWeeks: 12},
+	)
+	require.NoError(t, err)
+	require.Equal(t, 2, hops)
+
+	// Large traffic saturates rather than wrapping around to a small
+	// value that needs more hops.
+	firstNodeTraffic := uint64(math.MaxUint64 / 20)
+	hops, err = MinHopsToReach(
+		firstNodeTraffic, 5_000, mulDiv(firstNodeTraffic, 4, 24),
+		Params{},
+	)
+	require.NoError(t, err)
+	require.Equal(t, 1, hops)
+}
+
+// TestSustainedJamBlocks tests that burning reputation to jam limits the
+// attacker to jamming for less than the two week revenue period.
+func TestSustainedJamBlocks(t *testing.T) {
+	scenario := newScenario(
+		120_000, []uint8{100, 10, 25, 50}, 30_000, 300,
+	)
+	cfg := scenario.cfg
+
+	attack, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	attackAmt, totalCltv := scenario.attackerPayment, scenario.cltvTotal
+
+	endorsed, err := attack.TotalEndorsedOnTarget(attackAmt, totalCltv)
+	require.NoError(t, err)
+	require.EqualValues(t, 10, endorsed)
+
+	// The attacker's surplus of 20_000 is entirely burned by a single jam,
+	// so they can only jam for one hold period.
+	blocks := attack.sustainedJamBlocks(attackAmt, endorsed, totalCltv)
+	require.EqualValues(t, totalCltv, blocks)
+	require.Less(
+		t, blocks, revenuePeriodWeeks*Params{}.blocksPerWeek(),
+	)
+
+	// Paying more allows the attacker to sustain the jam for longer, but
+	// they still need to pay enough for each jam.
+	blocks = attack.sustainedJamBlocks(attackAmt*2, endorsed, totalCltv)
+	require.EqualValues(t, totalCltv*2, blocks)
+
+	require.Zero(t, attack.sustainedJamBlocks(5_000, endorsed, totalCltv))
+
+	// If fast blocks and a large multiplier make each jam burn almost
+	// nothing, a large payment can repeat the jam for longer than we can
+	// count, which saturates rather than wrapping around.
+	cfg.params = Params{
+		EndorsementMultiplier: 1_000,
+		SecondsPerBlock:       1,
+	}
+	attack, err = newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	blocks = attack.sustainedJamBlocks(math.MaxUint64, 1, totalCltv)
+	require.EqualValues(t, uint64(math.MaxUint64), blocks)
+}
+
+// TestRecoveryTime tests calculation of the time it takes a target to rebuild
+// the reputation lost in an attack.
+func TestRecoveryTime(t *testing.T) {
+	outcome := AttackOutcome{
+		targetReputation: 4_800_000,
+		targetThreshold:  800_000,
+		reputationChange: 1_000_000,
+	}
+
+	// The target builds 200_000 reputation per week with its normal
+	// traffic, so it takes exactly five weeks to recover.
+	weeks, err := outcome.RecoveryTime(4_800_000 / reputationPeriodWeeks)
+	require.NoError(t, err)
+	require.EqualValues(t, 5, weeks)
+
+	// Partial weeks are rounded up.
+	weeks, err = outcome.RecoveryTime(300_000)
+	require.NoError(t, err)
+	require.EqualValues(t, 4, weeks)
+
+	_, err = outcome.RecoveryTime(0)
+	require.ErrorIs(t, err, errNoRecovery)
+
+	// If nothing was lost, there's nothing to recover.
+	weeks, err = AttackOutcome{}.RecoveryTime(0)
+	require.NoError(t, err)
+	require.Zero(t, weeks)
+
+	// Rounding up a loss that can't grow any larger doesn't wrap.
+	outcome.reputationChange = math.MaxUint64
+	weeks, err = outcome.RecoveryTime(2)
+	require.NoError(t, err)
+	require.EqualValues(t, uint64(math.MaxUint64/2+1), weeks)
+}
+
+// TestReputationScalingSaturates tests that scaling large reputations by
+// percentages saturates rather than wrapping around to small values.
+func TestReputationScalingSaturates(t *testing.T) {
+	// Round trip requirements scale reputation down without overflowing
+	// the product.
+	c := channel{roundTripPercent: 50}
+	require.EqualValues(
+		t, uint64(math.MaxUint64/2), c.peerReputation(math.MaxUint64),
+	)
+
+	// A base fee this large earns every node the maximum reputation,
+	// which the target's uptime scales down and its last hop weight
+	// scales back up.
+	scenario := newScenario(
+		120_000, []uint8{100, 10, 25, 50}, 30_000, 300,
+	)
+	cfg := scenario.cfg
+	for i := range cfg.trafficFlows {
+		cfg.trafficFlows[i].feePolicy = FeePolicy{
+			BaseMsat: math.MaxUint64,
+		}
+	}
+	cfg.trafficFlows[2].uptimePercent = 50
+	cfg.lastHopWeightPercent = 300
+
+	attack, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	target, _ := attack.targetChannels()
+	require.EqualValues(
+		t, uint64(math.MaxUint64), target.incomingReputation,
+	)
+
+	cfg.lastHopWeightPercent = 0
+	attack, err = newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	target, _ = attack.targetChannels()
+	require.EqualValues(
+		t, uint64(math.MaxUint64/2), target.incomingReputation,
+	)
+
+	// Spreading a large change over the revenue period scales it down
+	// without overflowing.
+	windowBlocks := revenuePeriodWeeks * Params{}.blocksPerWeek()
+	change := averagedReputationChange(
+		math.MaxUint64, windowBlocks/2, Params{},
+	)
+	require.EqualValues(t, uint64(math.MaxUint64/2), change)
+
+	// Cltv deltas that can't be summed saturate.
+	attack.channels[0].cltvDelta = math.MaxUint64
+	require.EqualValues(t, uint64(math.MaxUint64), attack.routeDelta())
+}
+
+// TestWeeklyGrowthCap tests that capping reputation growth per week limits the
+// reputation that an attacker can build within the revenue period.
+func TestWeeklyGrowthCap(t *testing.T) {
+	scenario := newScenario(
+		120_000, []uint8{100, 10, 25, 50}, 30_000, 300,
+	)
+	cfg := scenario.cfg
+
+	attackAmt, totalCltv := scenario.attackerPayment, scenario.cltvTotal
+
+	uncapped, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+	require.Equal(t, attackAmt, uncapped.attackerReputation(attackAmt))
+
+	// With a cap of 12_000 per week, the attacker can only build 24_000
+	// reputation over the two week revenue period, and honest nodes can
+	// only build 288_000 over the reputation period.
+	cfg.weeklyGrowthCap = 12_000
+	capped, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+	require.EqualValues(t, 24_000, capped.attackerReputation(attackAmt))
+
+	require.EqualValues(t, 120_000, capped.channels[0].incomingReputation)
+	require.EqualValues(t, 288_000, capped.channels[1].incomingReputation)
+
+	// The capped honest reputation can't meet the third node's threshold,
+	// so the attack can't get anything endorsed.
+	endorsed, err := capped.TotalEndorsedOnTarget(attackAmt, totalCltv)
+	require.NoError(t, err)
+	require.Zero(t, endorsed)
+}
+
+// TestUptimeDegradation tests that an attacker who degrades the target's
+// uptime amplifies the reputation loss caused by jamming.
+func TestUptimeDegradation(t *testing.T) {
+	cfg := newScenario(
+		120_000, []uint8{100, 10, 25, 50}, 30_000, 300,
+	).cfg
+
+	var (
+		// Jamming 1_500 with a total cltv of 460 holds it on the
+		// target for 300 blocks, which costs 3_000_000 reputation.
+		totalEndorsed uint64 = 1_500
+		totalCltv     uint64 = 460
+	)
+
+	attack, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	outcome := attack.Outcome(totalEndorsed, totalCltv)
+	require.EqualValues(t, 4_800_000, outcome.targetReputation)
+	require.False(t, outcome.lostReputation())
+
+	// A node that's offline 10% of the time earns less reputation.
+	cfg.trafficFlows[2].uptimePercent = 90
+	attack, err = newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	outcome = attack.Outcome(totalEndorsed, totalCltv)
+	require.EqualValues(t, 4_320_000, outcome.targetReputation)
+	require.False(t, outcome.lostReputation())
+
+	// If the attacker knocks a further 30% off the target's uptime, the
+	// same jam causes it to lose reputation.
+	cfg.targetUptimeLoss = 30
+	attack, err = newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	outcome = attack.Outcome(totalEndorsed, totalCltv)
+	require.EqualValues(t, 2_880_000, outcome.targetReputation)
+	require.True(t, outcome.lostReputation())
+
+	// Other nodes are unaffected.
+	require.EqualValues(t, 1_200_000, attack.channels[1].incomingReputation)
+}
+
+// TestSlotCapacity tests that an attack that is feasible by amount is blocked
+// when a hop doesn't have enough endorsed slots for the attacker's HTLCs.
+func TestSlotCapacity(t *testing.T) {
+	scenario := newScenario(
+		1_000_000, []uint8{100, 50, 50, 25}, 1_000_000, 1000,
+	)
+	scenario.cfg.lastHopWeightPercent = 50
+	scenario.cfg.attackerSlots = 10
+
+	effective, err := scenario.effective()
+	require.NoError(t, err)
+	require.True(t, effective)
+
+	// If the target's incoming peer only has five endorsed slots, the
+	// attacker can't hold its HTLCs on that hop.
+	scenario.cfg.trafficFlows[1].slotCapacity = 5
+
+	effective, err = scenario.effective()
+	require.ErrorIs(t, err, errInsufficientSlots)
+	require.False(t, effective)
+
+	// Splitting the amount across fewer HTLCs makes the attack feasible
+	// again.
+	scenario.cfg.attackerSlots = 5
+
+	effective, err = scenario.effective()
+	require.NoError(t, err)
+	require.True(t, effective)
+}
+
+// TestCheapestAttack tests selection of the cheapest way to acquire the
+// reputation needed to jam the target.
+func TestCheapestAttack(t *testing.T) {
+	cfg := ladderingAttackCfg{
+		firstNodeTraffic: 1_000_000,
+		trafficFlows: []trafficFlow{
+			{
+				portionBasisPoints: 10_000,
+			},
+			{
+				portionBasisPoints: 5_000,
+			},
+			{
+				portionBasisPoints: 5_000,
+			},
+			{
+				portionBasisPoints: 2_500,
+			},
+		},
+		lastHopWeightPercent: 50,
+	}
+
+	var (
+		attackAmt uint64 = 1_000_000
+		totalCltv uint64 = 1000
+	)
+
+	attack, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	endorsed, err := attack.TotalEndorsedOnTarget(attackAmt, totalCltv)
+	require.NoError(t, err)
+
+	// Without a market for channels, laddering is cheaper than acquiring
+	// reputation with the target directly.
+	outcome := attack.Outcome(endorsed, totalCltv)
+	require.True(t, outcome.Effective(attackAmt))
+
+	strategy, cost := outcome.cheapestAttack(attackAmt)
+	require.Equal(t, strategyLadder, strategy)
+	require.Equal(t, attackAmt, cost)
+
+	// If a reputable channel can be bought for less than the ladder costs,
+	// the attacker will buy it instead.
+	cfg.channelPurchaseCost = attackAmt / 2
+	attack, err = newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	outcome = attack.Outcome(endorsed, totalCltv)
+	strategy, cost = outcome.cheapestAttack(attackAmt)
+	require.Equal(t, strategyPurchase, strategy)
+	require.Equal(t, attackAmt/2, cost)
+
+	// An expensive channel is not worth buying.
+	outcome.purchaseCost = outcome.targetCost + 1
+	strategy, _ = outcome.cheapestAttack(attackAmt)
+	require.Equal(t, strategyLadder, strategy)
+
+	// If the ladder is more expensive than going directly, the attacker
+	// will acquire reputation with the target.
+	strategy, cost = outcome.cheapestAttack(outcome.targetCost + 1)
+	require.Equal(t, strategyDirect, strategy)
+	require.Equal(t, outcome.targetCost, cost)
+}
+
+// TestLadderFees tests that the fee policies of the nodes in a ladder change
+// the amount that an attacker can get endorsed on each hop.
+func TestLadderFees(t *testing.T) {
+	var (
+		attackAmt uint64 = 30_000
+		totalCltv uint64 = 300
+	)
+
+	tests := []struct {
+		name     string
+		fees     []FeePolicy
+		endorsed uint64
+	}{
+		{
+			name:     "pass through",
+			fees:     make([]FeePolicy, 4),
+			endorsed: 10,
+		},
+		{
+			// Charging 100% is equivalent to counting volume.
+			name: "full volume",
+			fees: []FeePolicy{
+				{PPM: 1_000_000},
+				{PPM: 1_000_000},
+				{PPM: 1_000_000},
+				{PPM: 1_000_000},
+			},
+			endorsed: 10,
+		},
+		{
+			// Halving the first node's fees halves the attacker's
+			// surplus on the first hop.
+			name: "cheap first node",
+			fees: []FeePolicy{
+				{PPM: 500_000},
+				{},
+				{},
+				{},
+			},
+			endorsed: 5,
+		},
+		{
+			// A node with very low fees doesn't accumulate enough
+			// surplus to endorse anything, so it can't be laddered.
+			name: "cheap second node",
+			fees: []FeePolicy{
+				{},
+				{PPM: 100},
+				{},
+				{},
+			},
+			endorsed: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := newScenario(
+				120_000, []uint8{100, 10, 25, 50}, 30_000, 300,
+			).cfg
+			for i, fees := range test.fees {
+				cfg.trafficFlows[i].feePolicy = fees
+			}
+
+			attack, err := newLadderingAttack(cfg)
+			require.NoError(t, err)
+
+			endorsed, err := attack.TotalEndorsedOnTarget(
+				attackAmt, totalCltv,
+			)
+			require.NoError(t, err)
+			require.Equal(t, test.endorsed, endorsed)
+		})
+	}
+}
+
+// TestPerHopCltvDelta tests that per-hop cltv deltas determine the hold time
+// that is used to calculate the endorsed amount on each hop.
+func TestPerHopCltvDelta(t *testing.T) {
+	scenario := newScenario(
+		120_000, []uint8{100, 10, 25, 50}, 70_000, 400,
+	)
+	cfg := scenario.cfg
+
+	attackAmt, totalCltv := scenario.attackerPayment, scenario.cltvTotal
+
+	// With default deltas, the second hop holds the HTLC for 320 blocks
+	// which limits the endorsed amount.
+	uniform, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	endorsed, err := uniform.TotalEndorsedOnTarget(attackAmt, totalCltv)
+	require.NoError(t, err)
+	require.EqualValues(t, 9, endorsed)
+
+	final, err := uniform.FinalCLTV(totalCltv)
+	require.NoError(t, err)
+	require.EqualValues(t, 160, final)
+
+	// If the first node takes a large delta, the second hop holds the
+	// HTLC for less time so more can be endorsed there.
+	for i, delta := range []uint64{200, 40, 40, 40} {
+		cfg.trafficFlows[i].cltvDelta = delta
+	}
+
+	perHop, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	endorsed, err = perHop.TotalEndorsedOnTarget(attackAmt, totalCltv)
+	require.NoError(t, err)
+	require.EqualValues(t, 15, endorsed)
+
+	final, err = perHop.FinalCLTV(totalCltv)
+	require.NoError(t, err)
+	require.EqualValues(t, 120, final)
+
+	// Deltas that sum to more than the total cltv are insufficient.
+	_, err = perHop.TotalEndorsedOnTarget(attackAmt, 319)
+	require.ErrorIs(t, err, errInsufficientCltv)
+
+	_, err = perHop.TotalEndorsedOnTarget(attackAmt, 320)
+	require.NoError(t, err)
+
+	// The final cltv has the same boundary: exactly enough cltv leaves
+	// the final node with its delta, and one block less is insufficient.
+	final, err = perHop.FinalCLTV(320)
+	require.NoError(t, err)
+	require.EqualValues(t, 40, final)
+
+	_, err = perHop.FinalCLTV(319)
+	require.ErrorIs(t, err, errInsufficientCltv)
+	require.EqualError(t, err, "insufficient cltv: total: 319 < required: "+
+		"320 (route delta: 280 over 3 hops + final delta: 40)")
+
+	// Nodes that don't set a delta require the default, so leaving the
+	// first node's delta unset adds 80 blocks to the route.
+	cfg.trafficFlows[0].cltvDelta = 0
+	defaulted, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+	require.EqualValues(t, cltvDelta, defaulted.channels[0].cltvDelta)
+
+	final, err = defaulted.FinalCLTV(totalCltv)
+	require.NoError(t, err)
+	require.EqualValues(t, 240, final)
+
+	// A zero final delta is rejected.
+	cfg.trafficFlows[3].cltvDelta = 0
+	_, err = newLadderingAttack(cfg)
+	require.ErrorIs(t, err, errZeroFinalCltv)
+}
+
+// TestSlotExhaustion tests that an attack is effective when the attacker can
+// exhaust the target's endorsed slots, even if it can't jam it by value.
+func TestSlotExhaustion(t *testing.T) {
+	scenario := newScenario(
+		120_000, []uint8{100, 10, 25, 50}, 30_000, 300,
+	)
+	cfg := scenario.cfg
+
+	attackAmt, totalCltv := scenario.attackerPayment, scenario.cltvTotal
+
+	attack, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	endorsed, err := attack.TotalEndorsedOnTarget(attackAmt, totalCltv)
+	require.NoError(t, err)
+	require.EqualValues(t, 10, endorsed)
+
+	// With the default slot count and minimum HTLC size, the attacker
+	// can't hold a single HTLC.
+	outcome := attack.Outcome(endorsed, totalCltv)
+	require.Zero(t, outcome.endorsedSlots)
+	require.False(t, outcome.lostReputation())
+	require.False(t, outcome.Effective(attackAmt))
+
+	// If the target only has a few endorsed slots and small HTLCs are
+	// allowed, the attacker runs the target out of slots before value.
+	cfg.minHTLCSize = 2
+	cfg.trafficFlows[2].slotCapacity = 5
+
+	attack, err = newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	outcome = attack.Outcome(endorsed, totalCltv)
+	require.EqualValues(t, 5, outcome.endorsedSlots)
+	require.EqualValues(t, 5, outcome.targetSlots)
+	require.False(t, outcome.lostReputation())
+	require.True(t, outcome.slotsExhausted())
+	require.True(t, outcome.Effective(attackAmt))
+}
+
+// TestSlotExhaustionCloseness tests that closeness agrees with effectiveness
+// when the attacker exhausts the target's slots before jamming it by value,
+// and when the target's resources are split into buckets.
+func TestSlotExhaustionCloseness(t *testing.T) {
+	outcome := AttackOutcome{
+		targetReputation: 1_000_000,
+		targetThreshold:  500_000,
+		reputationChange: 100_000,
+		targetCost:       500_000,
+		targetSlots:      5,
+		endorsedSlots:    4,
+	}
+
+	// The attacker is short of one slot, and far from jamming the target
+	// by value.
+	require.False(t, outcome.Effective(100_000))
+	require.InDelta(t, 0.8, outcome.Closeness(100_000), 0.0001)
+
+	// Exhausting the slots makes the attack effective without losing the
+	// target any reputation.
+	outcome.endorsedSlots = 5
+	require.False(t, outcome.lostReputation())
+	require.True(t, outcome.Effective(100_000))
+	require.GreaterOrEqual(t, outcome.Closeness(100_000), 1.0)
+
+	// When resources are split, the attacker needs to occupy the
+	// protected bucket regardless of slots.
+	outcome.protectedSplit = true
+	outcome.endorsedValue = 3_000
+	outcome.protectedShortfall = 1_000
+	require.False(t, outcome.Effective(100_000))
+	require.InDelta(t, 0.75, outcome.Closeness(100_000), 0.0001)
+
+	outcome.protectedShortfall = 0
+	require.True(t, outcome.Effective(100_000))
+	require.GreaterOrEqual(t, outcome.Closeness(100_000), 1.0)
+
+	// Occupying the bucket isn't harmful if the target never had good
+	// reputation.
+	outcome.targetReputation = 400_000
+	require.False(t, outcome.Effective(100_000))
+	require.InDelta(t, 0.8, outcome.Closeness(100_000), 0.0001)
+}
+
+// TestWeeklyTrafficProfile tests that a node whose traffic was front-loaded
+// months ago has low recent revenue, and low reputation when reputation
+// decays.
+func TestWeeklyTrafficProfile(t *testing.T) {
+	cfg := newScenario(
+		120_000, []uint8{100, 10, 25}, 30_000, 300,
+	).cfg
+
+	constant, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	// A profile with equal weights is the same as constant traffic.
+	even := make([]uint64, reputationPeriodWeeks)
+	for i := range even {
+		even[i] = 1
+	}
+	cfg.trafficFlows[1].weeklyProfile = even
+
+	evenAttack, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+	require.Equal(t, constant.channels, evenAttack.channels)
+
+	// All of the second node's traffic was forwarded more than three
+	// months ago.
+	frontLoaded := make([]uint64, reputationPeriodWeeks)
+	for i := reputationPeriodWeeks / 2; i < len(frontLoaded); i++ {
+		frontLoaded[i] = 1
+	}
+	cfg.trafficFlows[1].weeklyProfile = frontLoaded
+
+	flat, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	// Under a flat window, the node still looks reputable but it has no
+	// recent revenue.
+	require.Equal(t, constant.channels[1].incomingReputation,
+		flat.channels[1].incomingReputation)
+	require.EqualValues(t, 100_000, constant.channels[1].outgoingRevenue)
+	require.Zero(t, flat.channels[1].outgoingRevenue)
+
+	// When reputation decays, the node's old traffic counts for much less
+	// than constant traffic would.
+	cfg.params = Params{
+		DecayHalfLifeWeeks: 4,
+	}
+	decayed, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	cfg.trafficFlows[1].weeklyProfile = nil
+	decayedConstant, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	require.Less(t, decayed.channels[1].incomingReputation*4,
+		decayedConstant.channels[1].incomingReputation)
+
+	// Other nodes are unaffected.
+	require.Equal(t, decayedConstant.channels[0], decayed.channels[0])
+	require.Equal(t, decayedConstant.channels[2], decayed.channels[2])
+}
+
+// TestMinEffectivePayment tests searching for the smallest payment that makes
+// a laddering attack effective.
+func TestMinEffectivePayment(t *testing.T) {
+	// The network in TestLadderAttackSetup can't be attacked, because the
+	// amount that can be endorsed on the target is limited by the hops
+	// before it regardless of how much the attacker pays.
+	attack, err := newLadderingAttack(newScenario(
+		120_000, []uint8{100, 10, 25, 50}, 30_000, 300,
+	).cfg)
+	require.NoError(t, err)
+
+	_, _, ok, err := attack.minEffectivePayment(300)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	scenario := newScenario(
+		1_000_000, []uint8{100, 50, 50, 25}, 1_000_000, 1000,
+	)
+	scenario.cfg.lastHopWeightPercent = 50
+
+	attack, err = newLadderingAttack(scenario.cfg)
+	require.NoError(t, err)
+
+	payment, outcome, ok, err := attack.minEffectivePayment(1000)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.EqualValues(t, 883_333, payment)
+	require.True(t, outcome.Effective(payment))
+
+	// One msat less doesn't get enough endorsed to jam the target.
+	endorsed, err := attack.TotalEndorsedOnTarget(payment-1, 1000)
+	require.NoError(t, err)
+	require.False(t, attack.Outcome(endorsed, 1000).Effective(payment-1))
+
+	// Hold times that don't cover the route's cltv deltas are rejected.
+	_, _, _, err = attack.minEffectivePayment(100)
+	require.ErrorIs(t, err, errInsufficientCltv)
+}
+
+// TestMinEffectivePaymentAboveJam tests that the smallest effective payment
+// is found when laddering isn't cheaper than attacking the target directly at
+// the smallest payment that jams it, but is cheaper with a larger payment.
+func TestMinEffectivePaymentAboveJam(t *testing.T) {
+	// The first hop's threshold of 500_000 is far above the target's
+	// revenue of 100_000, so the attacker pays more to start laddering
+	// than it would to build reputation with the target directly. With
+	// the lowest endorsement level, each msat that the attacker pays
+	// above the first hop's threshold gets seven times as much jammed on
+	// the target, so the direct cost catches up as the payment grows.
+	attack := &LadderingAttack{
+		channels: []channel{
+			{
+				incomingReputation: 10_000_000,
+				outgoingRevenue:    500_000,
+				slotCapacity:       maxHTLCSlots,
+			},
+			{
+				incomingReputation: 1_100_000,
+				outgoingRevenue:    100_000,
+				slotCapacity:       maxHTLCSlots,
+			},
+			{
+				outgoingRevenue: 1_000_000,
+				slotCapacity:    maxHTLCSlots,
+			},
+		},
+		endorsementLevel: 1,
+		attackerSlots:    1,
+		settlePercent:    100,
+	}
+
+	payment, outcome, ok, err := attack.minEffectivePayment(300)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.True(t, outcome.Effective(payment))
+
+	// The smallest payment that jams the target isn't effective.
+	jamPayment, err := smallestPayment(0, payment,
+		func(payment uint64) (bool, error) {
+			endorsed, err := attack.TotalEndorsedOnTarget(
+				payment, 300,
+			)
+
+			return attack.Outcome(endorsed, 300).jams(), err
+		},
+	)
+	require.NoError(t, err)
+	require.Less(t, jamPayment, payment)
+
+	endorsed, err := attack.TotalEndorsedOnTarget(jamPayment, 300)
+	require.NoError(t, err)
+	require.False(t, attack.Outcome(endorsed, 300).Effective(jamPayment))
+
+	// One msat less than the payment found isn't effective either.
+	endorsed, err = attack.TotalEndorsedOnTarget(payment-1, 300)
+	require.NoError(t, err)
+	require.False(t, attack.Outcome(endorsed, 300).Effective(payment-1))
+}
+
+// TestMinEffectivePaymentProtected tests that the search for the smallest
+// effective payment requires the attacker to occupy the protected bucket when
+// resources are split, rather than stopping once it jams the target by value.
+func TestMinEffectivePaymentProtected(t *testing.T) {
+	scenario := newScenario(
+		1_000_000, []uint8{100, 50, 50, 25}, 1_000_000, 1000,
+	)
+	scenario.cfg.lastHopWeightPercent = 50
+	scenario.cfg.protectedSlotPercent = 10
+	scenario.cfg.protectedLiquidity = 130
+
+	attack, err := newLadderingAttack(scenario.cfg)
+	require.NoError(t, err)
+
+	payment, outcome, ok, err := attack.minEffectivePayment(1000)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.True(t, outcome.protectedOccupied())
+	require.True(t, outcome.Effective(payment))
+
+	// A smaller payment already jams the target by value, but it doesn't
+	// occupy the protected bucket.
+	valuePayment, err := smallestPayment(0, payment,
+		func(payment uint64) (bool, error) {
+			endorsed, err := attack.TotalEndorsedOnTarget(
+				payment, 1000,
+			)
+
+			return attack.Outcome(endorsed, 1000).lostReputation(),
+				err
+		},
+	)
+	require.NoError(t, err)
+	require.Less(t, valuePayment, payment)
+
+	endorsed, err := attack.TotalEndorsedOnTarget(payment-1, 1000)
+	require.NoError(t, err)
+
+	outcome = attack.Outcome(endorsed, 1000)
+	require.True(t, outcome.lostReputation())
+	require.False(t, outcome.Effective(payment-1))
+}
+
+// TestAttackOutcomeLossPercent tests the percentage of reputation that an
+// attack costs the target.
+func TestAttackOutcomeLossPercent(t *testing.T) {
+	require.Zero(t, AttackOutcome{reputationChange: 10}.lossPercent())
+
+	require.EqualValues(t, 33, AttackOutcome{
+		targetReputation: 3_000,
+		reputationChange: 1_000,
+	}.lossPercent())
+
+	require.EqualValues(t, 100, AttackOutcome{
+		targetReputation: 3_000,
+		reputationChange: 5_000,
+	}.lossPercent())
+}
+
+// TestAttackOutcomeMargin tests that the margin of a laddering attack is
+// positive exactly when the attack is effective.
+func TestAttackOutcomeMargin(t *testing.T) {
+	tests := []struct {
+		name    string
+		outcome AttackOutcome
+		payment uint64
+		margin  int64
+	}{
+		{
+			name: "effective",
+			outcome: AttackOutcome{
+				targetReputation: 1_000,
+				targetThreshold:  600,
+				reputationChange: 500,
+				targetCost:       2_000,
+			},
+			payment: 1_500,
+			margin:  500,
+		},
+		{
+			name: "ladder more expensive",
+			outcome: AttackOutcome{
+				targetReputation: 1_000,
+				targetThreshold:  600,
+				reputationChange: 500,
+				targetCost:       2_000,
+			},
+			payment: 2_500,
+			margin:  -500,
+		},
+		{
+			name: "target keeps reputation",
+			outcome: AttackOutcome{
+				targetReputation: 1_000,
+				targetThreshold:  600,
+				reputationChange: 300,
+				targetCost:       2_000,
+			},
+			payment: 1_500,
+			margin:  -100,
+		},
+		{
+			name: "exactly at threshold",
+			outcome: AttackOutcome{
+				targetReputation: 1_000,
+				targetThreshold:  600,
+				reputationChange: 400,
+				targetCost:       2_000,
+			},
+			payment: 1_500,
+			margin:  0,
+		},
+		{
+			name: "slots exhausted",
+			outcome: AttackOutcome{
+				targetReputation: 1_000,
+				targetThreshold:  600,
+				targetCost:       2_000,
+				endorsedSlots:    483,
+				targetSlots:      483,
+			},
+			payment: 1_500,
+			margin:  500,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			margin := test.outcome.Margin(test.payment)
+			require.Equal(t, test.margin, margin)
+			require.Equal(t, test.outcome.Effective(test.payment),
+				margin > 0)
+		})
+	}
+}
+
+// TestTrafficOverflow tests that traffic that is amplified beyond a uint64
+// by a long chain of small traffic portions is rejected rather than wrapping
+// around.
+func TestTrafficOverflow(t *testing.T) {
+	flows := make([]trafficFlow, 10)
+	for i := range flows {
+		flows[i] = trafficFlow{
+			portionBasisPoints: 100,
+		}
+	}
+
+	cfg := ladderingAttackCfg{
+		firstNodeTraffic: 1_000_000,
+		trafficFlows:     flows,
+	}
+
+	_, err := newLadderingAttack(cfg)
+	require.ErrorIs(t, err, errTrafficOverflow)
+
+	// A shorter chain fits comfortably.
+	cfg.trafficFlows = flows[:3]
+	attack, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+	require.EqualValues(t, uint64(1_000_000_000_000),
+		attack.channels[2].incomingReputation)
+}
+
+// TestSubPercentPortion tests amplifying traffic through a node that only
+// contributes a fraction of a percent of its downstream's traffic.
+func TestSubPercentPortion(t *testing.T) {
+	cfg := ladderingAttackCfg{
+		firstNodeTraffic: 120_000,
+		trafficFlows: []trafficFlow{
+			{portionBasisPoints: 10_000},
+			{portionBasisPoints: 50},
+			{portionBasisPoints: 2_500},
+			{portionBasisPoints: 5_000},
+		},
+	}
+
+	attack, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	// Contributing 0.5% of traffic amplifies the next node's traffic by
+	// 200x.
+	require.EqualValues(t, 120_000, attack.channels[0].incomingReputation)
+	require.EqualValues(t, 24_000_000,
+		attack.channels[1].incomingReputation)
+	require.EqualValues(t, 96_000_000,
+		attack.channels[2].incomingReputation)
+
+	// Portions must be in [1, 10000] basis points.
+	cfg.trafficFlows[1].portionBasisPoints = 0
+	_, err = newLadderingAttack(cfg)
+	require.Error(t, err)
+
+	cfg.trafficFlows[1].portionBasisPoints = 10_001
+	_, err = newLadderingAttack(cfg)
+	require.Error(t, err)
+}
+
+// TestMinEffectiveLength tests finding the shortest ladder that results in an
+// effective attack.
+func TestMinEffectiveLength(t *testing.T) {
+	// Ladders of three and four nodes aren't effective, five nodes is,
+	// and extending the ladder to six nodes through a node with low
+	// reputation makes the attack ineffective again.
+	scenario := newScenario(
+		1_000_000, []uint8{100, 100, 50, 50, 10, 100}, 1_000_000, 1000,
+	)
+
+	var results []bool
+	for length := 3; length <= 6; length++ {
+		prefix := scenario
+		prefix.cfg.trafficFlows = scenario.cfg.trafficFlows[:length]
+
+		effective, err := prefix.effective()
+		require.NoError(t, err)
+		results = append(results, effective)
+	}
+	require.Equal(t, []bool{false, false, true, false}, results)
+
+	length, outcome, ok, err := scenario.cfg.minEffectiveLength(
+		scenario.attackerPayment, scenario.cltvTotal,
+	)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 5, length)
+	require.True(t, outcome.Effective(scenario.attackerPayment))
+
+	// If the cltv can't cover longer ladders, they're skipped.
+	_, _, ok, err = scenario.cfg.minEffectiveLength(
+		scenario.attackerPayment, 300,
+	)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// The network from TestLadderAttackSetup is never effective.
+	scenario = newScenario(120_000, []uint8{100, 10, 25, 50}, 30_000, 300)
+	_, _, ok, err = scenario.cfg.minEffectiveLength(
+		scenario.attackerPayment, scenario.cltvTotal,
+	)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+// TestSettlePercent tests that the attacker only earns reputation from the
+// payments that settle, while still paying for every payment that it sends.
+func TestSettlePercent(t *testing.T) {
+	scenario := newScenario(
+		1_000_000_000, []uint8{100, 50, 50, 25}, 1_000_000_000, 1000,
+	)
+	scenario.cfg.lastHopWeightPercent = 50
+
+	attack, err := newLadderingAttack(scenario.cfg)
+	require.NoError(t, err)
+
+	endorsed, err := attack.TotalEndorsedOnTarget(
+		scenario.attackerPayment, scenario.cltvTotal,
+	)
+	require.NoError(t, err)
+
+	outcome := attack.Outcome(endorsed, scenario.cltvTotal)
+	require.True(t, outcome.Effective(scenario.attackerPayment))
+	require.EqualValues(t, 94_199_733,
+		outcome.Margin(scenario.attackerPayment))
+
+	// When only half of the attacker's payments settle, it earns half of
+	// the reputation and can't get enough endorsed to jam the target. The
+	// payment isn't discounted for the portion that fails.
+	settlePercent := uint8(50)
+	scenario.cfg.settlePercent = &settlePercent
+
+	attack, err = newLadderingAttack(scenario.cfg)
+	require.NoError(t, err)
+
+	halfEndorsed, err := attack.TotalEndorsedOnTarget(
+		scenario.attackerPayment, scenario.cltvTotal,
+	)
+	require.NoError(t, err)
+	require.Less(t, halfEndorsed, endorsed)
+
+	outcome = attack.Outcome(halfEndorsed, scenario.cltvTotal)
+	require.Equal(t, scenario.attackerPayment,
+		outcome.ladderCost(scenario.attackerPayment))
+	require.False(t, outcome.Effective(scenario.attackerPayment))
+
+	// Sending twice as much settles the same amount as before, so the
+	// attacker gets as much endorsed, but it pays for the full amount
+	// that it sends so the ladder is no longer cheaper.
+	doubled := scenario.attackerPayment * 2
+	halfEndorsed, err = attack.TotalEndorsedOnTarget(
+		doubled, scenario.cltvTotal,
+	)
+	require.NoError(t, err)
+	require.Equal(t, endorsed, halfEndorsed)
+
+	outcome = attack.Outcome(halfEndorsed, scenario.cltvTotal)
+	require.True(t, outcome.lostReputation())
+	require.False(t, outcome.Effective(doubled))
+
+	// An attacker that only probes the ladder with payments that fail
+	// earns no reputation at all.
+	settlePercent = 0
+	attack, err = newLadderingAttack(scenario.cfg)
+	require.NoError(t, err)
+	require.Zero(t, attack.attackerReputation(scenario.attackerPayment))
+
+	endorsed, err = attack.TotalEndorsedOnTarget(
+		scenario.attackerPayment, scenario.cltvTotal,
+	)
+	require.NoError(t, err)
+	require.Zero(t, endorsed)
+
+	settlePercent = 101
+	_, err = newLadderingAttack(scenario.cfg)
+	require.Error(t, err)
+}
+
+// TestSettleBurnedFees tests that the attacker burns the first node's fees on
+// the portion of its payment that settles, in addition to the payment itself.
+func TestSettleBurnedFees(t *testing.T) {
+	outcome := AttackOutcome{
+		settlePercent: 100,
+		firstHopFees: FeePolicy{
+			BaseMsat: 1_000,
+			PPM:      1_000,
+		},
+		capitalCost: 500,
+	}
+
+	// The full payment settles, so the attacker burns the base fee and
+	// 0.1% of it.
+	require.EqualValues(t, 2_000, outcome.burnedFees(1_000_000))
+	require.EqualValues(t, 1_002_500, outcome.ladderCost(1_000_000))
+
+	// Only the settled half pays the proportional fee.
+	outcome.settlePercent = 50
+	require.EqualValues(t, 1_500, outcome.burnedFees(1_000_000))
+	require.EqualValues(t, 1_002_000, outcome.ladderCost(1_000_000))
+
+	// Payments that all fail don't burn any fees.
+	outcome.settlePercent = 0
+	require.Zero(t, outcome.burnedFees(1_000_000))
+	require.EqualValues(t, 1_000_500, outcome.ladderCost(1_000_000))
+}
+
+// TestZeroFinalRevenue tests that ladders where the final node doesn't earn
+// any revenue are rejected, because the target's reputation would trivially
+// clear a zero threshold.
+func TestZeroFinalRevenue(t *testing.T) {
+	scenario := newScenario(10, []uint8{100, 100, 100}, 1_000, 300)
+
+	_, err := newLadderingAttack(scenario.cfg)
+	require.ErrorIs(t, err, errZeroRevenue)
+
+	_, err = runScenario(scenario.cfg, 1_000, 300)
+	require.ErrorIs(t, err, errZeroRevenue)
+
+	// With enough traffic for the final node to earn fees, the ladder is
+	// valid.
+	scenario = newScenario(1_000, []uint8{100, 100, 100}, 1_000, 300)
+
+	attack, err := newLadderingAttack(scenario.cfg)
+	require.NoError(t, err)
+	require.NotZero(t, attack.channels[2].outgoingRevenue)
 }
@@ -0,0 +1,109 @@
+package reputationfuzz
+
+// Params holds the parameters of the reputation algorithm that is being
+// modeled. The zero value uses the default parameters.
+type Params struct {
+	// RevenuePeriodWeeks is the period over which a node's revenue is
+	// assessed to set its reputation threshold. A zero value uses the
+	// default of two weeks.
+	RevenuePeriodWeeks uint64
+
+	// ReputationPeriodWeeks is the period over which a peer's forwards
+	// are counted towards its reputation. A zero value uses the default
+	// of 24 weeks.
+	ReputationPeriodWeeks uint64
+
+	// DecayHalfLifeWeeks models reputation as an exponential moving
+	// average of traffic with this half-life, rather than a flat total
+	// over the reputation period. This weights recent activity more
+	// heavily, as the reputation algorithm does. A zero value uses a flat
+	// window.
+	DecayHalfLifeWeeks uint64
+
+	// MinimumHTLC is the size of the smallest HTLC in msat that a peer
+	// must be able to get endorsed to be considered to have good
+	// reputation, which decides whether a peer is an interesting target.
+	// A zero value uses the default of roughly $1.
+	MinimumHTLC uint64
+
+	// EndorsementMultiplier is the number of seconds that a HTLC is
+	// expected to resolve in. A HTLC's reputation cost is its amount for
+	// each multiple of this period that it is held for, so a larger value
+	// makes HTLCs cheaper to get endorsed. A zero value uses the default
+	// of 90 seconds.
+	EndorsementMultiplier uint64
+
+	// SecondsPerBlock is the expected time between blocks, used to convert
+	// a HTLC's hold time from blocks to seconds. A zero value uses the
+	// default of ten minutes.
+	SecondsPerBlock uint64
+}
+
+// revenuePeriod returns the revenue period in weeks.
+func (p Params) revenuePeriod() uint64 {
+	if p.RevenuePeriodWeeks == 0 {
+		return revenuePeriodWeeks
+	}
+
+	return p.RevenuePeriodWeeks
+}
+
+// reputationPeriod returns the reputation period in weeks.
+func (p Params) reputationPeriod() uint64 {
+	if p.ReputationPeriodWeeks == 0 {
+		return reputationPeriodWeeks
+	}
+
+	return p.ReputationPeriodWeeks
+}
+
+// minimumHTLC returns the minimum HTLC size that a peer with good reputation
+// can get endorsed, in msat.
+func (p Params) minimumHTLC() uint64 {
+	if p.MinimumHTLC == 0 {
+		return minimumHTLCReputation
+	}
+
+	return p.MinimumHTLC
+}
+
+// endorsementMultiplier returns the number of seconds that a HTLC is expected
+// to resolve in.
+func (p Params) endorsementMultiplier() uint64 {
+	if p.EndorsementMultiplier == 0 {
+		return endorsementMultiplier
+	}
+
+	return p.EndorsementMultiplier
+}
+
+// secondsPerBlock returns the expected time between blocks in seconds.
+func (p Params) secondsPerBlock() uint64 {
+	if p.SecondsPerBlock == 0 {
+		return secondsPerBlock
+	}
+
+	return p.SecondsPerBlock
+}
+
+// blocksPerWeek returns the approximate number of blocks mined in a week at
+// the expected time between blocks.
+func (p Params) blocksPerWeek() uint64 {
+	return secondsPerWeek / p.secondsPerBlock()
+}
+
+// blocksPerYear returns the approximate number of blocks mined in a year at
+// the expected time between blocks.
+func (p Params) blocksPerYear() uint64 {
+	return secondsPerYear / p.secondsPerBlock()
+}
+
+// weighting returns the weighting that is applied to traffic when calculating
+// reputation, nil if traffic is counted uniformly over a flat window.
+func (p Params) weighting() reputationWeighting {
+	if p.DecayHalfLifeWeeks == 0 {
+		return nil
+	}
+
+	return emaWeighting(p.DecayHalfLifeWeeks)
+}
@@ -0,0 +1,96 @@
+package reputationfuzz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestReputationParams tests that zero valued params use the default periods
+// and that custom periods are threaded through the attack models.
+func TestReputationParams(t *testing.T) {
+	// A zero value uses our defaults, so we never divide by a zero
+	// reputation period.
+	require.EqualValues(t, 1_000_000, revenueFromReputation(
+		12_000_000, Params{},
+	))
+
+	// A one week revenue period over a 12 week reputation period.
+	params := Params{
+		RevenuePeriodWeeks:    1,
+		ReputationPeriodWeeks: 12,
+	}
+	require.EqualValues(t, 1_000_000, revenueFromReputation(
+		12_000_000, params,
+	))
+
+	params.RevenuePeriodWeeks = 3
+	require.EqualValues(t, 3_000_000, revenueFromReputation(
+		12_000_000, params,
+	))
+
+	// Surge attacks use the params to calculate revenue.
+	outcome, err := surgeAttack(
+		[]uint64{12_000_000, 24_000_000}, 0, surgeAttackCfg{
+			params: params,
+		},
+	)
+	require.NoError(t, err)
+	require.EqualValues(t, 9_000_000, outcome.peaceRevenue)
+	require.EqualValues(t, 6_000_000, outcome.attackRevenue)
+
+	// Ladder attacks use the params to calculate outgoing revenue.
+	cfg := newScenario(
+		120_000, []uint8{100, 10, 25}, 30_000, 300,
+	).cfg
+	cfg.params = params
+
+	attack, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+	require.EqualValues(t, 30_000, attack.channels[0].outgoingRevenue)
+	require.EqualValues(t, 300_000, attack.channels[1].outgoingRevenue)
+	require.EqualValues(t, 1_200_000, attack.channels[2].outgoingRevenue)
+}
+
+// TestEndorsementParams tests that the endorsement multiplier and block time
+// set the reputation cost of a HTLC, and scale the amount that an attacker can
+// get endorsed.
+func TestEndorsementParams(t *testing.T) {
+	// The defaults match a 90 second multiplier and ten minute blocks.
+	require.EqualValues(t, 3_000_000, budgetForEndorsedValue(
+		1_500, 300, Params{
+			EndorsementMultiplier: 90,
+			SecondsPerBlock:       600,
+		},
+	))
+
+	// The cost and size of a HTLC are inverse to each other.
+	params := Params{
+		EndorsementMultiplier: 30,
+		SecondsPerBlock:       120,
+	}
+	cost := budgetForEndorsedValue(1_500, 300, params)
+	require.EqualValues(t, 1_800_000, cost)
+	require.EqualValues(t, 1_500, endorsedValueForBudget(cost, 300, params))
+
+	scenario := newScenario(
+		1_000_000, []uint8{100, 50, 100, 100}, 1_000_000, 300,
+	)
+	attack, err := newLadderingAttack(scenario.cfg)
+	require.NoError(t, err)
+
+	endorsed, err := attack.TotalEndorsedOnTarget(1_000_000, 300)
+	require.NoError(t, err)
+	require.EqualValues(t, 458, endorsed)
+
+	// Halving the block time halves the time that the attacker's HTLCs
+	// are held for, so it can get twice as much endorsed (less rounding).
+	scenario.cfg.params.SecondsPerBlock = 5 * 60
+
+	attack, err = newLadderingAttack(scenario.cfg)
+	require.NoError(t, err)
+
+	endorsed, err = attack.TotalEndorsedOnTarget(1_000_000, 300)
+	require.NoError(t, err)
+	require.EqualValues(t, 916, endorsed)
+}
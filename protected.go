@@ -0,0 +1,88 @@
+package reputationfuzz
+
+import "math"
+
+// protectedSlots returns the number of slots in the protected bucket of a
+// channel with the slot capacity provided, when the percentage of slots
+// provided is reserved for protected HTLCs. A zero percentage doesn't split
+// the channel's slots, so all of them are protected.
+func protectedSlots(capacity uint16, protectedPercent uint8) uint16 {
+	if protectedPercent == 0 {
+		return capacity
+	}
+
+	return uint16(uint64(capacity) * uint64(protectedPercent) / 100)
+}
+
+// protectedShortfall returns the additional endorsed value that the attacker
+// would need to occupy the protected bucket on the target's outgoing link,
+// given the total amount that it can get endorsed on the target. The attacker
+// occupies the bucket if it fills all of its slots with minimum sized HTLCs,
+// or fills its liquidity, so the shortfall is the smaller of the two. Zero is
+// returned if the attacker already occupies the bucket. A bucket without any
+// slots can't hold the attacker's HTLCs, so it can never be occupied and the
+// maximum shortfall is returned.
+func (l *LadderingAttack) protectedShortfall(totalEndorsed uint64) uint64 {
+	minHTLCSize := l.minHTLCSize
+	if minHTLCSize == 0 {
+		minHTLCSize = defaultMinHTLCSize
+	}
+
+	var (
+		target        = l.channels[len(l.channels)-2]
+		targetSlots   = uint64(target.slotCapacity)
+		endorsedSlots = l.endorsedSlots(totalEndorsed)
+	)
+
+	if targetSlots == 0 {
+		return math.MaxUint64
+	}
+
+	if endorsedSlots >= targetSlots {
+		return 0
+	}
+
+	shortfall := saturatingMul(targetSlots-endorsedSlots, minHTLCSize)
+	if l.protectedLiquidity == 0 {
+		return shortfall
+	}
+
+	if totalEndorsed >= l.protectedLiquidity {
+		return 0
+	}
+
+	remaining := l.protectedLiquidity - totalEndorsed
+	if remaining < shortfall {
+		return remaining
+	}
+
+	return shortfall
+}
+
+// protectedOccupied returns a boolean indicating whether the attacker's
+// endorsed HTLCs occupy the whole protected bucket on the target's outgoing
+// link. The attacker must hold some value endorsed to occupy the bucket.
+func (a AttackOutcome) protectedOccupied() bool {
+	return a.protectedShortfall == 0 && a.endorsedValue != 0
+}
+
+// TotalEndorsedOnProtected calculates the total amount that an attacker can
+// get endorsed on the target node given some payment amount and htlc hold
+// time, along with a boolean indicating whether the attacker's endorsed HTLCs
+// can occupy all of the protected slots or liquidity on the target's outgoing
+// link.
+func (l *LadderingAttack) TotalEndorsedOnProtected(attackerPayment,
+	totalCltv uint64) (uint64, bool, error) {
+
+	totalEndorsed, err := l.TotalEndorsedOnTarget(
+		attackerPayment, totalCltv,
+	)
+	if err != nil {
+		return 0, false, err
+	}
+
+	occupied := totalEndorsed != 0 &&
+		l.protectedShortfall(totalEndorsed) == 0
+
+	return totalEndorsed, occupied, nil
+}
@@ -0,0 +1,136 @@
+package reputationfuzz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestProtectedSlots tests splitting a channel's slots into protected and
+// general buckets.
+func TestProtectedSlots(t *testing.T) {
+	require.EqualValues(t, 483, protectedSlots(483, 0))
+	require.EqualValues(t, 483, protectedSlots(483, 100))
+	require.EqualValues(t, 241, protectedSlots(483, 50))
+	require.EqualValues(t, 0, protectedSlots(5, 10))
+}
+
+// TestProtectedBucket tests that an attack is only effective if it occupies
+// the protected bucket on the target's outgoing link when resources are
+// split, regardless of the value that it jams.
+func TestProtectedBucket(t *testing.T) {
+	scenario := newScenario(
+		1_000_000, []uint8{100, 50, 50, 25}, 1_000_000, 1000,
+	)
+	scenario.cfg.lastHopWeightPercent = 50
+
+	// Without a split, the attack is effective by value.
+	attack, err := newLadderingAttack(scenario.cfg)
+	require.NoError(t, err)
+
+	endorsed, occupied, err := attack.TotalEndorsedOnProtected(
+		scenario.attackerPayment, scenario.cltvTotal,
+	)
+	require.NoError(t, err)
+	require.EqualValues(t, 135, endorsed)
+	require.False(t, occupied)
+	require.True(t, attack.Outcome(endorsed, scenario.cltvTotal).Effective(
+		scenario.attackerPayment,
+	))
+
+	// With 10% of slots protected, the attacker's 135 msat can't fill
+	// the target's 48 protected slots with 1000 msat HTLCs, so the attack
+	// isn't harmful.
+	scenario.cfg.protectedSlotPercent = 10
+	attack, err = newLadderingAttack(scenario.cfg)
+	require.NoError(t, err)
+
+	endorsed, occupied, err = attack.TotalEndorsedOnProtected(
+		scenario.attackerPayment, scenario.cltvTotal,
+	)
+	require.NoError(t, err)
+	require.False(t, occupied)
+
+	outcome := attack.Outcome(endorsed, scenario.cltvTotal)
+	require.EqualValues(t, 48, outcome.targetSlots)
+	require.False(t, outcome.Effective(scenario.attackerPayment))
+	require.EqualValues(t, -48_000,
+		outcome.Margin(scenario.attackerPayment))
+
+	// If protected liquidity is limited, the attacker can occupy the
+	// bucket by filling it.
+	scenario.cfg.protectedLiquidity = 100
+	attack, err = newLadderingAttack(scenario.cfg)
+	require.NoError(t, err)
+
+	endorsed, occupied, err = attack.TotalEndorsedOnProtected(
+		scenario.attackerPayment, scenario.cltvTotal,
+	)
+	require.NoError(t, err)
+	require.True(t, occupied)
+	require.True(t, attack.Outcome(endorsed, scenario.cltvTotal).Effective(
+		scenario.attackerPayment,
+	))
+
+	// Smaller HTLCs also allow the attacker to fill every protected slot.
+	scenario.cfg.protectedLiquidity = 0
+	scenario.cfg.minHTLCSize = 1
+	attack, err = newLadderingAttack(scenario.cfg)
+	require.NoError(t, err)
+
+	_, occupied, err = attack.TotalEndorsedOnProtected(
+		scenario.attackerPayment, scenario.cltvTotal,
+	)
+	require.NoError(t, err)
+	require.True(t, occupied)
+
+	scenario.cfg.protectedSlotPercent = 101
+	_, err = newLadderingAttack(scenario.cfg)
+	require.Error(t, err)
+}
+
+// TestProtectedBucketEmpty tests that a protected bucket without any slots
+// can't be occupied, so an attack on it is never effective.
+func TestProtectedBucketEmpty(t *testing.T) {
+	scenario := newScenario(
+		1_000_000, []uint8{100, 50, 50, 25}, 1_000_000, 1000,
+	)
+	scenario.cfg.lastHopWeightPercent = 50
+	scenario.cfg.protectedSlotPercent = 10
+
+	// With 5 slots on the target's outgoing link, 10% of them rounds
+	// down to no protected slots at all.
+	scenario.cfg.trafficFlows[2].slotCapacity = 5
+	attack, err := newLadderingAttack(scenario.cfg)
+	require.NoError(t, err)
+
+	outcome := attack.Outcome(135, scenario.cltvTotal)
+	require.Zero(t, outcome.targetSlots)
+	require.False(t, outcome.protectedOccupied())
+	require.False(t, outcome.Effective(scenario.attackerPayment))
+	require.Negative(t, outcome.Margin(scenario.attackerPayment))
+
+	// Holding nothing endorsed doesn't occupy the bucket either.
+	outcome = attack.Outcome(0, scenario.cltvTotal)
+	require.False(t, outcome.protectedOccupied())
+	require.False(t, outcome.Effective(scenario.attackerPayment))
+}
+
+// TestProtectedBucketNoReputation tests that occupying the protected bucket
+// isn't harmful if the target never had good reputation with the final node,
+// because it couldn't use the bucket to begin with.
+func TestProtectedBucketNoReputation(t *testing.T) {
+	outcome := AttackOutcome{
+		targetReputation: 900,
+		targetThreshold:  1_000,
+		targetCost:       2_000,
+		protectedSplit:   true,
+		endorsedValue:    500,
+	}
+	require.True(t, outcome.protectedOccupied())
+	require.False(t, outcome.Effective(0))
+	require.EqualValues(t, -100, outcome.Margin(0))
+
+	outcome.targetReputation = 1_000
+	require.True(t, outcome.Effective(0))
+}
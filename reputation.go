@@ -0,0 +1,157 @@
+package reputationfuzz
+
+import (
+	"math"
+	"math/bits"
+)
+
+const (
+	// revenuePeriodWeeks is the default period over which revenue is
+	// assessed.
+	revenuePeriodWeeks = 2
+
+	// reputationPeriodWeeks is the default period over which reputation
+	// is assessed.
+	reputationPeriodWeeks = 24
+
+	// endorsementMultiplier is the default number of seconds that a HTLC
+	// is expected to resolve in. A HTLC's reputation cost is its amount
+	// for each multiple of this period that it is held for.
+	endorsementMultiplier = 90
+
+	// secondsPerBlock is the default expected time between blocks, used to
+	// convert a HTLC's hold time from blocks to seconds.
+	secondsPerBlock = 10 * 60
+)
+
+// revenueFromReputation returns the revenue over the revenue period that is
+// represented by the reputation provided, assuming a constant rate of traffic.
+// Reputation is the sum of each week's traffic adjusted by the params'
+// weighting, so we divide by the total weight to get the weekly rate of
+// traffic, which is the reputation period for a flat window.
+func revenueFromReputation(reputation uint64, params Params) uint64 {
+	// We can't express revenue for an empty reputation period. Our params
+	// default a zero period, but we guard against it regardless.
+	weight := totalWeight(params.reputationPeriod(), params.weighting())
+	if weight == 0 {
+		return 0
+	}
+
+	return mulDiv(
+		reputation, saturatingMul(params.revenuePeriod(), 100), weight,
+	)
+}
+
+// reputationForRevenue returns the reputation that a peer needs to represent
+// the revenue provided over the revenue period, assuming a constant rate of
+// traffic. This is the inverse of revenueFromReputation, and saturates at
+// math.MaxUint64 for revenue that can't be represented as reputation.
+func reputationForRevenue(revenue uint64, params Params) uint64 {
+	return mulDiv(
+		revenue, totalWeight(params.reputationPeriod(), params.weighting()),
+		saturatingMul(params.revenuePeriod(), 100),
+	)
+}
+
+// budgetForEndorsedValue returns the reputation budget that a peer needs to
+// get HTLCs with the total value provided endorsed when they're held for
+// htlcHold blocks, which is also the penalty for using them to slow jam. This
+// is rounded up, so that a budget is never understated, and is the exact
+// inverse of endorsedValueForBudget: it returns the smallest budget for which
+// endorsedValueForBudget gives at least the value provided. The budget
+// saturates at math.MaxUint64 rather than overflowing for large values and
+// holds.
+func budgetForEndorsedValue(value, htlcHold uint64, params Params) uint64 {
+	// Saturating the product of the value and hold before dividing would
+	// understate the budget, so we only saturate the result.
+	holdSeconds := saturatingMul(htlcHold, params.secondsPerBlock())
+	if holdSeconds == math.MaxUint64 && value != 0 {
+		return math.MaxUint64
+	}
+
+	return mulDivRoundUp(
+		value, holdSeconds, params.endorsementMultiplier(),
+	)
+}
+
+// endorsedValueForBudget returns the total value of HTLCs that a peer can get
+// endorsed with the reputation budget provided when they're held for htlcHold
+// blocks. This is rounded down, so that the value that an attacker can afford
+// is never overstated: budgetForEndorsedValue of the value returned never
+// exceeds the budget.
+func endorsedValueForBudget(budget, htlcHold uint64, params Params) uint64 {
+	return mulDiv(
+		budget, params.endorsementMultiplier(),
+		saturatingMul(htlcHold, params.secondsPerBlock()),
+	)
+}
+
+// saturatingMul returns a * b, saturating at math.MaxUint64 if the product
+// overflows.
+func saturatingMul(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	if hi != 0 {
+		return math.MaxUint64
+	}
+
+	return lo
+}
+
+// saturatingAdd returns a + b, saturating at math.MaxUint64 if the sum
+// overflows.
+func saturatingAdd(a, b uint64) uint64 {
+	sum, carry := bits.Add64(a, b, 0)
+	if carry != 0 {
+		return math.MaxUint64
+	}
+
+	return sum
+}
+
+// mulDiv returns a * b / c without overflowing on the intermediate product,
+// saturating at math.MaxUint64 if the result doesn't fit in a uint64.
+func mulDiv(a, b, c uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	if hi >= c {
+		return math.MaxUint64
+	}
+
+	quotient, _ := bits.Div64(hi, lo, c)
+
+	return quotient
+}
+
+// mulDivRoundUp returns a * b / c rounded up without overflowing on the
+// intermediate product, saturating at math.MaxUint64 if the result doesn't fit
+// in a uint64.
+func mulDivRoundUp(a, b, c uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	if hi >= c {
+		return math.MaxUint64
+	}
+
+	quotient, remainder := bits.Div64(hi, lo, c)
+	if remainder != 0 {
+		return saturatingAdd(quotient, 1)
+	}
+
+	return quotient
+}
+
+// signedDiff returns a - b as a signed value, saturating at the bounds of an
+// int64 if the difference doesn't fit.
+func signedDiff(a, b uint64) int64 {
+	if a >= b {
+		if a-b > math.MaxInt64 {
+			return math.MaxInt64
+		}
+
+		return int64(a - b)
+	}
+
+	if b-a > math.MaxInt64 {
+		return math.MinInt64
+	}
+
+	return -int64(b - a)
+}
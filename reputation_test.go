@@ -0,0 +1,192 @@
+package reputationfuzz
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestRevenueFromReputationSaturates tests that revenue is calculated without
+// overflowing for large reputation values.
+func TestRevenueFromReputationSaturates(t *testing.T) {
+	require.EqualValues(t, 1_000_000_000,
+		revenueFromReputation(12_000_000_000, Params{}))
+
+	// Multiplying before dividing would previously have wrapped around.
+	require.EqualValues(t, uint64(math.MaxUint64/12),
+		revenueFromReputation(math.MaxUint64, Params{}))
+
+	// A revenue period that overflows when scaled saturates rather than
+	// wrapping around to a tiny period.
+	huge := Params{RevenuePeriodWeeks: math.MaxUint64/100 + 1}
+	require.Greater(t, revenueFromReputation(1_000_000, huge),
+		revenueFromReputation(1_000_000, Params{}))
+
+	// A peer's revenue sums both directions without wrapping.
+	peer := surgePeer{bidirectionalPeer: bidirectionalPeer{
+		incoming: math.MaxUint64,
+		outgoing: math.MaxUint64,
+	}}
+	require.EqualValues(t, uint64(math.MaxUint64),
+		peer.revenue(Params{RevenuePeriodWeeks: 24}))
+}
+
+// TestReputationForRevenue tests converting revenue to reputation, and that
+// it round trips with revenueFromReputation.
+func TestReputationForRevenue(t *testing.T) {
+	require.EqualValues(t, 12_000_000_000,
+		reputationForRevenue(1_000_000_000, Params{}))
+
+	require.EqualValues(t, uint64(math.MaxUint64),
+		reputationForRevenue(math.MaxUint64, Params{}))
+
+	for _, params := range []Params{
+		{},
+		{RevenuePeriodWeeks: 1, ReputationPeriodWeeks: 4},
+		{DecayHalfLifeWeeks: 3},
+	} {
+		// Each conversion truncates, so values round trip to within
+		// the scaling factor between the two.
+		var (
+			weight = totalWeight(
+				params.reputationPeriod(), params.weighting(),
+			)
+			scale               = params.revenuePeriod() * 100
+			revenueTolerance    = scale/weight + 1
+			reputationTolerance = weight/scale + 1
+		)
+
+		for _, revenue := range []uint64{0, 1, 999, 1_000_000_007} {
+			reputation := reputationForRevenue(revenue, params)
+			roundTrip := revenueFromReputation(reputation, params)
+
+			require.LessOrEqual(t, roundTrip, revenue)
+			require.LessOrEqual(t, revenue-roundTrip, revenueTolerance)
+		}
+
+		for _, reputation := range []uint64{0, 11, 12_345_678_901} {
+			revenue := revenueFromReputation(reputation, params)
+			roundTrip := reputationForRevenue(revenue, params)
+
+			require.LessOrEqual(t, roundTrip, reputation)
+			require.LessOrEqual(t, reputation-roundTrip,
+				reputationTolerance)
+		}
+	}
+}
+
+// TestBudgetForEndorsedValueSaturates tests that the reputation budget for
+// large HTLCs saturates rather than wrapping around.
+func TestBudgetForEndorsedValueSaturates(t *testing.T) {
+	// Regular values are unchanged.
+	require.EqualValues(t, 3_000_000, budgetForEndorsedValue(
+		1_500, 300, Params{},
+	))
+
+	// 1000 BTC in msat held for two weeks overflows the intermediate
+	// product, but the result still fits.
+	var oneBTC uint64 = 100_000_000_000
+	require.EqualValues(t, uint64(1_344_000_000_000_000_000),
+		budgetForEndorsedValue(oneBTC*1000, 2016, Params{}))
+
+	// Larger amounts overflow the result, so saturate.
+	require.EqualValues(t, uint64(math.MaxUint64),
+		budgetForEndorsedValue(oneBTC*100_000, 2016, Params{}))
+	require.EqualValues(t, uint64(math.MaxUint64), budgetForEndorsedValue(
+		math.MaxUint64, math.MaxUint64, Params{},
+	))
+}
+
+// TestEndorsedValueForBudget tests that the value that a budget can get
+// endorsed and the budget that a value requires are exact inverses, rounded so
+// that the value an attacker can afford is never overstated.
+func TestEndorsedValueForBudget(t *testing.T) {
+	// The default 90 second multiplier doesn't divide evenly into blocks,
+	// so a 1 msat HTLC held for one block costs 6_2/3 msat.
+	require.EqualValues(t, 7, budgetForEndorsedValue(1, 1, Params{}))
+	require.EqualValues(t, 1, endorsedValueForBudget(7, 1, Params{}))
+	require.Zero(t, endorsedValueForBudget(6, 1, Params{}))
+
+	rand := rand.New(rand.NewSource(1))
+	for _, params := range []Params{
+		{},
+		{EndorsementMultiplier: 7, SecondsPerBlock: 13},
+		{EndorsementMultiplier: 1_000, SecondsPerBlock: 1},
+	} {
+		for i := 0; i < 10_000; i++ {
+			var (
+				budget = rand.Uint64() >> rand.Intn(64)
+				hold   = uint64(rand.Intn(maxCltvTotal) + 1)
+				value  = endorsedValueForBudget(
+					budget, hold, params,
+				)
+			)
+
+			// The budget for the value never exceeds the budget
+			// that it was calculated from.
+			require.LessOrEqual(t,
+				budgetForEndorsedValue(value, hold, params),
+				budget, "budget: %v, hold: %v", budget, hold)
+
+			// Unless the value saturates, one more msat can't be
+			// afforded so the value is the largest that the
+			// budget covers.
+			if value < math.MaxUint64 {
+				require.Greater(t, budgetForEndorsedValue(
+					value+1, hold, params,
+				), budget, "budget: %v, hold: %v", budget,
+					hold)
+			}
+
+			// Unless the budget saturates, the budget for a value
+			// always covers it.
+			required := budgetForEndorsedValue(budget, hold, params)
+			if required < math.MaxUint64 {
+				covered := endorsedValueForBudget(
+					required, hold, params,
+				)
+				require.GreaterOrEqual(t, covered, budget,
+					"value: %v, hold: %v", budget, hold)
+			}
+		}
+	}
+}
+
+// TestSaturatingArithmetic tests that the arithmetic helpers saturate rather
+// than wrapping around on overflow.
+func TestSaturatingArithmetic(t *testing.T) {
+	require.EqualValues(t, 6, saturatingMul(2, 3))
+	require.EqualValues(t, uint64(math.MaxUint64),
+		saturatingMul(math.MaxUint64, 2))
+
+	require.EqualValues(t, 5, saturatingAdd(2, 3))
+	require.EqualValues(t, uint64(math.MaxUint64),
+		saturatingAdd(math.MaxUint64, 1))
+
+	// The intermediate product overflows, but the result fits.
+	require.EqualValues(t, uint64(math.MaxUint64/2),
+		mulDiv(math.MaxUint64, 10, 20))
+	require.EqualValues(t, uint64(math.MaxUint64),
+		mulDiv(math.MaxUint64, 2, 1))
+
+	// Rounding up only applies to results that don't divide exactly.
+	require.EqualValues(t, 2, mulDivRoundUp(3, 2, 3))
+	require.EqualValues(t, 3, mulDivRoundUp(4, 2, 3))
+	require.EqualValues(t, uint64(math.MaxUint64/2+1),
+		mulDivRoundUp(math.MaxUint64, 10, 20))
+	require.EqualValues(t, uint64(math.MaxUint64),
+		mulDivRoundUp(math.MaxUint64, 2, 1))
+}
+
+// TestSignedDiff tests signed differences of unsigned values, saturating at
+// the bounds of an int64.
+func TestSignedDiff(t *testing.T) {
+	require.EqualValues(t, 5, signedDiff(10, 5))
+	require.EqualValues(t, -5, signedDiff(5, 10))
+	require.EqualValues(t, int64(math.MaxInt64),
+		signedDiff(math.MaxUint64, 0))
+	require.EqualValues(t, int64(math.MinInt64),
+		signedDiff(0, math.MaxUint64))
+}
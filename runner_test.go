@@ -0,0 +1,127 @@
+package reputationfuzz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestRunScenario tests running known scenarios through the same pipeline
+// that the fuzz test uses.
+func TestRunScenario(t *testing.T) {
+	ladder := func(firstNodeTraffic uint64, portions ...uint16) Config {
+		flows := make([]TrafficFlow, len(portions))
+		for i, portion := range portions {
+			flows[i] = TrafficFlow{
+				PortionBasisPoints: portion,
+			}
+		}
+
+		return Config{
+			FirstNodeTraffic:     firstNodeTraffic,
+			TrafficFlows:         flows,
+			LastHopWeightPercent: 50,
+		}
+	}
+
+	// The fees charged by the second node mean that it earns less
+	// revenue than the first.
+	decreasing := ladder(10_000_000_000, 10_000, 2_500, 1_000, 5_000)
+	decreasing.TrafficFlows[1].FeePolicy = FeePolicy{PPM: 1000}
+
+	// A small network is an interesting target if we lower the minimum
+	// HTLC that its peers must be able to get endorsed.
+	lowFloor := ladder(1_000_000, 10_000, 5_000, 5_000, 2_500)
+	lowFloor.Params.MinimumHTLC = 1
+
+	// If the final node only counts a small portion of the target's
+	// traffic as round trip, the target can't get a minimum sized HTLC
+	// endorsed even though its total reputation is large enough.
+	roundTrip := ladder(10_000_000_000, 10_000, 2_500, 1_000, 5_000)
+	roundTrip.TrafficFlows[3].RoundTripPercent = 1
+
+	tests := []struct {
+		name      string
+		cfg       Config
+		payment   uint64
+		cltv      uint64
+		effective bool
+		err       error
+	}{
+		{
+			name: "effective ladder",
+			cfg: ladder(
+				10_000_000_000, 10_000, 2_500, 1_000, 5_000,
+			),
+			payment:   10_000_000_000,
+			cltv:      1000,
+			effective: true,
+		},
+		{
+			name: "payment too small",
+			cfg: ladder(
+				10_000_000_000, 10_000, 2_500, 1_000, 5_000,
+			),
+			payment: 1_000_000_000,
+			cltv:    1000,
+		},
+		{
+			name:    "decreasing revenue",
+			cfg:     decreasing,
+			payment: 1_000_000_000,
+			cltv:    1000,
+			err:     errSkipInput,
+		},
+		{
+			name: "target can't endorse minimum htlc",
+			cfg: ladder(
+				1_000_000, 10_000, 5_000, 5_000, 2_500,
+			),
+			payment: 1_000_000,
+			cltv:    1000,
+			err:     errSkipInput,
+		},
+		{
+			name:    "round trip below minimum htlc",
+			cfg:     roundTrip,
+			payment: 10_000_000_000,
+			cltv:    1000,
+			err:     errSkipInput,
+		},
+		{
+			name:      "lower minimum htlc",
+			cfg:       lowFloor,
+			payment:   1_000_000,
+			cltv:      1000,
+			effective: true,
+		},
+		{
+			name: "insufficient cltv",
+			cfg: ladder(
+				10_000_000_000, 10_000, 2_500, 1_000, 5_000,
+			),
+			payment: 1_000_000_000,
+			cltv:    200,
+			err:     errInsufficientCltv,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := RunScenario(
+				test.cfg, test.payment, test.cltv,
+			)
+			require.ErrorIs(t, err, test.err)
+			if test.err != nil {
+				return
+			}
+
+			require.Equal(t, test.effective, result.Effective())
+		})
+	}
+
+	// Invalid configs fail outright rather than being skipped.
+	_, err := RunScenario(ladder(1_000_000_000, 10_000, 5_000), 1, 300)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, errSkipInput)
+}
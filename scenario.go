@@ -0,0 +1,148 @@
+package reputationfuzz
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+)
+
+// ladderScenario describes a single laddering attack to be evaluated: the
+// network that the attack is performed on and the attacker's choices.
+type ladderScenario struct {
+	cfg ladderingAttackCfg
+
+	attackerPayment uint64
+
+	cltvTotal uint64
+}
+
+func (s ladderScenario) String() string {
+	portions := make([]uint16, len(s.cfg.trafficFlows))
+	for i, flow := range s.cfg.trafficFlows {
+		portions[i] = flow.portionBasisPoints
+	}
+
+	return fmt.Sprintf("first node: %v, traffic portions (bps): %v, "+
+		"attacker payment: %v, cltv: %v", s.cfg.firstNodeTraffic,
+		portions, s.attackerPayment, s.cltvTotal)
+}
+
+// SeedCorpus returns the scenario as a call that adds it to the ladder fuzz
+// test's seed corpus, in Go source form, so that a finding can be pasted back
+// into the fuzz test to reproduce it.
+func (s ladderScenario) SeedCorpus() string {
+	description := make([]byte, 0, len(s.cfg.trafficFlows)*portionBytes)
+	for _, flow := range s.cfg.trafficFlows {
+		description = binary.LittleEndian.AppendUint16(
+			description, flow.portionBasisPoints,
+		)
+	}
+
+	return fmt.Sprintf("f.Add(uint64(%v), uint64(%v), uint64(%v), "+
+		"uint8(%v), %#v)", s.cfg.firstNodeTraffic, s.attackerPayment,
+		s.cltvTotal, len(s.cfg.trafficFlows), description)
+}
+
+// effective runs the scenario, returning a boolean indicating whether it is an
+// effective laddering attack.
+func (s ladderScenario) effective() (bool, error) {
+	ladder, err := newLadderingAttack(s.cfg)
+	if err != nil {
+		return false, err
+	}
+
+	totalEndorsed, err := ladder.TotalEndorsedOnTarget(
+		s.attackerPayment, s.cltvTotal,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	outcome := ladder.Outcome(totalEndorsed, s.cltvTotal)
+
+	return outcome.Effective(s.attackerPayment), nil
+}
+
+// corpusComparison describes the change in effective attacks between two
+// corpora of scenarios.
+type corpusComparison struct {
+	// added is the set of effective attacks that are only present in the
+	// second corpus.
+	added []ladderScenario
+
+	// removed is the set of effective attacks that are only present in
+	// the first corpus.
+	removed []ladderScenario
+}
+
+// compareCorpora evaluates two corpora of scenarios, returning the effective
+// attacks that have been introduced and closed between the first and second
+// corpus. Every scenario is evaluated with the params provided, so that the
+// same model parameters are used for both corpora. Scenarios that can't be
+// evaluated are treated as ineffective attacks.
+func compareCorpora(before, after []ladderScenario,
+	params Params) corpusComparison {
+
+	beforeEffective := effectiveScenarios(before, params)
+	afterEffective := effectiveScenarios(after, params)
+
+	return corpusComparison{
+		added:   difference(afterEffective, beforeEffective),
+		removed: difference(beforeEffective, afterEffective),
+	}
+}
+
+// difference returns the scenarios in the first set that are not in the
+// second.
+func difference(scenarios, other []ladderScenario) []ladderScenario {
+	var diff []ladderScenario
+	for _, scenario := range scenarios {
+		if !containsScenario(other, scenario) {
+			diff = append(diff, scenario)
+		}
+	}
+
+	return diff
+}
+
+// effectiveScenarios returns the scenarios in a corpus that are effective
+// attacks when evaluated with the params provided, without duplicates.
+func effectiveScenarios(corpus []ladderScenario,
+	params Params) []ladderScenario {
+
+	var effective []ladderScenario
+	for _, scenario := range corpus {
+		if containsScenario(effective, scenario) {
+			continue
+		}
+
+		// Params are held by value, so setting them on our copy of
+		// the scenario doesn't mutate the caller's corpus.
+		evaluated := scenario
+		evaluated.cfg.params = params
+
+		ok, err := evaluated.effective()
+		if err == nil && ok {
+			effective = append(effective, scenario)
+		}
+	}
+
+	return effective
+}
+
+// containsScenario returns a boolean indicating whether the set of scenarios
+// contains the scenario provided. Scenarios are compared on every field of
+// their config rather than their string representation, which only describes
+// the route's traffic portions. Recency weightings are functions, so they are
+// only considered equal when neither scenario has one.
+func containsScenario(scenarios []ladderScenario,
+	scenario ladderScenario) bool {
+
+	for _, other := range scenarios {
+		if reflect.DeepEqual(other, scenario) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,125 @@
+package reputationfuzz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newScenario creates a ladder scenario with the traffic portions provided,
+// expressed as whole percentages for brevity.
+func newScenario(firstNodeTraffic uint64, portions []uint8, attackerPayment,
+	cltvTotal uint64) ladderScenario {
+
+	flows := make([]trafficFlow, len(portions))
+	for i, portion := range portions {
+		flows[i] = trafficFlow{
+			portionBasisPoints: uint16(portion) * 100,
+		}
+	}
+
+	return ladderScenario{
+		cfg: ladderingAttackCfg{
+			firstNodeTraffic: firstNodeTraffic,
+			trafficFlows:     flows,
+		},
+		attackerPayment: attackerPayment,
+		cltvTotal:       cltvTotal,
+	}
+}
+
+// TestCompareCorpora tests reporting of the effective attacks that are added
+// and removed between two corpora.
+func TestCompareCorpora(t *testing.T) {
+	var (
+		// Effective with a flat reputation window when the last hop is
+		// weighted at 50%.
+		closed = newScenario(
+			10_000_000_000, []uint8{100, 50, 25, 50},
+			10_000_000_000, 1000,
+		)
+		introduced = newScenario(
+			10_000_000_000, []uint8{100, 25, 25, 100},
+			10_000_000_000, 300,
+		)
+
+		// Only effective when reputation decays with a four week
+		// half-life.
+		decaying = newScenario(
+			10_000_000_000, []uint8{100, 10, 10, 25},
+			10_000_000_000, 1000,
+		)
+	)
+	closed.cfg.lastHopWeightPercent = 50
+	introduced.cfg.lastHopWeightPercent = 50
+	decaying.cfg.lastHopWeightPercent = 50
+
+	before := []ladderScenario{closed, decaying}
+	after := []ladderScenario{decaying, introduced, introduced}
+
+	comparison := compareCorpora(before, after, Params{})
+	require.Equal(t, []ladderScenario{introduced}, comparison.added)
+	require.Equal(t, []ladderScenario{closed}, comparison.removed)
+
+	// With decaying reputation, only the scenario that is in both
+	// corpora is effective, so there is no difference between them.
+	decay := Params{DecayHalfLifeWeeks: 4}
+	comparison = compareCorpora(before, after, decay)
+	require.Empty(t, comparison.added)
+	require.Empty(t, comparison.removed)
+
+	// The params should not have mutated the corpora.
+	require.Zero(t, before[1].cfg.params)
+
+	// Scenarios with the same traffic portions but a different last hop
+	// weighting are distinct attacks, even though they are described by
+	// the same string.
+	reweighted := closed
+	reweighted.cfg.lastHopWeightPercent = 40
+	require.Equal(t, closed.String(), reweighted.String())
+
+	comparison = compareCorpora(
+		[]ladderScenario{closed}, []ladderScenario{reweighted},
+		Params{},
+	)
+	require.Equal(t, []ladderScenario{reweighted}, comparison.added)
+	require.Equal(t, []ladderScenario{closed}, comparison.removed)
+}
+
+// TestLadderSeedCorpus tests that a ladder scenario's seed corpus entry
+// reproduces the same attack when it is fed back through the fuzz test's
+// inputs.
+func TestLadderSeedCorpus(t *testing.T) {
+	// A ladder with a target that's interesting enough to run.
+	scenario := newScenario(
+		1_000_000_000, []uint8{100, 50, 100, 100}, 1_000_000_000, 300,
+	)
+
+	seed := scenario.SeedCorpus()
+	require.Equal(t, "f.Add(uint64(1000000000), uint64(1000000000), "+
+		"uint64(300), uint8(4), []byte{0x10, 0x27, 0x88, 0x13, 0x10, "+
+		"0x27, 0x10, 0x27})", seed)
+
+	args := parseSeedCorpus(t, seed)
+	require.Len(t, args, 5)
+
+	reproduced, err := decodeLadderInputs(
+		args[0].(uint64), args[1].(uint64), args[2].(uint64),
+		args[3].(uint8), args[4].([]byte),
+	)
+	require.NoError(t, err)
+	require.Equal(t, scenario, reproduced)
+
+	// Running the reproduced scenario gives the same result.
+	expected, err := runScenario(
+		scenario.cfg, scenario.attackerPayment, scenario.cltvTotal,
+	)
+	require.NoError(t, err)
+
+	result, err := runScenario(
+		reproduced.cfg, reproduced.attackerPayment,
+		reproduced.cltvTotal,
+	)
+	require.NoError(t, err)
+	require.Equal(t, expected, result)
+}
@@ -0,0 +1,57 @@
+package reputationfuzz
+
+// Snapshot describes the state of the target node at a single step in an
+// attack.
+type Snapshot struct {
+	// Step is the index of the step in the attack, where zero is the
+	// state of the target before the attack begins.
+	Step int
+
+	// Reputation is the target's reputation with its outgoing peer.
+	Reputation uint64
+
+	// Revenue is the revenue that the target's reputation represents
+	// over the revenue period.
+	Revenue uint64
+}
+
+// attackedReputation returns the reputation that the target is left with once
+// the attack has taken full effect.
+func (a AttackOutcome) attackedReputation() uint64 {
+	if a.reputationChange >= a.targetReputation {
+		return 0
+	}
+
+	return a.targetReputation - a.reputationChange
+}
+
+// Simulate produces the trajectory of the target's reputation and revenue as
+// the attack unfolds over the number of steps provided, assuming that the
+// attacker's jamming bites at a constant rate. The first snapshot holds the
+// target's peace time values, and the last holds its values once the attack
+// has taken full effect, so steps+1 snapshots are returned.
+func Simulate(outcome AttackOutcome, steps int, params Params) []Snapshot {
+	if steps < 1 {
+		return nil
+	}
+
+	var (
+		trajectory = make([]Snapshot, 0, steps+1)
+		loss       = outcome.targetReputation - outcome.attackedReputation()
+	)
+
+	for step := 0; step <= steps; step++ {
+		reputation := outcome.targetReputation -
+			mulDiv(loss, uint64(step), uint64(steps))
+
+		revenue := revenueFromReputation(reputation, params)
+
+		trajectory = append(trajectory, Snapshot{
+			Step:       step,
+			Reputation: reputation,
+			Revenue:    revenue,
+		})
+	}
+
+	return trajectory
+}
@@ -0,0 +1,66 @@
+package reputationfuzz
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSimulate tests that the simulated trajectory of an attack starts at
+// peace time values and ends at the attacked values.
+func TestSimulate(t *testing.T) {
+	outcome := AttackOutcome{
+		targetReputation: 4_800_000,
+		targetThreshold:  800_000,
+		reputationChange: 1_200_000,
+	}
+
+	trajectory := Simulate(outcome, 4, Params{})
+	require.Len(t, trajectory, 5)
+
+	require.Equal(t, Snapshot{
+		Step:       0,
+		Reputation: 4_800_000,
+		Revenue:    400_000,
+	}, trajectory[0])
+
+	require.Equal(t, Snapshot{
+		Step:       4,
+		Reputation: 3_600_000,
+		Revenue:    300_000,
+	}, trajectory[4])
+
+	// Reputation decreases at a constant rate as the jam bites.
+	for i := 1; i < len(trajectory); i++ {
+		require.EqualValues(t, 300_000, trajectory[i-1].Reputation-
+			trajectory[i].Reputation)
+	}
+
+	// A reputation change larger than the target's reputation bottoms
+	// out at zero.
+	outcome.reputationChange = 10_000_000
+	trajectory = Simulate(outcome, 2, Params{})
+	require.Zero(t, trajectory[2].Reputation)
+	require.Zero(t, trajectory[2].Revenue)
+
+	require.Nil(t, Simulate(outcome, 0, Params{}))
+
+	// A shorter revenue period earns proportionally less revenue from the
+	// same reputation.
+	outcome.reputationChange = 1_200_000
+	trajectory = Simulate(outcome, 1, Params{RevenuePeriodWeeks: 1})
+	require.EqualValues(t, 200_000, trajectory[0].Revenue)
+	require.EqualValues(t, 150_000, trajectory[1].Revenue)
+
+	// Losing a reputation too large to scale by the step doesn't wrap.
+	outcome = AttackOutcome{
+		targetReputation: math.MaxUint64,
+		reputationChange: math.MaxUint64,
+	}
+	trajectory = Simulate(outcome, 4, Params{})
+	require.EqualValues(
+		t, uint64(math.MaxUint64/2+1), trajectory[2].Reputation,
+	)
+	require.Zero(t, trajectory[4].Reputation)
+}
@@ -0,0 +1,167 @@
+// Package stats records a row of statistics for every scenario that a fuzz
+// run analyzes, so that the rate of successful attacks and the distribution
+// of their losses can be tracked across runs.
+package stats
+
+import (
+	"encoding/csv"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// header holds the names of the columns that are written for each row.
+var header = []string{
+	"attack", "network_length", "cutoff", "attacker_payment",
+	"cltv_total", "success", "loss_percent", "margin",
+}
+
+// Row describes a single scenario that was analyzed by a fuzz test.
+type Row struct {
+	// Attack is the name of the attack that was analyzed.
+	Attack string
+
+	// NetworkLength is the number of nodes or peers in the network that
+	// the attack was performed on.
+	NetworkLength int
+
+	// Cutoff is the index of the best peer that is cut off by the attack,
+	// zero if not applicable.
+	Cutoff int
+
+	// AttackerPayment is the amount that the attacker paid to build
+	// reputation, zero if not applicable.
+	AttackerPayment uint64
+
+	// CltvTotal is the total cltv of the attacker's HTLCs, zero if not
+	// applicable.
+	CltvTotal uint64
+
+	// Success indicates whether the attack was successful.
+	Success bool
+
+	// LossPercent is the percentage of reputation or revenue that the
+	// attack cost the target.
+	LossPercent uint64
+
+	// Margin is the amount by which the attack succeeded, or failed if
+	// negative.
+	Margin int64
+}
+
+// record returns the row's fields in the order of the header.
+func (r Row) record() []string {
+	return []string{
+		r.Attack,
+		strconv.Itoa(r.NetworkLength),
+		strconv.Itoa(r.Cutoff),
+		strconv.FormatUint(r.AttackerPayment, 10),
+		strconv.FormatUint(r.CltvTotal, 10),
+		strconv.FormatBool(r.Success),
+		strconv.FormatUint(r.LossPercent, 10),
+		strconv.FormatInt(r.Margin, 10),
+	}
+}
+
+// Writer appends rows to a CSV file. It is safe for concurrent use, and
+// several processes may append to the same file.
+type Writer struct {
+	mu   sync.Mutex
+	file *os.File
+	csv  *csv.Writer
+}
+
+// Open opens the CSV file at the path provided for appending, creating it
+// with a header row if it doesn't exist yet.
+func Open(path string) (*Writer, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := create(path); err != nil {
+			return nil, err
+		}
+
+		file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Writer{
+		file: file,
+		csv:  csv.NewWriter(file),
+	}, nil
+}
+
+// create creates the CSV file at the path provided with its header row. The
+// header is written to a temporary file that is then linked into place, so
+// that the file never exists without its header and processes that append to
+// it concurrently can't write a row before it. If another process creates the
+// file first, its header is kept.
+func create(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".stats-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := csv.NewWriter(tmp)
+	if err := writer.Write(header); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	err = os.Link(tmp.Name(), path)
+	if errors.Is(err, os.ErrExist) {
+		return nil
+	}
+
+	return err
+}
+
+// Write appends a row to the file.
+func (w *Writer) Write(row Row) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.write(row.record())
+}
+
+// write appends a record to the file, flushing it immediately so that each
+// record reaches the file in a single append and records written by other
+// processes aren't interleaved with it. The caller must hold the mutex if the
+// writer is shared.
+func (w *Writer) write(record []string) error {
+	if err := w.csv.Write(record); err != nil {
+		return err
+	}
+
+	w.csv.Flush()
+
+	return w.csv.Error()
+}
+
+// Close flushes any buffered rows and closes the file.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		w.file.Close()
+		return err
+	}
+
+	return w.file.Close()
+}
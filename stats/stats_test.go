@@ -0,0 +1,92 @@
+package stats
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestWriter tests appending rows to a file from several goroutines and
+// writers at once.
+func TestWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.csv")
+
+	const (
+		writers = 4
+		rows    = 100
+	)
+
+	var (
+		wg   sync.WaitGroup
+		errs = make(chan error, writers*(rows+1))
+	)
+
+	// Writers open the file concurrently, so that only one of them
+	// creates it.
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			writer, err := Open(path)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer func() {
+				errs <- writer.Close()
+			}()
+
+			var rowsWg sync.WaitGroup
+			for j := 0; j < rows; j++ {
+				rowsWg.Add(1)
+				go func(j int) {
+					defer rowsWg.Done()
+
+					errs <- writer.Write(Row{
+						Attack:        "ladder",
+						NetworkLength: j,
+						Success:       j%2 == 0,
+						LossPercent:   uint64(j),
+						Margin:        -int64(j),
+					})
+				}(j)
+			}
+			rowsWg.Wait()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	// Every row is intact, and the header is only written once.
+	records, err := csv.NewReader(file).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, writers*rows+1)
+	require.Equal(t, header, records[0])
+
+	for _, record := range records[1:] {
+		require.Equal(t, "ladder", record[0])
+		require.Equal(t, "0", record[2])
+	}
+
+	require.Contains(t, records, []string{
+		"ladder", "3", "0", "0", "0", "false", "3", "-3",
+	})
+
+	// The temporary files that the header was written to are removed.
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
@@ -0,0 +1,77 @@
+package reputationfuzz
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errTooManyPeers is returned when there isn't enough reputation to give
+// every peer a non-zero share.
+var errTooManyPeers = errors.New("too many peers for reputation")
+
+// GenerateWorstCasePeers produces the distribution of peer reputation for a
+// node with the total (two week) revenue and peer count provided that is most
+// favorable to a surge attacker that cuts off all of the node's peers.
+//
+// When all peers are cut off, the attacker pays the difference between the
+// best peer's reputation and the node's revenue, so the attacker wants the
+// best peer's reputation to be as low as possible while still having good
+// reputation (ie, being able to get a minimum HTLC endorsed). This is achieved
+// by concentrating reputation evenly in as many peers as possible that can
+// each still meet this requirement, with all other peers contributing a
+// negligible amount. If there isn't enough reputation to give every peer a
+// non-zero share, errTooManyPeers is returned.
+func GenerateWorstCasePeers(totalRevenue uint64, count int,
+	params Params) ([]uint64, error) {
+
+	if count == 0 {
+		return nil, nil
+	}
+
+	var (
+		totalReputation = mulDiv(
+			totalRevenue, params.reputationPeriod(),
+			params.revenuePeriod(),
+		)
+
+		htlcEndorsed = budgetForEndorsedValue(
+			params.minimumHTLC(), 100, params,
+		)
+
+		// Each concentrated peer must have at least the node's revenue
+		// plus enough to get a minimum HTLC endorsed.
+		concentrated = int(totalReputation /
+			saturatingAdd(totalRevenue, htlcEndorsed))
+	)
+
+	if concentrated > count {
+		concentrated = count
+	}
+
+	// If no peer can meet the requirement, we just concentrate all of the
+	// reputation in a single peer.
+	if concentrated == 0 {
+		concentrated = 1
+	}
+
+	// All remaining peers are given the smallest non-zero reputation, and
+	// the remainder is split evenly between our concentrated peers.
+	negligible := uint64(count - concentrated)
+	if negligible >= totalReputation {
+		return nil, fmt.Errorf("%w: %v peers, total reputation: %v",
+			errTooManyPeers, count, totalReputation)
+	}
+
+	share := (totalReputation - negligible) / uint64(concentrated)
+
+	peers := make([]uint64, count)
+	for i := range peers {
+		if i < concentrated {
+			peers[i] = share
+		} else {
+			peers[i] = 1
+		}
+	}
+
+	return peers, nil
+}
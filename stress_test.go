@@ -0,0 +1,97 @@
+package reputationfuzz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestGenerateWorstCasePeers tests that the worst case distribution of peers
+// is vulnerable to a surge attack, while more uniform distributions with the
+// same total revenue are not.
+func TestGenerateWorstCasePeers(t *testing.T) {
+	var (
+		totalRevenue uint64 = 10_000_000_000
+		count               = 20
+	)
+
+	peers, err := GenerateWorstCasePeers(totalRevenue, count, Params{})
+	require.NoError(t, err)
+	require.Len(t, peers, count)
+
+	outcome, err := surgeAttack(peers, count-1, surgeAttackCfg{})
+	require.NoError(t, err)
+
+	success, err := outcome.Success()
+	require.NoError(t, err)
+	require.True(t, success)
+
+	// Count the number of peers that reputation was concentrated in, and
+	// spread the same reputation over one more peer.
+	var concentrated int
+	for _, peer := range peers {
+		if peer > 1 {
+			concentrated++
+		}
+	}
+	require.Less(t, concentrated, count)
+
+	spread := make([]uint64, count)
+	for i := range spread {
+		switch {
+		case i <= concentrated:
+			spread[i] = peers[0] * uint64(concentrated) /
+				uint64(concentrated+1)
+
+		default:
+			spread[i] = 1
+		}
+	}
+
+	// A uniform distribution of the same reputation over all peers.
+	uniform := make([]uint64, count)
+	for i := range uniform {
+		uniform[i] = totalRevenue * reputationPeriodWeeks /
+			revenuePeriodWeeks / uint64(count)
+	}
+
+	for _, distribution := range [][]uint64{spread, uniform} {
+		for cutoff := 0; cutoff < count; cutoff++ {
+			outcome, err := surgeAttack(
+				distribution, cutoff, surgeAttackCfg{},
+			)
+			require.NoError(t, err)
+
+			success, err := outcome.Success()
+			require.NoError(t, err)
+			require.False(t, success, "cutoff: %v", cutoff)
+		}
+	}
+}
+
+// TestGenerateWorstCasePeersParams tests generating the worst case
+// distribution with non-default parameters, and rejecting peer counts that
+// can't each be given some reputation.
+func TestGenerateWorstCasePeersParams(t *testing.T) {
+	// With a reputation period that is four times the revenue period,
+	// three peers can each hold the node's revenue plus a minimum HTLC's
+	// worth of reputation.
+	params := Params{
+		RevenuePeriodWeeks:    2,
+		ReputationPeriodWeeks: 8,
+	}
+
+	peers, err := GenerateWorstCasePeers(10_000_000_000, 5, params)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{
+		13_333_333_332, 13_333_333_332, 13_333_333_332, 1, 1,
+	}, peers)
+
+	// A tiny revenue can't give a non-zero share to every peer.
+	_, err = GenerateWorstCasePeers(1, 100, Params{})
+	require.ErrorIs(t, err, errTooManyPeers)
+
+	peers, err = GenerateWorstCasePeers(0, 0, Params{})
+	require.NoError(t, err)
+	require.Nil(t, peers)
+}
@@ -2,47 +2,114 @@ package reputationfuzz
 
 import (
 	"fmt"
+	"math"
 	"sort"
 )
 
-// minimumHTLCReputation is the minimum size of HTLC that we require a peer to
-// be able to get endorsed for it to have sufficient reputation for us to care
-// about the results that we get from fuzzing, expressed in msat. This
-// represents around $1 at the time of writing.
-const minimumHTLCReputation = 17_00_000
+// minimumHTLCReputation is the default minimum size of HTLC that we require a
+// peer to be able to get endorsed for it to have sufficient reputation for us
+// to care about the results that we get from fuzzing, expressed in msat. This
+// represents $1 at our default fiat rate.
+const minimumHTLCReputation = 1 * msatPerDollar
 
-type surgeAttackOutcome struct {
+// SurgeAttackOutcome describes the impact of a surge attack on the targeted
+// node's revenue.
+type SurgeAttackOutcome struct {
 	cutoffReputation uint64
 	peaceRevenue     uint64
 	attackRevenue    uint64
+
+	// attackerSettledRevenue is the fee revenue that the node earns from
+	// the attacker's jamming HTLCs during the attack, which is zero if the
+	// HTLCs are held and then failed back rather than settled.
+	attackerSettledRevenue uint64
+
+	// weeklyRevenue is the node's normal revenue per week, which is used
+	// to model the revenue that it loses while it recovers from the
+	// attack. Recovery isn't modeled if zero.
+	weeklyRevenue uint64
+
+	// jamLiquidity is the liquidity that the attacker locks up in its
+	// general jamming HTLCs for the two week attack.
+	jamLiquidity uint64
+
+	// params are the reputation parameters that the attack was modeled
+	// with, which set the size and reputation cost of the HTLC that cut
+	// off peers must have been able to get endorsed, and the reputation
+	// period that bounds the revenue that the node loses if it never
+	// recovers.
+	params Params
+
+	// thresholdPercent is the percentage of the node's revenue that
+	// peers' reputation must clear to have good reputation, zero for 100%.
+	thresholdPercent uint16
+
+	// bestReputation is the reputation of the node's best honest peer,
+	// which is used to detect thresholds that no honest peer can clear.
+	bestReputation uint64
+}
+
+func (s *SurgeAttackOutcome) String() string {
+	paid := s.attackerPays()
+	loss := s.LossBasisPoints()
+
+	return fmt.Sprintf("Node lost: %v.%02d %% of revenue  - attacker paid: %v to meet threshold: %v, "+
+		"node still earned: %v (%v honest + %v attacker + %v settled "+
+		"jams), lost %v recovering, margin: %v, total capital: %v",
+		loss/100, loss%100, paid, s.threshold(), s.earnedUnderAttack(),
+		s.attackRevenue, paid, s.attackerSettledRevenue,
+		s.recoveryLoss(), s.Margin(), s.TotalCapital())
+}
+
+// Margin returns the signed margin by which the attack is successful, which
+// is positive if the attack succeeds and zero or negative if it fails. If the
+// cut off peers had good reputation, this is the amount of revenue that the
+// node loses. Otherwise, it is the amount of reputation that the cut off
+// peers were short of having good reputation, as a negative value.
+func (s *SurgeAttackOutcome) Margin() int64 {
+	htlcEndorsed := s.requiredBudget()
+	if !s.hadGoodReputation() {
+		return signedDiff(
+			s.cutoffReputation,
+			saturatingAdd(s.threshold(), htlcEndorsed),
+		)
+	}
+
+	return signedDiff(s.revenueAtStake(), s.earnedUnderAttack())
+}
+
+// Success returns a boolean indicating whether the attack was successful,
+// requiring that cut off peers could get a minimum sized HTLC endorsed.
+func (s *SurgeAttackOutcome) Success() (bool, error) {
+	return s.successWithMinimum(s.requiredHTLC())
 }
 
-func (s *surgeAttackOutcome) String() string {
-	paid := s.cutoffReputation - s.peaceRevenue
-	loss := (s.peaceRevenue - (paid + s.attackRevenue)) * 100 / s.peaceRevenue
+// requiredHTLC returns the size of HTLC that cut off peers must have been able
+// to get endorsed to have had good reputation.
+func (s *SurgeAttackOutcome) requiredHTLC() uint64 {
+	return s.params.minimumHTLC()
+}
 
-	return fmt.Sprintf("Node lost: %v %% of revenue  - attacker paid: %v to meet threshold: %v, "+
-		"node still earned: %v (%v honest + %v attacker)", loss,
-		paid, s.peaceRevenue, s.attackRevenue+paid, s.attackRevenue, paid)
+// requiredBudget returns the reputation that cut off peers must have had above
+// the threshold to get a HTLC of the required size endorsed.
+func (s *SurgeAttackOutcome) requiredBudget() uint64 {
+	return budgetForEndorsedValue(s.requiredHTLC(), 100, s.params)
 }
 
-func (s *surgeAttackOutcome) success() (bool, error) {
+// successWithMinimum returns a boolean indicating whether the attack was
+// successful, requiring that cut off peers could get a HTLC of at least the
+// minimum size provided endorsed for them to be considered to have had good
+// reputation.
+func (s *SurgeAttackOutcome) successWithMinimum(minimumHTLC uint64) (bool,
+	error) {
+
 	// If the reputation that we're cutting off is less than the peace
 	// time revenue, the peers never had good reputation to start with
 	// so there's no point in attacking.
-	//
-	// Height is hardcoded to a low value here because it isn't really
-	// all that relevant to the attack.
-	htlcEndorsed := htlcReputationCost(minimumHTLCReputation, 100)
-
-	if s.cutoffReputation < s.peaceRevenue+htlcEndorsed {
+	if !s.hadGoodReputationWithMinimum(minimumHTLC) {
 		return false, nil
 	}
 
-	// The attacker only needs to pay the difference between the best peer
-	// it's trying to cut off and the reputation threshold.
-	attackerPays := s.cutoffReputation - s.peaceRevenue
-
 	// Since we're always cutting traffic off, we should never have revenue
 	// under attack that's more than during peace.
 	if s.attackRevenue > s.peaceRevenue {
@@ -51,12 +118,628 @@ func (s *surgeAttackOutcome) success() (bool, error) {
 	}
 
 	// The attack is only successful if the node earns less than in times
-	/// of peace.
-	return attackerPays+s.attackRevenue < s.peaceRevenue, nil
+	/// of peace. If the attacker's jamming HTLCs settle, the fees that
+	// they pay count towards the node's earnings and may keep it above
+	// its peace time revenue, so the attack backfires. Any revenue that
+	// the node loses while recovering from the attack counts against it.
+	return s.earnedUnderAttack() < s.revenueAtStake(), nil
+}
+
+// revenueAtStake returns the revenue that the node would have earned during
+// the attack in times of peace, plus the revenue that it loses while it
+// recovers from the attack.
+func (s *SurgeAttackOutcome) revenueAtStake() uint64 {
+	return saturatingAdd(s.peaceRevenue, s.recoveryLoss())
+}
+
+// recoveryWeeks returns the number of weeks of normal traffic that the node
+// needs to climb back above the threshold that the attacker inflated to the
+// cut off reputation. During recovery the node only earns revenue from the
+// peers that weren't cut off, so errNoRecovery is returned if it doesn't have
+// any surviving traffic that can re-clear the threshold. Zero is returned if
+// recovery isn't modeled.
+func (s *SurgeAttackOutcome) recoveryWeeks() (uint64, error) {
+	deficit := s.attackerPays()
+	if s.weeklyRevenue == 0 || deficit == 0 {
+		return 0, nil
+	}
+
+	var surviving uint64
+	if s.peaceRevenue != 0 {
+		surviving = mulDiv(
+			s.weeklyRevenue, s.attackRevenue, s.peaceRevenue,
+		)
+	}
+
+	if surviving == 0 {
+		return 0, fmt.Errorf("%w: no surviving revenue per week",
+			errNoRecovery)
+	}
+
+	// Round up, because the node hasn't recovered until it has cleared
+	// the threshold.
+	weeks := deficit / surviving
+	if deficit%surviving != 0 {
+		weeks++
+	}
+
+	return weeks, nil
+}
+
+// recoveryLoss returns the revenue that the node loses from the peers that
+// were cut off while it recovers from the attack. If the node can never
+// recover, the loss is capped at a reputation period of lost revenue, which
+// is as long as the model tracks the effects of the attack.
+func (s *SurgeAttackOutcome) recoveryLoss() uint64 {
+	if s.weeklyRevenue == 0 || s.attackRevenue >= s.peaceRevenue {
+		return 0
+	}
+
+	weeklyLoss := mulDiv(
+		s.weeklyRevenue, s.peaceRevenue-s.attackRevenue,
+		s.peaceRevenue,
+	)
+
+	weeks, err := s.recoveryWeeks()
+	if err != nil {
+		weeks = s.params.reputationPeriod()
+	}
+
+	return saturatingMul(weeks, weeklyLoss)
+}
+
+// revenueLost returns the revenue that the node loses to the attack, including
+// the revenue lost while it recovers, or zero if it earns at least as much as
+// it would have in peace time.
+func (s *SurgeAttackOutcome) revenueLost() uint64 {
+	earned := s.earnedUnderAttack()
+	if atStake := s.revenueAtStake(); atStake > earned {
+		return atStake - earned
+	}
+
+	return 0
+}
+
+// earnedUnderAttack returns the total revenue that the node earns during the
+// attack: the attacker's payment to meet the threshold, the revenue from peers
+// that aren't cut off and the fees from any of the attacker's jamming HTLCs
+// that settle.
+func (s *SurgeAttackOutcome) earnedUnderAttack() uint64 {
+	return saturatingAdd(
+		saturatingAdd(s.attackerPays(), s.attackRevenue),
+		s.attackerSettledRevenue,
+	)
+}
+
+// hadGoodReputation returns a boolean indicating whether the peers that are
+// cut off by the attack had good reputation to begin with, which requires that
+// they could get at least a minimum sized HTLC endorsed.
+func (s *SurgeAttackOutcome) hadGoodReputation() bool {
+	return s.hadGoodReputationWithMinimum(s.requiredHTLC())
+}
+
+// hadGoodReputationWithMinimum returns a boolean indicating whether the peers
+// that are cut off could get a HTLC of the minimum size provided endorsed.
+func (s *SurgeAttackOutcome) hadGoodReputationWithMinimum(
+	minimumHTLC uint64) bool {
+
+	// Height is hardcoded to a low value here because it isn't really
+	// all that relevant to the attack.
+	htlcEndorsed := budgetForEndorsedValue(minimumHTLC, 100, s.params)
+
+	return s.cutoffReputation >= saturatingAdd(s.threshold(), htlcEndorsed)
+}
+
+// minimumHTLCFlipPoint returns the smallest minimum HTLC size at which the
+// attack is no longer considered successful, because the cut off peers can't
+// get a HTLC of that size endorsed. A false boolean is returned if the attack
+// is not successful even when there is no minimum HTLC requirement.
+func (s *SurgeAttackOutcome) minimumHTLCFlipPoint() (uint64, bool, error) {
+	success, err := s.successWithMinimum(0)
+	if err != nil || !success {
+		return 0, false, err
+	}
+
+	// Success is monotonically decreasing in the minimum HTLC size, and
+	// the cut off peers can't possibly get a HTLC larger than their total
+	// reputation would pay for endorsed, so we binary search between the
+	// two.
+	low, high := uint64(0), saturatingAdd(
+		endorsedValueForBudget(s.cutoffReputation, 100, s.params), 1,
+	)
+	for low+1 < high {
+		mid := low + (high-low)/2
+
+		success, err := s.successWithMinimum(mid)
+		if err != nil {
+			return 0, false, err
+		}
+
+		if success {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+
+	return high, true, nil
+}
+
+// lossPercent returns the percentage of its peace time revenue that the node
+// loses under attack, accounting for the attacker's payment. Zero is returned
+// if the node doesn't lose any revenue.
+func (s *SurgeAttackOutcome) lossPercent() uint64 {
+	return s.LossBasisPoints() / 100
+}
+
+// LossBasisPoints returns the portion of its peace time revenue that the node
+// loses under attack in basis points, accounting for the attacker's payment.
+// If the attacker's payment and the revenue that the node still earns exceed
+// its peace time revenue, the attack made the node money and zero is returned.
+func (s *SurgeAttackOutcome) LossBasisPoints() uint64 {
+	earned := s.earnedUnderAttack()
+	if earned >= s.peaceRevenue {
+		return 0
+	}
+
+	return mulDiv(s.peaceRevenue-earned, basisPoints, s.peaceRevenue)
+}
+
+// attackerPays returns the amount that the attacker needs to pay to cut off
+// peers. The attacker only needs to pay the difference between the best peer
+// it's trying to cut off and the reputation threshold. If the node multiplies
+// its revenue to set the threshold, each msat that the attacker pays raises
+// the threshold by the multiplier.
+func (s *SurgeAttackOutcome) attackerPays() uint64 {
+	threshold := s.threshold()
+	if s.cutoffReputation < threshold {
+		return 0
+	}
+
+	return mulDiv(
+		s.cutoffReputation-threshold, 100, s.thresholdMultiplier(),
+	)
+}
+
+// thresholdMultiplier returns the percentage of the node's revenue that peers
+// must clear to have good reputation.
+func (s *SurgeAttackOutcome) thresholdMultiplier() uint64 {
+	if s.thresholdPercent == 0 {
+		return 100
+	}
+
+	return uint64(s.thresholdPercent)
+}
+
+// threshold returns the reputation that peers must clear to have good
+// reputation when the node isn't under attack.
+func (s *SurgeAttackOutcome) threshold() uint64 {
+	return mulDiv(s.peaceRevenue, s.thresholdMultiplier(), 100)
+}
+
+// selfDenial returns a boolean indicating whether the node's threshold is so
+// high that none of its honest peers can get a minimum sized HTLC endorsed,
+// even when it isn't under attack. A defender that raises its threshold this
+// far denies service to its own peers.
+func (s *SurgeAttackOutcome) selfDenial() bool {
+	htlcEndorsed := s.requiredBudget()
+
+	return s.bestReputation < saturatingAdd(s.threshold(), htlcEndorsed)
+}
+
+// thresholdMultiplierFlipPoint returns the smallest threshold multiplier, as a
+// percentage of the node's revenue, at which the attack is no longer
+// successful. Raising the threshold makes the attacker's payment go further,
+// but once the cut off peers can't clear it they never had good reputation to
+// deny. A false boolean is returned if the attack isn't successful at the
+// outcome's multiplier, or remains successful at the largest multiplier.
+func (s *SurgeAttackOutcome) thresholdMultiplierFlipPoint() (uint16, bool,
+	error) {
+
+	withMultiplier := func(percent uint64) (bool, error) {
+		outcome := *s
+		outcome.thresholdPercent = uint16(percent)
+
+		return outcome.Success()
+	}
+
+	low := s.thresholdMultiplier()
+	success, err := withMultiplier(low)
+	if err != nil || !success {
+		return 0, false, err
+	}
+
+	high := uint64(math.MaxUint16)
+	success, err = withMultiplier(high)
+	if err != nil || success {
+		return 0, false, err
+	}
+
+	for low+1 < high {
+		mid := low + (high-low)/2
+
+		success, err := withMultiplier(mid)
+		if err != nil {
+			return 0, false, err
+		}
+
+		if success {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+
+	return uint16(high), true, nil
+}
+
+// TotalCapital returns the total capital that the attacker needs to marshal
+// for the attack, which is the amount that it pays to cut off peers plus the
+// liquidity that it locks up in its general jamming HTLCs.
+func (s *SurgeAttackOutcome) TotalCapital() uint64 {
+	return saturatingAdd(s.attackerPays(), s.jamLiquidity)
 }
 
-func revenueFromReputation(reputation uint64) uint64 {
-	return reputation * revenuePeriodWeeks / reputationPeriodWeeks
+// Closeness returns a continuous measure of how close the outcome is to being
+// a successful attack, where 1.0 is exactly at the boundary of success and
+// values above 1.0 are successful attacks.
+func (s *SurgeAttackOutcome) Closeness() float64 {
+	htlcEndorsed := s.requiredBudget()
+
+	// The cut off peers must have had good reputation to begin with, and
+	// the attacker's payment plus the revenue that the node still earns
+	// must be less than its peace time revenue. Both need to hold, so we're
+	// only as close as the furthest of the two.
+	goodReputation := ratio(
+		s.cutoffReputation, saturatingAdd(s.threshold(), htlcEndorsed),
+	)
+	revenueLoss := ratio(s.revenueAtStake(), s.earnedUnderAttack())
+
+	return math.Min(goodReputation, revenueLoss)
+}
+
+// surgeAttackCfg holds optional parameters that adjust how a surge attack is
+// modeled. The zero value models the attack without any protective
+// mechanisms in place.
+type surgeAttackCfg struct {
+	// reputationFloor is a level of reputation that the attack can't
+	// reduce a peer below: peers with at least this much reputation are
+	// always considered to have good reputation, so they can't be cut off
+	// by the attacker. A zero value disables the floor.
+	reputationFloor uint64
+
+	// peerGroups optionally groups the entries in honestPeers by the peer
+	// that they belong to, with each entry holding an identifier for the
+	// peer. In some designs reputation and slots are allocated per peer
+	// pair rather than per channel, so channels with the same peer are
+	// aggregated and cut off together. If empty, each entry is treated as
+	// a distinct peer.
+	peerGroups []int
+
+	// bandLowIndex restricts the attacker to cutting off a contiguous band
+	// of peers by value, from this index up to the cutoff index in the
+	// sorted set of peers. This models slot contention that only allows
+	// the attacker to deny peers within a band of value. A zero value cuts
+	// off all peers up to the cutoff index.
+	bandLowIndex int
+
+	// allowlist optionally marks entries in honestPeers as known-good
+	// peers that the defender always trusts. Allowlisted peers can't be
+	// cut off, so their revenue always counts towards the target's
+	// threshold and continues to be earned during the attack. If peers
+	// are grouped, a group is allowlisted if any of its channels are. If
+	// empty, no peers are allowlisted.
+	allowlist []bool
+
+	// insider optionally marks the entry in honestPeers that is controlled
+	// by the attacker, at most one of which may be set. The node earns
+	// the insider's fees in times of peace but loses them during the
+	// attack, and the attacker doesn't need to pay to cut off its own
+	// reputation. If peers are grouped, a group is the insider if any of
+	// its channels are. If empty, the attacker is purely external.
+	insider []bool
+
+	// peerAges optionally holds the age of each entry in honestPeers in
+	// weeks. If peers are grouped, a group's age is the age of its oldest
+	// channel. If empty, all peers are treated as established.
+	peerAges []uint64
+
+	// graceWeeks is the number of weeks after a peer's channel is opened
+	// during which it has provisional reputation, so it is treated as
+	// having good reputation and can't be cut off by the attacker. A zero
+	// value disables the grace period.
+	graceWeeks uint64
+
+	// jamSettledRevenue is the fee revenue that the attacker's jamming
+	// HTLCs pay the node if they settle rather than being held and failed
+	// back. A zero value models jams that are always failed back, which
+	// earn the node nothing but lock up its liquidity.
+	jamSettledRevenue uint64
+
+	// weeklyRevenue is the node's normal revenue per week. If set, the
+	// revenue that the node loses while it rebuilds its peers' reputation
+	// after the attack counts towards the attack's success, so that short
+	// and cheap attacks with long recovery tails are judged damaging. A
+	// zero value doesn't model recovery.
+	weeklyRevenue uint64
+
+	// thresholdPercent is a defender side mitigation that sets the
+	// threshold that peers must clear to have good reputation to this
+	// percentage of the node's revenue. A higher threshold makes it harder
+	// for the attacker's target peers to have good reputation to begin
+	// with, but also for the node's other honest peers. A zero value uses
+	// the node's revenue as its threshold.
+	thresholdPercent uint16
+
+	// jamLiquidity is the liquidity that the attacker locks up in its
+	// general jamming HTLCs for the duration of the attack. It doesn't
+	// change the node's revenue, but counts towards the total capital
+	// that the attacker needs. A zero value models an attacker that
+	// fills every slot on the targeted link with a minimum sized HTLC.
+	jamLiquidity uint64
+
+	// capitalRateBasisPoints is the annualized rate of return that the
+	// attacker forgoes on the liquidity locked in its general jamming
+	// HTLCs, in basis points. It prices the general jam baseline, and a
+	// zero value ignores the opportunity cost of locked capital.
+	capitalRateBasisPoints uint16
+
+	// params holds the parameters of the reputation algorithm, using the
+	// defaults if unset.
+	params Params
+}
+
+// bidirectionalPeer describes the reputation that an honest peer has built
+// with a targeted node by forwarding traffic in each direction.
+type bidirectionalPeer struct {
+	// incoming is the reputation that the peer has earned from traffic
+	// that it forwarded to the targeted node.
+	incoming uint64
+
+	// outgoing is the reputation that the peer has earned from traffic
+	// that the targeted node forwarded to it.
+	outgoing uint64
+}
+
+// reputation returns the peer's combined reputation in both directions.
+func (b bidirectionalPeer) reputation() uint64 {
+	return saturatingAdd(b.incoming, b.outgoing)
+}
+
+// outgoingPeers converts a set of single-direction honest peers to
+// bidirectional peers that only have outgoing reputation.
+func outgoingPeers(honestPeers []uint64) []bidirectionalPeer {
+	peers := make([]bidirectionalPeer, len(honestPeers))
+	for i, reputation := range honestPeers {
+		peers[i] = bidirectionalPeer{
+			outgoing: reputation,
+		}
+	}
+
+	return peers
+}
+
+// surgePeer is a peer of a node targeted by a surge attack.
+type surgePeer struct {
+	bidirectionalPeer
+
+	// allowlisted indicates that the peer is always trusted by the
+	// targeted node.
+	allowlisted bool
+
+	// ageWeeks is the age of the peer's channel with the targeted node.
+	ageWeeks uint64
+
+	// insider indicates that the peer is controlled by the attacker.
+	insider bool
+}
+
+// revenue returns the revenue that the peer's traffic in both directions
+// represents over the revenue period.
+func (s surgePeer) revenue(params Params) uint64 {
+	return saturatingAdd(
+		revenueFromReputation(s.incoming, params),
+		revenueFromReputation(s.outgoing, params),
+	)
+}
+
+// groupPeers aggregates the reputation of channels that belong to the same
+// peer, returning one entry per distinct peer in the order that they first
+// appear.
+func (c surgeAttackCfg) groupPeers(honestPeers []bidirectionalPeer) (
+	[]surgePeer, error) {
+
+	if len(c.allowlist) != 0 && len(c.allowlist) != len(honestPeers) {
+		return nil, fmt.Errorf("allowlist: %v != peer count: %v",
+			len(c.allowlist), len(honestPeers))
+	}
+
+	if len(c.peerAges) != 0 && len(c.peerAges) != len(honestPeers) {
+		return nil, fmt.Errorf("peer ages: %v != peer count: %v",
+			len(c.peerAges), len(honestPeers))
+	}
+
+	if len(c.insider) != 0 && len(c.insider) != len(honestPeers) {
+		return nil, fmt.Errorf("insider: %v != peer count: %v",
+			len(c.insider), len(honestPeers))
+	}
+
+	var insiders int
+	for _, insider := range c.insider {
+		if insider {
+			insiders++
+		}
+	}
+
+	if insiders > 1 {
+		return nil, fmt.Errorf("%v insiders, at most one peer may be "+
+			"controlled by the attacker", insiders)
+	}
+
+	allowlisted := func(i int) bool {
+		return len(c.allowlist) != 0 && c.allowlist[i]
+	}
+
+	insider := func(i int) bool {
+		return len(c.insider) != 0 && c.insider[i]
+	}
+
+	// Peers are treated as established if we don't have ages for them.
+	age := func(i int) uint64 {
+		if len(c.peerAges) == 0 {
+			return math.MaxUint64
+		}
+
+		return c.peerAges[i]
+	}
+
+	if len(c.peerGroups) == 0 {
+		peers := make([]surgePeer, len(honestPeers))
+		for i, peer := range honestPeers {
+			peers[i] = surgePeer{
+				bidirectionalPeer: peer,
+				allowlisted:       allowlisted(i),
+				ageWeeks:          age(i),
+				insider:           insider(i),
+			}
+		}
+
+		return peers, nil
+	}
+
+	if len(c.peerGroups) != len(honestPeers) {
+		return nil, fmt.Errorf("peer groups: %v != peer count: %v",
+			len(c.peerGroups), len(honestPeers))
+	}
+
+	var (
+		grouped []surgePeer
+		index   = make(map[int]int)
+	)
+
+	for i, peer := range honestPeers {
+		group := c.peerGroups[i]
+
+		idx, ok := index[group]
+		if !ok {
+			idx = len(grouped)
+			index[group] = idx
+			grouped = append(grouped, surgePeer{})
+		}
+
+		grouped[idx].incoming = saturatingAdd(
+			grouped[idx].incoming, peer.incoming,
+		)
+		grouped[idx].outgoing = saturatingAdd(
+			grouped[idx].outgoing, peer.outgoing,
+		)
+		grouped[idx].allowlisted = grouped[idx].allowlisted ||
+			allowlisted(i)
+		grouped[idx].insider = grouped[idx].insider || insider(i)
+
+		if age(i) > grouped[idx].ageWeeks {
+			grouped[idx].ageWeeks = age(i)
+		}
+	}
+
+	return grouped, nil
+}
+
+// withPeer returns a copy of the config for a set of honest peers of the size
+// provided that covers an additional peer appended to the set, with the peer
+// in its own group if the config groups peers. If the config tracks peer
+// ages, the peer is treated as brand new.
+func (c surgeAttackCfg) withPeer(peerCount int,
+	allowlisted bool) surgeAttackCfg {
+
+	if len(c.allowlist) != 0 || allowlisted {
+		allowlist := make([]bool, peerCount, peerCount+1)
+		copy(allowlist, c.allowlist)
+		c.allowlist = append(allowlist, allowlisted)
+	}
+
+	if len(c.insider) != 0 {
+		c.insider = append(
+			append([]bool(nil), c.insider...), false,
+		)
+	}
+
+	if len(c.peerAges) != 0 {
+		c.peerAges = append(
+			append([]uint64(nil), c.peerAges...), 0,
+		)
+	}
+
+	if len(c.peerGroups) != 0 {
+		var group int
+		for _, existing := range c.peerGroups {
+			if existing >= group {
+				group = existing + 1
+			}
+		}
+
+		c.peerGroups = append(
+			append([]int(nil), c.peerGroups...), group,
+		)
+	}
+
+	return c
+}
+
+// protected returns a boolean indicating whether the peer provided is
+// protected from being cut off by the attacker.
+func (c surgeAttackCfg) protected(peer surgePeer) bool {
+	if peer.allowlisted {
+		return true
+	}
+
+	// Peers within their grace period have provisional reputation.
+	if peer.ageWeeks < c.graceWeeks {
+		return true
+	}
+
+	return c.reputationFloor != 0 && peer.reputation() >= c.reputationFloor
+}
+
+// generalJamLiquidity returns the liquidity that the attacker locks up in its
+// general jamming HTLCs. If the config doesn't set it, the attacker occupies
+// every slot on the targeted link with a minimum sized HTLC, which is the
+// least liquidity that it needs to deny peers without good reputation.
+func (c surgeAttackCfg) generalJamLiquidity() uint64 {
+	if c.jamLiquidity != 0 {
+		return c.jamLiquidity
+	}
+
+	return uint64(maxHTLCSlots) * defaultMinHTLCSize
+}
+
+// bestHonestReputation returns the reputation of the best peer in the sorted
+// set of peers provided that isn't controlled by the attacker.
+func bestHonestReputation(peers []surgePeer) uint64 {
+	for i := len(peers) - 1; i >= 0; i-- {
+		if !peers[i].insider {
+			return peers[i].reputation()
+		}
+	}
+
+	return 0
+}
+
+// sortedPeers groups the honest peers provided and sorts them from least to
+// most valuable.
+func (c surgeAttackCfg) sortedPeers(honestPeers []bidirectionalPeer) (
+	[]surgePeer, error) {
+
+	peers, err := c.groupPeers(honestPeers)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].reputation() < peers[j].reputation()
+	})
+
+	return peers, nil
 }
 
 // surgeAttack determines whether a targeted node will lose reputation if
@@ -67,19 +750,46 @@ func revenueFromReputation(reputation uint64) uint64 {
 // Honest peers provides the fee revenue from the nodes peers, and cutoff
 // provides the index at which the attacker will aim to cut off peer
 // reputation (zero value means that the least valuable peer is cut off, because
-// there's no point in an attack that doesn't target any peers).
-func surgeAttack(honestPeers []uint64, cutoffIndex int) (*surgeAttackOutcome,
-	error) {
+// there's no point in an attack that doesn't target any peers). Peers that are
+// protected by the config provided are not cut off, even if they fall beneath
+// the cutoff index. If the config groups channels by peer, the cutoff index
+// refers to the sorted set of grouped peers. If the config marks one of the
+// peers as the attacker, the node loses its revenue during the attack but it
+// is never cut off.
+//
+// Honest peers are assumed to have only built reputation in the outgoing
+// direction, see surgeAttackBidirectional for peers that forward traffic in
+// both directions.
+func surgeAttack(honestPeers []uint64, cutoffIndex int,
+	cfg surgeAttackCfg) (*SurgeAttackOutcome, error) {
+
+	return surgeAttackBidirectional(
+		outgoingPeers(honestPeers), cutoffIndex, cfg,
+	)
+}
 
-	if cutoffIndex > len(honestPeers)-1 {
+// surgeAttackBidirectional models a surge attack against a node with honest
+// peers that have built reputation in both directions. A peer's reputation is
+// the combination of the two directions, so the attacker must overcome the
+// combined reputation to cut it off, and the node earns revenue from each
+// direction.
+func surgeAttackBidirectional(honestPeers []bidirectionalPeer, cutoffIndex int,
+	cfg surgeAttackCfg) (*SurgeAttackOutcome, error) {
+
+	peers, err := cfg.sortedPeers(honestPeers)
+	if err != nil {
+		return nil, err
+	}
+
+	if cutoffIndex > len(peers)-1 {
 		return nil, fmt.Errorf("Cutoff: %v > peer count: %v",
-			cutoffIndex, len(honestPeers))
+			cutoffIndex, len(peers))
 	}
 
-	// Sort from least to most valuable peer.
-	sort.Slice(honestPeers, func(i, j int) bool {
-		return honestPeers[i] < honestPeers[j]
-	})
+	if cfg.bandLowIndex < 0 || cfg.bandLowIndex > cutoffIndex {
+		return nil, fmt.Errorf("Band low index: %v not in [0, %v]",
+			cfg.bandLowIndex, cutoffIndex)
+	}
 
 	// First, we'll calculate the revenue threshold for the targeted link.
 	var (
@@ -88,29 +798,288 @@ func surgeAttack(honestPeers []uint64, cutoffIndex int) (*surgeAttackOutcome,
 		reputationToCutOff uint64
 	)
 
-	for i, reputation := range honestPeers {
+	for i, peer := range peers {
 		// We're assuming constant traffic from the node, add it to our
 		// two week revenue total (representing when we're not under
 		// attack).
-		peerContribution := revenueFromReputation(reputation)
-		twoWeekRevenue += peerContribution
+		peerContribution := peer.revenue(cfg.params)
+		twoWeekRevenue = saturatingAdd(twoWeekRevenue, peerContribution)
+
+		// If the attacker is one of our peers, it stops forwarding
+		// traffic during the attack, and it doesn't need to pay to
+		// cut off its own reputation.
+		if peer.insider {
+			continue
+		}
 
 		// If we're beneath the cutoff, the attacker will need to pay
 		// up to this peer's reputation to cut it off from having good
 		// reputation.
 		//
-		// If we're after the cutoff index, this peer will still be able
-		// to earn us fees in the two week period that we're attacked.
-		if i <= cutoffIndex {
-			reputationToCutOff = reputation
+		// If we're outside of the band being cut off, or the peer is
+		// protected from being cut off, this peer will still be able to
+		// earn us fees in the two week period that we're attacked.
+		inBand := i >= cfg.bandLowIndex && i <= cutoffIndex
+		if inBand && !cfg.protected(peer) {
+			reputationToCutOff = peer.reputation()
 		} else {
-			attackRevenue += peerContribution
+			attackRevenue = saturatingAdd(
+				attackRevenue, peerContribution,
+			)
 		}
 	}
 
-	return &surgeAttackOutcome{
+	// If the node doesn't earn any revenue, there's nothing for the
+	// attack to cost it.
+	if twoWeekRevenue == 0 {
+		return nil, fmt.Errorf("%w: %v peers", errZeroRevenue,
+			len(peers))
+	}
+
+	return &SurgeAttackOutcome{
 		cutoffReputation: reputationToCutOff,
 		peaceRevenue:     twoWeekRevenue,
 		attackRevenue:    attackRevenue,
+
+		attackerSettledRevenue: cfg.jamSettledRevenue,
+		weeklyRevenue:          cfg.weeklyRevenue,
+		jamLiquidity:           cfg.generalJamLiquidity(),
+		params:                 cfg.params,
+		thresholdPercent:       cfg.thresholdPercent,
+		bestReputation:         bestHonestReputation(peers),
 	}, nil
 }
+
+// surgeAttackAllCutoffs returns the outcome of a surge attack for every
+// possible cutoff index, sorting the peers once and accumulating the revenue
+// that is cut off as the cutoff increases rather than modeling each attack
+// separately. The outcome at index i of the slice returned is the outcome of
+// surgeAttack with cutoff index i, and is nil for cutoffs that fall beneath
+// the config's band, which are not valid attacks.
+func surgeAttackAllCutoffs(honestPeers []uint64,
+	cfg surgeAttackCfg) ([]*SurgeAttackOutcome, error) {
+
+	peers, err := cfg.sortedPeers(outgoingPeers(honestPeers))
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.bandLowIndex < 0 || cfg.bandLowIndex >= len(peers) {
+		return nil, fmt.Errorf("Band low index: %v not in [0, %v)",
+			cfg.bandLowIndex, len(peers))
+	}
+
+	var (
+		contributions = make([]uint64, len(peers))
+		peaceRevenue  uint64
+	)
+
+	for i, peer := range peers {
+		contributions[i] = peer.revenue(cfg.params)
+		peaceRevenue = saturatingAdd(peaceRevenue, contributions[i])
+	}
+
+	if peaceRevenue == 0 {
+		return nil, fmt.Errorf("%w: %v peers", errZeroRevenue,
+			len(peers))
+	}
+
+	// Walk up through the band, tracking the best peer that has been cut
+	// off so far and the revenue that the node loses by cutting off all of
+	// the unprotected peers in the band up to the current cutoff.
+	var (
+		outcomes           = make([]*SurgeAttackOutcome, len(peers))
+		bestReputation     = bestHonestReputation(peers)
+		reputationToCutOff uint64
+		revenueCutOff      uint64
+	)
+
+	// An insider's revenue is lost at every cutoff, because the attacker
+	// stops forwarding its traffic during the attack.
+	for i, peer := range peers {
+		if peer.insider {
+			revenueCutOff += contributions[i]
+		}
+	}
+
+	for i := cfg.bandLowIndex; i < len(peers); i++ {
+		if !peers[i].insider && !cfg.protected(peers[i]) {
+			reputationToCutOff = peers[i].reputation()
+			revenueCutOff += contributions[i]
+		}
+
+		outcomes[i] = &SurgeAttackOutcome{
+			cutoffReputation: reputationToCutOff,
+			peaceRevenue:     peaceRevenue,
+			attackRevenue:    peaceRevenue - revenueCutOff,
+
+			attackerSettledRevenue: cfg.jamSettledRevenue,
+			weeklyRevenue:          cfg.weeklyRevenue,
+			jamLiquidity:           cfg.generalJamLiquidity(),
+			params:                 cfg.params,
+			thresholdPercent:       cfg.thresholdPercent,
+			bestReputation:         bestReputation,
+		}
+	}
+
+	return outcomes, nil
+}
+
+// bestSurgeCutoff returns the cutoff index that gives the attacker the
+// cheapest successful surge attack against the honest peers provided, along
+// with its outcome. If several cutoffs cost the attacker the same amount, the
+// cutoff that costs the node the most revenue is preferred. A false boolean is
+// returned if no cutoff results in a successful attack.
+func bestSurgeCutoff(honestPeers []uint64, cfg surgeAttackCfg) (int,
+	*SurgeAttackOutcome, bool, error) {
+
+	outcomes, err := surgeAttackAllCutoffs(honestPeers, cfg)
+	if err != nil {
+		return 0, nil, false, err
+	}
+
+	var (
+		best       *SurgeAttackOutcome
+		bestCutoff int
+		bestPays   uint64
+		bestLost   uint64
+	)
+
+	for cutoff, outcome := range outcomes {
+		// Cutoffs beneath the config's band aren't valid attacks.
+		if outcome == nil {
+			continue
+		}
+
+		success, err := outcome.Success()
+		if err != nil {
+			return 0, nil, false, fmt.Errorf("cutoff %v: %w", cutoff,
+				err)
+		}
+
+		if !success {
+			continue
+		}
+
+		pays, lost := outcome.attackerPays(), outcome.revenueLost()
+		if best != nil && (pays > bestPays ||
+			(pays == bestPays && lost <= bestLost)) {
+
+			continue
+		}
+
+		best, bestCutoff, bestPays, bestLost = outcome, cutoff, pays,
+			lost
+	}
+
+	return bestCutoff, best, best != nil, nil
+}
+
+// surgeTier describes a single tier of a multi-tier surge attack.
+type surgeTier struct {
+	// cutoffIndex is the index in the sorted set of peers up to which the
+	// tier cuts off reputation.
+	cutoffIndex int
+
+	// cutoffReputation is the reputation of the best peer that is cut off
+	// by the tier.
+	cutoffReputation uint64
+
+	// attackerPays is the amount that the attacker pays to inflate the
+	// node's threshold to the tier's cutoff, which also cuts off every
+	// lower tier.
+	attackerPays uint64
+
+	// marginalPays is the additional amount that the attacker pays to
+	// cut off the tier on top of the tiers below it.
+	marginalPays uint64
+
+	// survivingRevenue is the honest revenue that the node still earns
+	// from peers that are above the tier's cutoff.
+	survivingRevenue uint64
+}
+
+// surgeMultiOutcome is the outcome of a surge attack that cuts off peers at
+// several reputation levels.
+type surgeMultiOutcome struct {
+	// peaceRevenue is the node's revenue when it is not under attack.
+	peaceRevenue uint64
+
+	// tiers holds the outcome of each tier of the attack, in order of
+	// increasing cutoff.
+	tiers []surgeTier
+}
+
+// totalPaid returns the amount that the attacker pays to cut off every tier.
+// Inflating the threshold to the highest cutoff cuts off every tier below it,
+// so this is the payment for the highest tier, which is also the sum of each
+// tier's marginal payment.
+func (s *surgeMultiOutcome) totalPaid() uint64 {
+	if len(s.tiers) == 0 {
+		return 0
+	}
+
+	return s.tiers[len(s.tiers)-1].attackerPays
+}
+
+// residualRevenue returns the honest revenue that survives all of the tiers,
+// ie the revenue from peers above the highest cutoff.
+func (s *surgeMultiOutcome) residualRevenue() uint64 {
+	if len(s.tiers) == 0 {
+		return s.peaceRevenue
+	}
+
+	return s.tiers[len(s.tiers)-1].survivingRevenue
+}
+
+// surgeAttackMulti models a surge attack that cuts off peers at each of the
+// cutoff indices provided, which must be strictly increasing. Each tier
+// requires the attacker to inflate the node's threshold above the reputation
+// of the best peer in the tier, which also cuts off every tier below it, so
+// the attacker only pays the difference between successive tiers to cut off
+// the next one. This allows the diminishing returns of cutting off more
+// valuable peers to be observed.
+func surgeAttackMulti(honestPeers []uint64, cutoffIndices []int,
+	cfg surgeAttackCfg) (*surgeMultiOutcome, error) {
+
+	if len(cutoffIndices) == 0 {
+		return nil, fmt.Errorf("at least one cutoff required")
+	}
+
+	outcome := &surgeMultiOutcome{
+		tiers: make([]surgeTier, 0, len(cutoffIndices)),
+	}
+
+	for i, cutoff := range cutoffIndices {
+		if i > 0 && cutoff <= cutoffIndices[i-1] {
+			return nil, fmt.Errorf("cutoff: %v not greater than "+
+				"previous: %v", cutoff, cutoffIndices[i-1])
+		}
+
+		tier, err := surgeAttack(honestPeers, cutoff, cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		var paidBelow uint64
+		if i > 0 {
+			paidBelow = outcome.tiers[i-1].attackerPays
+		}
+
+		var marginalPays uint64
+		if pays := tier.attackerPays(); pays > paidBelow {
+			marginalPays = pays - paidBelow
+		}
+
+		outcome.peaceRevenue = tier.peaceRevenue
+		outcome.tiers = append(outcome.tiers, surgeTier{
+			cutoffIndex:      cutoff,
+			cutoffReputation: tier.cutoffReputation,
+			attackerPays:     tier.attackerPays(),
+			marginalPays:     marginalPays,
+			survivingRevenue: tier.attackRevenue,
+		})
+	}
+
+	return outcome, nil
+}
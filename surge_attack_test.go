@@ -0,0 +1,970 @@
+package reputationfuzz
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// equalPeers returns a set of honest peers that each have the reputation
+// provided.
+func equalPeers(count int, reputation uint64) []uint64 {
+	peers := make([]uint64, count)
+	for i := range peers {
+		peers[i] = reputation
+	}
+
+	return peers
+}
+
+// TestSurgeOutcomeCloseness tests that closeness increases monotonically as
+// the revenue a node earns under attack approaches its peace time revenue.
+func TestSurgeOutcomeCloseness(t *testing.T) {
+	outcome := &SurgeAttackOutcome{
+		cutoffReputation: 6_000_000_000,
+		peaceRevenue:     4_000_000_000,
+	}
+
+	// The attacker pays 2_000_000_000, so the attack is on the boundary of
+	// success when the node still earns 2_000_000_000 in attack revenue.
+	previous := 0.0
+	for _, attackRevenue := range []uint64{
+		4_000_000_000, 3_000_000_000, 2_500_000_000, 2_000_000_000,
+	} {
+		outcome.attackRevenue = attackRevenue
+		closeness := outcome.Closeness()
+		require.Greater(t, closeness, previous)
+
+		success, err := outcome.Success()
+		require.NoError(t, err)
+		require.False(t, success)
+
+		previous = closeness
+	}
+	require.Equal(t, 1.0, previous)
+
+	outcome.attackRevenue = 1_999_999_999
+	require.Greater(t, outcome.Closeness(), 1.0)
+
+	success, err := outcome.Success()
+	require.NoError(t, err)
+	require.True(t, success)
+}
+
+// TestSurgeReputationFloor tests that a reputation floor prevents an attacker
+// from cutting off peers with reputation above the floor.
+func TestSurgeReputationFloor(t *testing.T) {
+	peers := func() []uint64 {
+		return []uint64{
+			12_000_000_000, 12_000_000_000, 12_000_000_000,
+			12_000_000_000, 12_000_000_000, 12_000_000_000,
+			12_000_000_000, 12_000_000_000, 12_000_000_000,
+			12_000_000_000,
+		}
+	}
+
+	// Without a floor, cutting off all peers is a successful attack.
+	outcome, err := surgeAttack(peers(), 9, surgeAttackCfg{})
+	require.NoError(t, err)
+
+	success, err := outcome.Success()
+	require.NoError(t, err)
+	require.True(t, success)
+
+	// With a floor beneath the peers' reputation, none of them can be cut
+	// off so the attack is no longer successful.
+	outcome, err = surgeAttack(peers(), 9, surgeAttackCfg{
+		reputationFloor: 10_000_000_000,
+	})
+	require.NoError(t, err)
+	require.Zero(t, outcome.cutoffReputation)
+	require.Equal(t, outcome.peaceRevenue, outcome.attackRevenue)
+
+	success, err = outcome.Success()
+	require.NoError(t, err)
+	require.False(t, success)
+}
+
+// TestSurgeAllowlist tests that allowlisting a node's most valuable peers can
+// defeat every surge attack cutoff.
+func TestSurgeAllowlist(t *testing.T) {
+	// Five valuable peers are interleaved with five less valuable ones,
+	// so that allowlisted peers need to be tracked through sorting.
+	peers := []uint64{
+		14_000_000_000, 10_000_000_000, 14_000_000_000, 10_000_000_000,
+		14_000_000_000, 10_000_000_000, 14_000_000_000, 10_000_000_000,
+		14_000_000_000, 10_000_000_000,
+	}
+
+	// successfulCutoffs returns the number of cutoffs that are successful
+	// attacks when the first count valuable peers are allowlisted.
+	successfulCutoffs := func(count int) int {
+		allowlist := make([]bool, len(peers))
+		for i := 0; i < count; i++ {
+			allowlist[i*2] = true
+		}
+
+		var successful int
+		for cutoff := range peers {
+			outcome, err := surgeAttack(peers, cutoff, surgeAttackCfg{
+				allowlist: allowlist,
+			})
+			require.NoError(t, err)
+
+			success, err := outcome.Success()
+			require.NoError(t, err)
+
+			if success {
+				successful++
+			}
+		}
+
+		return successful
+	}
+
+	// Without an allowlist, cutting off any of the valuable peers is a
+	// successful attack. The less valuable peers don't have good
+	// reputation so there's no point in cutting them off.
+	require.Equal(t, 5, successfulCutoffs(0))
+
+	// Allowlisting some of the valuable peers isn't sufficient, because
+	// the attacker can still cut off the rest.
+	require.Positive(t, successfulCutoffs(4))
+
+	// Once all of the valuable peers are allowlisted, no cutoff is a
+	// successful attack.
+	require.Zero(t, successfulCutoffs(5))
+
+	_, err := surgeAttack(peers, 0, surgeAttackCfg{
+		allowlist: []bool{true},
+	})
+	require.Error(t, err)
+}
+
+// TestSurgePeerGroups tests that grouping redundant channels with the same peer
+// changes the outcome of a surge attack, because the attacker must pay to cut
+// off the peer's combined reputation.
+func TestSurgePeerGroups(t *testing.T) {
+	peers := func() []uint64 {
+		return []uint64{
+			7_000_000_000, 7_000_000_000, 7_000_000_000,
+			12_000_000_000, 12_000_000_000, 12_000_000_000,
+			12_000_000_000, 12_000_000_000, 12_000_000_000,
+			12_000_000_000, 12_000_000_000,
+		}
+	}
+
+	// When each channel is treated separately, the attacker can cut off
+	// every channel by paying up to 12_000_000_000.
+	outcome, err := surgeAttack(peers(), 10, surgeAttackCfg{})
+	require.NoError(t, err)
+	require.EqualValues(t, 12_000_000_000, outcome.cutoffReputation)
+
+	success, err := outcome.Success()
+	require.NoError(t, err)
+	require.True(t, success)
+
+	// When the first three channels belong to the same peer, cutting off
+	// every peer requires paying up to their combined reputation, which
+	// is too expensive.
+	cfg := surgeAttackCfg{
+		peerGroups: []int{0, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8},
+	}
+
+	outcome, err = surgeAttack(peers(), 8, cfg)
+	require.NoError(t, err)
+	require.EqualValues(t, 21_000_000_000, outcome.cutoffReputation)
+
+	success, err = outcome.Success()
+	require.NoError(t, err)
+	require.False(t, success)
+
+	// The grouped peer set has fewer entries, so the ungrouped cutoff is
+	// no longer valid.
+	_, err = surgeAttack(peers(), 10, cfg)
+	require.Error(t, err)
+
+	// Groups must be provided for every channel.
+	_, err = surgeAttack(peers(), 0, surgeAttackCfg{
+		peerGroups: []int{0},
+	})
+	require.Error(t, err)
+}
+
+// TestMinimumHTLCFlipPoint tests finding the minimum HTLC size at which an
+// attack on the seed peer set flips from successful to unsuccessful.
+func TestMinimumHTLCFlipPoint(t *testing.T) {
+	peers := []uint64{
+		2000, 995735184, 172248607, 186257710, 121153119, 794542970,
+		438050891, 372484894, 306771541, 271374988,
+	}
+
+	// At our default minimum HTLC, none of the peers have good enough
+	// reputation for the attack to be successful.
+	outcome, err := surgeAttack(peers, 5, surgeAttackCfg{})
+	require.NoError(t, err)
+
+	success, err := outcome.Success()
+	require.NoError(t, err)
+	require.False(t, success)
+
+	// Without any minimum, the attack succeeds, and it flips once the
+	// cost of a minimum HTLC exceeds the cut off peer's surplus of
+	// 1_886_387 over the threshold.
+	flip, ok, err := outcome.minimumHTLCFlipPoint()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.EqualValues(t, 2830, flip)
+
+	success, err = outcome.successWithMinimum(flip - 1)
+	require.NoError(t, err)
+	require.True(t, success)
+
+	success, err = outcome.successWithMinimum(flip)
+	require.NoError(t, err)
+	require.False(t, success)
+
+	// Cutting off all peers is never successful, so there is no flip.
+	outcome, err = surgeAttack(peers, 9, surgeAttackCfg{})
+	require.NoError(t, err)
+
+	_, ok, err = outcome.minimumHTLCFlipPoint()
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+// TestSurgeBandedCutoff compares cutting off a band of peers by value against
+// cutting off all peers up to the same index on the seed set.
+func TestSurgeBandedCutoff(t *testing.T) {
+	peers := func() []uint64 {
+		return []uint64{
+			2000, 995735184, 172248607, 186257710, 121153119,
+			794542970, 438050891, 372484894, 306771541, 271374988,
+		}
+	}
+
+	prefix, err := surgeAttack(peers(), 5, surgeAttackCfg{})
+	require.NoError(t, err)
+
+	banded, err := surgeAttack(peers(), 5, surgeAttackCfg{
+		bandLowIndex: 3,
+	})
+	require.NoError(t, err)
+
+	// The attacker pays the same to cut off the top of the band, and the
+	// node's peace time revenue is unchanged.
+	require.Equal(t, prefix.cutoffReputation, banded.cutoffReputation)
+	require.Equal(t, prefix.peaceRevenue, banded.peaceRevenue)
+
+	// The three least valuable peers are below the band, so they still
+	// earn the node revenue under attack.
+	lowRevenue := revenueFromReputation(2000, Params{}) +
+		revenueFromReputation(121153119, Params{}) +
+		revenueFromReputation(172248607, Params{})
+	require.Equal(t, prefix.attackRevenue+lowRevenue, banded.attackRevenue)
+
+	// Without a minimum HTLC requirement, both attacks succeed.
+	for _, outcome := range []*SurgeAttackOutcome{prefix, banded} {
+		success, err := outcome.successWithMinimum(0)
+		require.NoError(t, err)
+		require.True(t, success)
+	}
+
+	// The band must lie within the cutoff.
+	_, err = surgeAttack(peers(), 5, surgeAttackCfg{
+		bandLowIndex: 6,
+	})
+	require.Error(t, err)
+}
+
+// syntheticSurgePeers is a synthetic fixture of 18 peers for which a surge
+// attack that cuts off every peer (cutoff index 17) is successful. The values
+// were chosen by hand to mix a few small peers with a cluster of large ones,
+// and are not taken from a real node. Values are the peers' reputation over
+// the six month reputation period, in msat.
+var syntheticSurgePeers = []uint64{
+	1_250_000_000, 980_000_000, 2_100_000_000, 450_000_000,
+	3_300_000_000, 1_750_000_000, 2_600_000_000, 720_000_000,
+	11_400_000_000, 11_850_000_000, 12_200_000_000, 10_900_000_000,
+	11_100_000_000, 12_600_000_000, 11_750_000_000, 12_050_000_000,
+	10_650_000_000, 11_300_000_000,
+}
+
+// TestSurgeSyntheticFixture tests a surge attack against the synthetic
+// fixture, pinning the per-peer contributions and outcome that the model
+// currently produces so that changes to the model can't silently alter them.
+func TestSurgeSyntheticFixture(t *testing.T) {
+	peers := make([]uint64, len(syntheticSurgePeers))
+	copy(peers, syntheticSurgePeers)
+
+	outcome, err := surgeAttack(peers, 17, surgeAttackCfg{})
+	require.NoError(t, err)
+
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i] < peers[j]
+	})
+
+	// Each peer's two week revenue contribution, from least to most
+	// valuable peer.
+	contributions := []uint64{
+		37_500_000, 60_000_000, 81_666_666, 104_166_666, 145_833_333,
+		175_000_000, 216_666_666, 275_000_000, 887_500_000,
+		908_333_333, 925_000_000, 941_666_666, 950_000_000,
+		979_166_666, 987_500_000, 1_004_166_666, 1_016_666_666,
+		1_050_000_000,
+	}
+	require.Len(t, peers, len(contributions))
+	for i, peer := range peers {
+		revenue := revenueFromReputation(peer, Params{})
+		require.Equal(t, contributions[i], revenue, "peer: %v", i)
+	}
+
+	require.EqualValues(t, 12_600_000_000, outcome.cutoffReputation)
+	require.EqualValues(t, 10_745_833_328, outcome.peaceRevenue)
+	require.Zero(t, outcome.attackRevenue)
+	require.EqualValues(t, 1_854_166_672, outcome.attackerPays())
+
+	success, err := outcome.Success()
+	require.NoError(t, err)
+	require.True(t, success)
+}
+
+// TestSurgeAttackMulti tests cutting off peers in several tiers, reporting the
+// revenue that survives each tier.
+func TestSurgeAttackMulti(t *testing.T) {
+	// Peers contribute 1, 2, 2, 3, 3 and 4 (e9) revenue respectively.
+	peers := []uint64{
+		36_000_000_000, 12_000_000_000, 24_000_000_000,
+		48_000_000_000, 24_000_000_000, 36_000_000_000,
+	}
+
+	outcome, err := surgeAttackMulti(peers, []int{2, 4, 5}, surgeAttackCfg{})
+	require.NoError(t, err)
+	require.EqualValues(t, 15_000_000_000, outcome.peaceRevenue)
+
+	require.Equal(t, []surgeTier{
+		{
+			cutoffIndex:      2,
+			cutoffReputation: 24_000_000_000,
+			attackerPays:     9_000_000_000,
+			marginalPays:     9_000_000_000,
+			survivingRevenue: 10_000_000_000,
+		},
+		{
+			cutoffIndex:      4,
+			cutoffReputation: 36_000_000_000,
+			attackerPays:     21_000_000_000,
+			marginalPays:     12_000_000_000,
+			survivingRevenue: 4_000_000_000,
+		},
+		{
+			cutoffIndex:      5,
+			cutoffReputation: 48_000_000_000,
+			attackerPays:     33_000_000_000,
+			marginalPays:     12_000_000_000,
+			survivingRevenue: 0,
+		},
+	}, outcome.tiers)
+
+	// Cutting off the highest tier cuts off the tiers below it, so the
+	// attacker only pays for the highest tier.
+	require.EqualValues(t, 33_000_000_000, outcome.totalPaid())
+	require.Zero(t, outcome.residualRevenue())
+
+	// Cutoffs must be strictly increasing.
+	_, err = surgeAttackMulti(peers, []int{2, 2}, surgeAttackCfg{})
+	require.Error(t, err)
+
+	_, err = surgeAttackMulti(peers, nil, surgeAttackCfg{})
+	require.Error(t, err)
+}
+
+// TestSurgeAttackAllCutoffs tests that the outcomes for every cutoff match
+// modeling each cutoff separately.
+func TestSurgeAttackAllCutoffs(t *testing.T) {
+	peers := benchmarkPeers(200)
+
+	allowlist := make([]bool, len(peers))
+	for i := range allowlist {
+		allowlist[i] = i%17 == 0
+	}
+
+	cfgs := []surgeAttackCfg{
+		{},
+		{
+			reputationFloor: 50_000_000_000,
+			allowlist:       allowlist,
+		},
+		{
+			bandLowIndex: 20,
+		},
+	}
+
+	for _, cfg := range cfgs {
+		outcomes, err := surgeAttackAllCutoffs(peers, cfg)
+		require.NoError(t, err)
+		require.Len(t, outcomes, len(peers))
+
+		for cutoff, outcome := range outcomes {
+			if cutoff < cfg.bandLowIndex {
+				require.Nil(t, outcome)
+				continue
+			}
+
+			expected, err := surgeAttack(peers, cutoff, cfg)
+			require.NoError(t, err)
+			require.Equal(t, expected, outcome, "cutoff: %v", cutoff)
+		}
+	}
+
+	_, err := surgeAttackAllCutoffs(peers, surgeAttackCfg{
+		bandLowIndex: len(peers),
+	})
+	require.Error(t, err)
+}
+
+// benchmarkPeers returns a deterministic set of honest peers of the size
+// provided.
+func benchmarkPeers(count int) []uint64 {
+	rng := rand.New(rand.NewSource(1))
+
+	peers := make([]uint64, count)
+	for i := range peers {
+		peers[i] = uint64(rng.Int63n(100_000_000_000))
+	}
+
+	return peers
+}
+
+// BenchmarkSurgeAttack benchmarks modeling a surge attack on a node with
+// thousands of channels.
+func BenchmarkSurgeAttack(b *testing.B) {
+	peers := benchmarkPeers(5000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := surgeAttack(peers, len(peers)/2, surgeAttackCfg{})
+		require.NoError(b, err)
+	}
+}
+
+// BenchmarkSurgeAttackAllCutoffs benchmarks modeling a surge attack at every
+// cutoff on a node with thousands of channels.
+func BenchmarkSurgeAttackAllCutoffs(b *testing.B) {
+	peers := benchmarkPeers(5000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := surgeAttackAllCutoffs(peers, surgeAttackCfg{})
+		require.NoError(b, err)
+	}
+}
+
+// TestSurgeBidirectional tests that peers are ranked and cut off by their
+// combined reputation in both directions, and that the node earns revenue
+// from both directions.
+func TestSurgeBidirectional(t *testing.T) {
+	peers := []bidirectionalPeer{
+		// Heavy incoming traffic but light outgoing traffic, so
+		// this peer is the most valuable overall.
+		{
+			incoming: 30_000_000_000,
+			outgoing: 1_000_000_000,
+		},
+		{
+			outgoing: 20_000_000_000,
+		},
+		{
+			outgoing: 12_000_000_000,
+		},
+	}
+
+	outcome, err := surgeAttackBidirectional(peers, 1, surgeAttackCfg{})
+	require.NoError(t, err)
+
+	// Cutting off the two least valuable peers requires overcoming the
+	// outgoing-only peer, and the node keeps earning from both
+	// directions with the peer that has heavy incoming traffic.
+	require.Equal(t, &SurgeAttackOutcome{
+		cutoffReputation: 20_000_000_000,
+		peaceRevenue:     5_249_999_999,
+		attackRevenue:    2_583_333_333,
+		bestReputation:   31_000_000_000,
+		jamLiquidity:     uint64(maxHTLCSlots) * defaultMinHTLCSize,
+	}, outcome)
+
+	// The single-direction wrapper only accounts for outgoing traffic.
+	outgoing, err := surgeAttack(
+		[]uint64{1_000_000_000, 20_000_000_000, 12_000_000_000}, 1,
+		surgeAttackCfg{},
+	)
+	require.NoError(t, err)
+	require.EqualValues(t, 12_000_000_000, outgoing.cutoffReputation)
+	require.EqualValues(t, 1_666_666_666, outgoing.attackRevenue)
+}
+
+// TestSurgeGracePeriod tests that peers within their grace period have
+// provisional reputation, so they can't be cut off by the attacker.
+func TestSurgeGracePeriod(t *testing.T) {
+	peers := []uint64{12_000_000_000, 24_000_000_000, 36_000_000_000}
+
+	// The least valuable peer is brand new, so the attacker can only cut
+	// off the established peer above it.
+	outcome, err := surgeAttack(peers, 1, surgeAttackCfg{
+		peerAges:   []uint64{1, 10, 10},
+		graceWeeks: 4,
+	})
+	require.NoError(t, err)
+	require.Equal(t, &SurgeAttackOutcome{
+		cutoffReputation: 24_000_000_000,
+		peaceRevenue:     6_000_000_000,
+		attackRevenue:    4_000_000_000,
+		bestReputation:   36_000_000_000,
+		jamLiquidity:     uint64(maxHTLCSlots) * defaultMinHTLCSize,
+	}, outcome)
+
+	// Without a grace period both peers are cut off.
+	outcome, err = surgeAttack(peers, 1, surgeAttackCfg{
+		peerAges: []uint64{1, 10, 10},
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 3_000_000_000, outcome.attackRevenue)
+
+	// A grouped peer is as old as its oldest channel.
+	outcome, err = surgeAttack(peers, 0, surgeAttackCfg{
+		peerAges:   []uint64{1, 10, 2},
+		peerGroups: []int{0, 1, 0},
+		graceWeeks: 4,
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 24_000_000_000, outcome.cutoffReputation)
+
+	// If every peer is within the grace period, nobody can be cut off so
+	// the attack is ineffective.
+	outcome, err = surgeAttack(peers, 2, surgeAttackCfg{
+		peerAges:   []uint64{0, 1, 3},
+		graceWeeks: 4,
+	})
+	require.NoError(t, err)
+	require.Zero(t, outcome.cutoffReputation)
+	require.Equal(t, outcome.peaceRevenue, outcome.attackRevenue)
+
+	success, err := outcome.Success()
+	require.NoError(t, err)
+	require.False(t, success)
+
+	_, err = surgeAttack(peers, 1, surgeAttackCfg{
+		peerAges: []uint64{1},
+	})
+	require.Error(t, err)
+}
+
+// TestSurgeSettledJams tests that fees paid by the attacker's jamming HTLCs
+// count towards the node's revenue under attack if they settle.
+func TestSurgeSettledJams(t *testing.T) {
+	peers := equalPeers(10, 12_000_000_000)
+
+	// If the attacker's jams are failed back, the node only earns the
+	// attacker's payment and the revenue from the peer that isn't cut
+	// off.
+	held, err := surgeAttack(peers, 8, surgeAttackCfg{})
+	require.NoError(t, err)
+	require.EqualValues(t, 3_000_000_000, held.earnedUnderAttack())
+
+	success, err := held.Success()
+	require.NoError(t, err)
+	require.True(t, success)
+
+	// Settled jams that pay less than the node lost don't change the
+	// result of the attack, but reduce the node's loss.
+	settled, err := surgeAttack(peers, 8, surgeAttackCfg{
+		jamSettledRevenue: 6_000_000_000,
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 9_000_000_000, settled.earnedUnderAttack())
+	require.EqualValues(t, 10, settled.lossPercent())
+
+	success, err = settled.Success()
+	require.NoError(t, err)
+	require.True(t, success)
+
+	// If the settled jams pay enough fees, they keep the node above its
+	// peace time revenue and the attack backfires.
+	backfire, err := surgeAttack(peers, 8, surgeAttackCfg{
+		jamSettledRevenue: 8_000_000_000,
+	})
+	require.NoError(t, err)
+	require.Zero(t, backfire.lossPercent())
+	require.Greater(t, backfire.Closeness(), 0.0)
+	require.Less(t, backfire.Closeness(), 1.0)
+
+	success, err = backfire.Success()
+	require.NoError(t, err)
+	require.False(t, success)
+}
+
+// TestSurgeMargin tests that the margin of a surge attack is positive exactly
+// when the attack is successful.
+func TestSurgeMargin(t *testing.T) {
+	peers := equalPeers(10, 12_000_000_000)
+
+	// The node earns 3e9 under attack rather than 10e9.
+	outcome, err := surgeAttack(peers, 8, surgeAttackCfg{})
+	require.NoError(t, err)
+	require.EqualValues(t, 7_000_000_000, outcome.Margin())
+
+	// Settled jams that keep the node above its peace revenue give a
+	// negative margin.
+	outcome, err = surgeAttack(peers, 8, surgeAttackCfg{
+		jamSettledRevenue: 8_000_000_000,
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, -1_000_000_000, outcome.Margin())
+
+	// Peers that didn't have good reputation to begin with give a
+	// negative margin, even though the attacker doesn't pay anything.
+	outcome = &SurgeAttackOutcome{
+		cutoffReputation: 1_000,
+		peaceRevenue:     10_000,
+	}
+	require.Negative(t, outcome.Margin())
+
+	success, err := outcome.Success()
+	require.NoError(t, err)
+	require.False(t, success)
+}
+
+// TestSurgeRecovery tests that the revenue that a node loses while recovering
+// from a surge attack counts towards the attack's success.
+func TestSurgeRecovery(t *testing.T) {
+	// Six peers that each contribute 1e9 of revenue.
+	peers := equalPeers(6, 12_000_000_000)
+
+	// Cutting off five of the peers costs the attacker 6e9, so the node
+	// earns 7e9 under attack which is more than its peace revenue.
+	outcome, err := surgeAttack(peers, 4, surgeAttackCfg{})
+	require.NoError(t, err)
+
+	weeks, err := outcome.recoveryWeeks()
+	require.NoError(t, err)
+	require.Zero(t, weeks)
+	require.Zero(t, outcome.recoveryLoss())
+
+	success, err := outcome.Success()
+	require.NoError(t, err)
+	require.False(t, success)
+
+	// With 3e9 of revenue per week, the node only earns 0.5e9 per week
+	// from the surviving peer so it takes 12 weeks to recover, losing
+	// 2.5e9 of revenue each week.
+	outcome, err = surgeAttack(peers, 4, surgeAttackCfg{
+		weeklyRevenue: 3_000_000_000,
+	})
+	require.NoError(t, err)
+
+	weeks, err = outcome.recoveryWeeks()
+	require.NoError(t, err)
+	require.EqualValues(t, 12, weeks)
+	require.EqualValues(t, 30_000_000_000, outcome.recoveryLoss())
+	require.EqualValues(t, 29_000_000_000, outcome.Margin())
+
+	success, err = outcome.Success()
+	require.NoError(t, err)
+	require.True(t, success)
+
+	// If every peer is cut off, the node has no surviving traffic to
+	// re-clear the threshold with, so it never recovers and loses all of
+	// its weekly revenue for the 24 week reputation period.
+	outcome, err = surgeAttack(peers, 5, surgeAttackCfg{
+		weeklyRevenue: 3_000_000_000,
+	})
+	require.NoError(t, err)
+
+	_, err = outcome.recoveryWeeks()
+	require.ErrorIs(t, err, errNoRecovery)
+	require.EqualValues(t, 72_000_000_000, outcome.recoveryLoss())
+
+	// A shorter reputation period shortens the tail that we model.
+	outcome.params.ReputationPeriodWeeks = 12
+	require.EqualValues(t, 36_000_000_000, outcome.recoveryLoss())
+
+	success, err = outcome.Success()
+	require.NoError(t, err)
+	require.True(t, success)
+}
+
+// TestSurgeMinimumHTLC tests that lowering the minimum HTLC that peers must be
+// able to get endorsed makes more peers interesting targets.
+func TestSurgeMinimumHTLC(t *testing.T) {
+	// Eleven peers that each contribute 1e9 of revenue, so cutting all of
+	// them off only requires the attacker to pay 1e9.
+	peers := equalPeers(11, 12_000_000_000)
+
+	// At the default $1 floor, the peers can't get a minimum HTLC
+	// endorsed over the node's threshold.
+	outcome, err := surgeAttack(peers, 10, surgeAttackCfg{})
+	require.NoError(t, err)
+	require.False(t, outcome.hadGoodReputation())
+
+	success, err := outcome.Success()
+	require.NoError(t, err)
+	require.False(t, success)
+	require.Negative(t, outcome.Margin())
+
+	// At a $0.10 floor they had good reputation, so the attack succeeds.
+	outcome, err = surgeAttack(peers, 10, surgeAttackCfg{
+		params: Params{
+			MinimumHTLC: minimumHTLCReputation / 10,
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, outcome.hadGoodReputation())
+
+	success, err = outcome.Success()
+	require.NoError(t, err)
+	require.True(t, success)
+	require.EqualValues(t, 10_000_000_000, outcome.Margin())
+}
+
+// TestSurgeZeroRevenue tests that surge attacks on a node that doesn't earn
+// any revenue are rejected, and that an outcome with zero revenue can still be
+// printed.
+func TestSurgeZeroRevenue(t *testing.T) {
+	// Each peer's reputation is too small to contribute any revenue.
+	peers := []uint64{5, 11}
+
+	_, err := SurgeAttack(peers, 1, SurgeConfig{})
+	require.ErrorIs(t, err, errZeroRevenue)
+
+	_, err = surgeAttackAllCutoffs(peers, surgeAttackCfg{})
+	require.ErrorIs(t, err, errZeroRevenue)
+
+	outcome := &SurgeAttackOutcome{cutoffReputation: 11}
+	require.NotPanics(t, func() {
+		_ = outcome.String()
+	})
+	require.Zero(t, outcome.lossPercent())
+}
+
+// TestBestSurgeCutoff tests finding the cutoff that gives the attacker the
+// cheapest successful attack.
+func TestBestSurgeCutoff(t *testing.T) {
+	// Every peer has the same reputation, so every cutoff costs the
+	// attacker the same amount. Cutting off fewer than two peers doesn't
+	// cost the node enough revenue to cover the attacker's payment, and
+	// cutting off every peer costs the node the most revenue.
+	peers := []uint64{
+		12_000_000_000, 12_000_000_000, 12_000_000_000, 12_000_000_000,
+		12_000_000_000, 12_000_000_000, 12_000_000_000, 12_000_000_000,
+		12_000_000_000, 12_000_000_000,
+	}
+
+	cutoff, outcome, ok, err := bestSurgeCutoff(peers, surgeAttackCfg{})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 9, cutoff)
+	require.EqualValues(t, 2_000_000_000, outcome.attackerPays())
+	require.EqualValues(t, 8_000_000_000, outcome.revenueLost())
+
+	// With peers of varied reputation, cutting off more peers costs the
+	// node more revenue, but the cheapest successful attack is preferred.
+	peers = []uint64{
+		3_000_000_000, 5_000_000_000, 7_000_000_000, 9_000_000_000,
+		11_000_000_000, 12_000_000_000, 13_000_000_000, 14_000_000_000,
+		20_000_000_000, 40_000_000_000,
+	}
+
+	cutoff, outcome, ok, err = bestSurgeCutoff(peers, surgeAttackCfg{})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 6, cutoff)
+
+	expected, err := surgeAttack(peers, cutoff, surgeAttackCfg{})
+	require.NoError(t, err)
+	require.Equal(t, expected, outcome)
+
+	// The best cutoff is the cheapest of modeling each cutoff separately.
+	for i := range peers {
+		expected, err := surgeAttack(peers, i, surgeAttackCfg{})
+		require.NoError(t, err)
+
+		success, err := expected.Success()
+		require.NoError(t, err)
+		if !success {
+			continue
+		}
+
+		require.LessOrEqual(t, outcome.attackerPays(),
+			expected.attackerPays(), "cutoff: %v", i)
+	}
+
+	// Two peers can't lose the node enough revenue to cover the cost of
+	// cutting either of them off.
+	_, _, ok, err = bestSurgeCutoff(
+		[]uint64{1_000_000_000, 1_000_000_000}, surgeAttackCfg{},
+	)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+// TestSurgeThresholdMultiplier tests raising the threshold that peers must
+// clear to have good reputation as a mitigation against surge attacks.
+func TestSurgeThresholdMultiplier(t *testing.T) {
+	peers := equalPeers(10, 12_000_000_000)
+
+	outcome, err := surgeAttack(peers, 9, surgeAttackCfg{})
+	require.NoError(t, err)
+
+	success, err := outcome.Success()
+	require.NoError(t, err)
+	require.True(t, success)
+	require.False(t, outcome.selfDenial())
+	require.EqualValues(t, 2_000_000_000, outcome.attackerPays())
+
+	// A higher threshold means the attacker's payment goes further.
+	raised, err := surgeAttack(peers, 9, surgeAttackCfg{
+		thresholdPercent: 105,
+	})
+	require.NoError(t, err)
+	require.Less(t, raised.attackerPays(), outcome.attackerPays())
+
+	success, err = raised.Success()
+	require.NoError(t, err)
+	require.True(t, success)
+
+	// Once the threshold is raised beyond the peers' reputation, they
+	// never had good reputation to lose. Every peer has the same
+	// reputation, so the defender denies its own peers service.
+	flip, ok, err := outcome.thresholdMultiplierFlipPoint()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.EqualValues(t, 109, flip)
+
+	flipped, err := surgeAttack(peers, 9, surgeAttackCfg{
+		thresholdPercent: flip,
+	})
+	require.NoError(t, err)
+	require.True(t, flipped.selfDenial())
+
+	success, err = flipped.Success()
+	require.NoError(t, err)
+	require.False(t, success)
+
+	flipped.thresholdPercent = flip - 1
+	success, err = flipped.Success()
+	require.NoError(t, err)
+	require.True(t, success)
+
+	// There's no flip point for an attack that isn't successful.
+	flipped.thresholdPercent = flip
+	_, ok, err = flipped.thresholdMultiplierFlipPoint()
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+// TestSurgeLossBasisPoints tests the precise portion of revenue that a node
+// loses to a surge attack, including attacks that make the node money.
+func TestSurgeLossBasisPoints(t *testing.T) {
+	// The node earns 2_500 from its honest peers and 3_000 from the
+	// attacker, so it loses 4_500 of 10_000.
+	outcome := &SurgeAttackOutcome{
+		cutoffReputation: 13_000,
+		peaceRevenue:     10_000,
+		attackRevenue:    2_500,
+	}
+	require.EqualValues(t, 4_500, outcome.LossBasisPoints())
+	require.EqualValues(t, 45, outcome.lossPercent())
+	require.Contains(t, outcome.String(), "Node lost: 45.00 %")
+
+	// Fractions of a percent aren't truncated.
+	outcome.attackRevenue = 2_512
+	require.EqualValues(t, 4_488, outcome.LossBasisPoints())
+	require.EqualValues(t, 44, outcome.lossPercent())
+	require.Contains(t, outcome.String(), "Node lost: 44.88 %")
+
+	// The attacker's payment plus the revenue that the node still earns
+	// exceeds its peace time revenue, so the node doesn't lose anything.
+	outcome.cutoffReputation = 18_000
+	outcome.attackRevenue = 3_000
+	require.Zero(t, outcome.LossBasisPoints())
+	require.Zero(t, outcome.lossPercent())
+	require.Contains(t, outcome.String(), "Node lost: 0.00 %")
+}
+
+// TestSurgeInsider tests surge attacks where the attacker is one of the
+// target's own peers, including when it is the target's best peer.
+func TestSurgeInsider(t *testing.T) {
+	// Nine honest peers that each contribute 1e9 of revenue, and a
+	// larger peer that contributes 2e9.
+	peers := equalPeers(10, 12_000_000_000)
+	peers[9] = 24_000_000_000
+
+	params := Params{
+		MinimumHTLC: minimumHTLCReputation / 10,
+	}
+
+	// An external attacker has to pay up to the large peer's reputation
+	// to cut it off, which costs more than the node loses.
+	external, err := surgeAttack(peers, 9, surgeAttackCfg{params: params})
+	require.NoError(t, err)
+	require.EqualValues(t, 13_000_000_000, external.attackerPays())
+
+	success, err := external.Success()
+	require.NoError(t, err)
+	require.False(t, success)
+
+	// When the large peer is the attacker, the node loses its revenue
+	// during the attack without the attacker paying to cut it off.
+	insider := make([]bool, len(peers))
+	insider[9] = true
+
+	cfg := surgeAttackCfg{
+		insider: insider,
+		params:  params,
+	}
+
+	outcome, err := surgeAttack(peers, 9, cfg)
+	require.NoError(t, err)
+	require.Equal(t, &SurgeAttackOutcome{
+		cutoffReputation: 12_000_000_000,
+		peaceRevenue:     11_000_000_000,
+		params:           params,
+		bestReputation:   12_000_000_000,
+		jamLiquidity:     uint64(maxHTLCSlots) * defaultMinHTLCSize,
+	}, outcome)
+	require.EqualValues(t, 1_000_000_000, outcome.attackerPays())
+
+	success, err = outcome.Success()
+	require.NoError(t, err)
+	require.True(t, success)
+	require.EqualValues(t, 10_000_000_000, outcome.Margin())
+
+	// Cutting off the honest peers alone, the insider's revenue is still
+	// lost, whereas an external attacker leaves it with the node.
+	outcome, err = surgeAttack(peers, 8, cfg)
+	require.NoError(t, err)
+	require.EqualValues(t, 10_000_000_000, outcome.Margin())
+
+	external, err = surgeAttack(peers, 8, surgeAttackCfg{params: params})
+	require.NoError(t, err)
+	require.EqualValues(t, 8_000_000_000, external.Margin())
+
+	// Modeling every cutoff at once gives the same outcomes.
+	outcomes, err := surgeAttackAllCutoffs(peers, cfg)
+	require.NoError(t, err)
+	for i, expected := range outcomes {
+		outcome, err := surgeAttack(peers, i, cfg)
+		require.NoError(t, err)
+		require.Equal(t, outcome, expected, "cutoff: %v", i)
+	}
+
+	// Only a single peer may be the attacker.
+	insider[0] = true
+	_, err = surgeAttack(peers, 9, cfg)
+	require.Error(t, err)
+
+	_, err = surgeAttack(peers, 9, surgeAttackCfg{insider: insider[:2]})
+	require.Error(t, err)
+}
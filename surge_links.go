@@ -0,0 +1,221 @@
+package reputationfuzz
+
+import "fmt"
+
+// surgeLink describes one of a targeted node's outgoing links in a surge
+// attack that may inflate several links at once.
+type surgeLink struct {
+	// honestPeers holds the reputation of each honest peer that forwards
+	// traffic over the link.
+	honestPeers []uint64
+
+	// cutoffIndex is the index in the link's sorted set of peers up to
+	// which the attacker cuts off reputation if the link is surged.
+	cutoffIndex int
+
+	// surged indicates that the attacker inflates the link's value.
+	surged bool
+}
+
+// linkAttack describes the combined revenue of a node's outgoing links when
+// a set of them are surged.
+type linkAttack struct {
+	// peaceRevenue is the node's revenue across all of its links when it
+	// is not under attack.
+	peaceRevenue uint64
+
+	// honestRevenue is the revenue that the node still earns from honest
+	// peers that aren't cut off, across all of its links.
+	honestRevenue uint64
+
+	// divertedRevenue is the revenue from peers that are cut off on a
+	// surged link that the node earns by routing their traffic over one of
+	// its links that isn't surged instead.
+	divertedRevenue uint64
+
+	// attackerPays is the total amount that the attacker pays to inflate
+	// each surged link.
+	attackerPays uint64
+
+	// goodReputation indicates that the peers that are cut off on every
+	// surged link had good reputation to begin with.
+	goodReputation bool
+}
+
+// earned returns the total revenue that the node earns during the attack.
+func (l linkAttack) earned() uint64 {
+	return saturatingAdd(
+		saturatingAdd(l.honestRevenue, l.divertedRevenue),
+		l.attackerPays,
+	)
+}
+
+// lostRevenue returns the revenue that the node loses across all of its links
+// under attack, zero if the peers cut off didn't have good reputation to start
+// with or the node doesn't lose any revenue.
+func (l linkAttack) lostRevenue() uint64 {
+	earned := l.earned()
+	if !l.goodReputation || earned >= l.peaceRevenue {
+		return 0
+	}
+
+	return l.peaceRevenue - earned
+}
+
+// costEffectiveness returns the revenue that the node loses per msat that the
+// attacker pays.
+func (l linkAttack) costEffectiveness() float64 {
+	return ratio(l.lostRevenue(), l.attackerPays)
+}
+
+// surgeLinksOutcome is the outcome of a surge attack that inflates several of
+// a node's outgoing links at once, compared to the best attack that only
+// inflates one of them.
+type surgeLinksOutcome struct {
+	// combined is the outcome of surging every surged link together.
+	combined linkAttack
+
+	// bestSingle is the outcome of the most cost effective attack that
+	// only surges one link.
+	bestSingle linkAttack
+
+	// bestSingleIndex is the index of the link that is surged by the best
+	// single link attack, -1 if no single link attack loses the node any
+	// revenue.
+	bestSingleIndex int
+}
+
+// combinedMoreEffective returns a boolean indicating whether surging every
+// link together costs the node more revenue per msat that the attacker pays
+// than the best attack on a single link.
+func (s *surgeLinksOutcome) combinedMoreEffective() bool {
+	if s.combined.lostRevenue() == 0 {
+		return false
+	}
+
+	if s.bestSingleIndex < 0 {
+		return true
+	}
+
+	return s.combined.costEffectiveness() >
+		s.bestSingle.costEffectiveness()
+}
+
+func (s *surgeLinksOutcome) String() string {
+	return fmt.Sprintf("Combined attack lost: %v of %v revenue (%v "+
+		"diverted) with attacker paying: %v, best single link: %v "+
+		"lost %v with attacker paying: %v", s.combined.lostRevenue(),
+		s.combined.peaceRevenue, s.combined.divertedRevenue,
+		s.combined.attackerPays, s.bestSingleIndex,
+		s.bestSingle.lostRevenue(), s.bestSingle.attackerPays)
+}
+
+// surgeAttackLinks models a surge attack that inflates each of the node's
+// outgoing links that is marked as surged, denying reputation to the honest
+// peers on each link up to its cutoff index. The attacker pays separately to
+// inflate every link, and the node loses the revenue of the peers that are cut
+// off on each of them.
+//
+// Honest peers that are cut off on a surged link may route a percentage of
+// their traffic over the node's links that aren't surged instead, which
+// partially compensates the node for the revenue that it loses. Traffic can
+// only be diverted if at least one of the node's links isn't surged.
+//
+// The combined attack is compared to the best attack that surges only one of
+// the surged links, which leaves all of the node's other links available for
+// traffic to be diverted to.
+func surgeAttackLinks(links []surgeLink, divertPercent uint8,
+	params Params) (*surgeLinksOutcome, error) {
+
+	if divertPercent > 100 {
+		return nil, fmt.Errorf("divert percent: %v > 100",
+			divertPercent)
+	}
+
+	outcomes := make([]*SurgeAttackOutcome, len(links))
+	for i, link := range links {
+		outcome, err := surgeAttack(
+			link.honestPeers, link.cutoffIndex,
+			surgeAttackCfg{params: params},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("link %v: %w", i, err)
+		}
+
+		outcomes[i] = outcome
+	}
+
+	// attack returns the outcome of surging the links that are selected.
+	attack := func(surged func(i int) bool) linkAttack {
+		var (
+			result     = linkAttack{goodReputation: true}
+			cutOff     uint64
+			divertible bool
+		)
+
+		for i, outcome := range outcomes {
+			result.peaceRevenue = saturatingAdd(
+				result.peaceRevenue, outcome.peaceRevenue,
+			)
+
+			if !surged(i) {
+				result.honestRevenue = saturatingAdd(
+					result.honestRevenue,
+					outcome.peaceRevenue,
+				)
+				divertible = true
+
+				continue
+			}
+
+			result.honestRevenue = saturatingAdd(
+				result.honestRevenue, outcome.attackRevenue,
+			)
+			result.attackerPays = saturatingAdd(
+				result.attackerPays, outcome.attackerPays(),
+			)
+			result.goodReputation = result.goodReputation &&
+				outcome.hadGoodReputation()
+
+			cutOff += outcome.peaceRevenue - outcome.attackRevenue
+		}
+
+		if divertible {
+			result.divertedRevenue = mulDiv(
+				cutOff, uint64(divertPercent), 100,
+			)
+		}
+
+		return result
+	}
+
+	outcome := &surgeLinksOutcome{
+		combined: attack(func(i int) bool {
+			return links[i].surged
+		}),
+		bestSingleIndex: -1,
+	}
+
+	for i, link := range links {
+		if !link.surged {
+			continue
+		}
+
+		single := attack(func(j int) bool {
+			return i == j
+		})
+		if single.lostRevenue() == 0 {
+			continue
+		}
+
+		if outcome.bestSingleIndex < 0 ||
+			single.costEffectiveness() >
+				outcome.bestSingle.costEffectiveness() {
+
+			outcome.bestSingle = single
+			outcome.bestSingleIndex = i
+		}
+	}
+
+	return outcome, nil
+}
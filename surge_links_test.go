@@ -0,0 +1,73 @@
+package reputationfuzz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSurgeAttackLinks tests surging several of a node's outgoing links at
+// once, compared to the best attack on a single link.
+func TestSurgeAttackLinks(t *testing.T) {
+	link := surgeLink{
+		honestPeers: syntheticSurgePeers,
+		cutoffIndex: 17,
+		surged:      true,
+	}
+	links := []surgeLink{link, link}
+
+	// Without any traffic diverted, the node loses the sum of each link's
+	// loss and the attacker pays for each link, so surging both links is
+	// no more cost effective than surging one of them.
+	outcome, err := surgeAttackLinks(links, 0, Params{})
+	require.NoError(t, err)
+	require.Equal(t, linkAttack{
+		peaceRevenue:   21_491_666_656,
+		attackerPays:   3_708_333_344,
+		goodReputation: true,
+	}, outcome.combined)
+	require.EqualValues(t, 17_783_333_312, outcome.combined.lostRevenue())
+	require.Zero(t, outcome.bestSingleIndex)
+	require.EqualValues(t, 8_891_666_656, outcome.bestSingle.lostRevenue())
+	require.False(t, outcome.combinedMoreEffective())
+
+	// When half of the traffic that is cut off on a surged link is
+	// diverted to the node's other link, surging a single link is
+	// partially compensated. Surging both leaves nowhere for traffic to
+	// be diverted to, so the combined attack is more effective.
+	outcome, err = surgeAttackLinks(links, 50, Params{})
+	require.NoError(t, err)
+	require.Zero(t, outcome.combined.divertedRevenue)
+	require.EqualValues(t, 5_372_916_664,
+		outcome.bestSingle.divertedRevenue)
+	require.EqualValues(t, 3_518_749_992, outcome.bestSingle.lostRevenue())
+	require.True(t, outcome.combinedMoreEffective())
+
+	// A link that isn't surged absorbs traffic that is diverted from both
+	// of the surged links, removing the combined attack's advantage.
+	links = append(links, surgeLink{
+		honestPeers: []uint64{12_000_000_000},
+	})
+	outcome, err = surgeAttackLinks(links, 50, Params{})
+	require.NoError(t, err)
+	require.EqualValues(t, 10_745_833_328, outcome.combined.divertedRevenue)
+	require.EqualValues(t, 7_037_499_984, outcome.combined.lostRevenue())
+	require.False(t, outcome.combinedMoreEffective())
+
+	// If the peers that are cut off on a surged link never had good
+	// reputation, the combined attack isn't successful and the best single
+	// link attack only surges the other link.
+	links[0].cutoffIndex = 0
+	outcome, err = surgeAttackLinks(links, 0, Params{})
+	require.NoError(t, err)
+	require.Zero(t, outcome.combined.lostRevenue())
+	require.Equal(t, 1, outcome.bestSingleIndex)
+	require.False(t, outcome.combinedMoreEffective())
+
+	_, err = surgeAttackLinks(links, 101, Params{})
+	require.Error(t, err)
+
+	links[0].cutoffIndex = len(syntheticSurgePeers)
+	_, err = surgeAttackLinks(links, 0, Params{})
+	require.Error(t, err)
+}
@@ -0,0 +1,125 @@
+package reputationfuzz
+
+import "math"
+
+// reputationWeighting returns the weight, expressed as a percentage, that is
+// applied to traffic that was forwarded weeksAgo weeks before the present when
+// it is counted towards reputation.
+type reputationWeighting func(weeksAgo uint64) uint64
+
+// linearRecencyWeighting returns a weighting that weights traffic linearly by
+// how recently it was forwarded, so that the most recent week counts for
+// almost double and the oldest week in the params' reputation period counts
+// for very little. The weights average to 100% over the reputation period, so
+// a node with constant traffic has the same reputation as it would with
+// uniform weighting.
+func linearRecencyWeighting(params Params) reputationWeighting {
+	reputationPeriod := params.reputationPeriod()
+
+	return func(weeksAgo uint64) uint64 {
+		if weeksAgo >= reputationPeriod {
+			return 0
+		}
+
+		return 200 * (reputationPeriod - weeksAgo) /
+			(reputationPeriod + 1)
+	}
+}
+
+// weightedReputation returns the reputation earned by traffic that was
+// forwarded at a constant rate over the most recent number of weeks provided,
+// with each week's contribution adjusted by the weighting function. A nil
+// weighting counts all traffic uniformly. Reputation saturates at
+// math.MaxUint64 rather than overflowing for large amounts of traffic.
+func weightedReputation(traffic, weeks uint64,
+	weighting reputationWeighting) uint64 {
+
+	if weighting == nil || weeks == 0 {
+		return traffic
+	}
+
+	return mulDiv(
+		traffic, totalWeight(weeks, weighting),
+		saturatingMul(weeks, 100),
+	)
+}
+
+// emaWeighting returns a weighting that decays traffic exponentially with the
+// half-life provided, as an exponential moving average does, so that traffic
+// forwarded halfLifeWeeks ago counts for half as much as traffic forwarded
+// this week. Unlike linearRecencyWeighting, the weights are not normalized, so
+// a node with constant traffic has less reputation than with uniform
+// weighting because older traffic has decayed.
+func emaWeighting(halfLifeWeeks uint64) reputationWeighting {
+	return func(weeksAgo uint64) uint64 {
+		decay := math.Pow(0.5, float64(weeksAgo)/float64(halfLifeWeeks))
+		return uint64(math.Round(100 * decay))
+	}
+}
+
+// totalWeight returns the sum of the percentage weights applied to each week
+// in the number of weeks provided, which is 100 per week for a nil weighting.
+func totalWeight(weeks uint64, weighting reputationWeighting) uint64 {
+	if weighting == nil {
+		return saturatingMul(weeks, 100)
+	}
+
+	var total uint64
+	for weeksAgo := uint64(0); weeksAgo < weeks; weeksAgo++ {
+		total = saturatingAdd(total, weighting(weeksAgo))
+	}
+
+	return total
+}
+
+// profileReputation returns the reputation earned by a weekly traffic profile,
+// where the first entry is the traffic forwarded in the most recent week, with
+// each week's traffic adjusted by the weighting function. Only traffic within
+// the number of weeks provided counts. A nil weighting counts all traffic
+// uniformly. Reputation saturates at math.MaxUint64 rather than overflowing.
+func profileReputation(weeklyTraffic []uint64, weeks uint64,
+	weighting reputationWeighting) uint64 {
+
+	var reputation uint64
+	for weeksAgo, traffic := range weeklyTraffic {
+		if uint64(weeksAgo) >= weeks {
+			break
+		}
+
+		weight := uint64(100)
+		if weighting != nil {
+			weight = weighting(uint64(weeksAgo))
+		}
+
+		reputation = saturatingAdd(
+			reputation, mulDiv(traffic, weight, 100),
+		)
+	}
+
+	return reputation
+}
+
+// distributeTraffic splits the total traffic provided across the weeks in the
+// profile, proportionally to each week's weight. Only weeks within the number
+// of weeks provided are considered.
+func distributeTraffic(total uint64, profile []uint64, weeks uint64) []uint64 {
+	if uint64(len(profile)) > weeks {
+		profile = profile[:weeks]
+	}
+
+	var profileWeight uint64
+	for _, weight := range profile {
+		profileWeight = saturatingAdd(profileWeight, weight)
+	}
+
+	weekly := make([]uint64, len(profile))
+	if profileWeight == 0 {
+		return weekly
+	}
+
+	for i, weight := range profile {
+		weekly[i] = mulDiv(total, weight, profileWeight)
+	}
+
+	return weekly
+}
@@ -0,0 +1,139 @@
+package reputationfuzz
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestEMAWeighting tests that exponential decay weights traffic by its
+// half-life.
+func TestEMAWeighting(t *testing.T) {
+	weighting := emaWeighting(4)
+
+	require.EqualValues(t, 100, weighting(0))
+	require.EqualValues(t, 50, weighting(4))
+	require.EqualValues(t, 25, weighting(8))
+	require.EqualValues(t, 84, weighting(1))
+}
+
+// TestEMARampingProfile compares the revenue implied by reputation under a
+// flat window and an exponential moving average for a node whose traffic has
+// been ramping up.
+func TestEMARampingProfile(t *testing.T) {
+	var (
+		flat = Params{}
+		ema  = Params{
+			DecayHalfLifeWeeks: 4,
+		}
+	)
+
+	// impliedRevenue returns the revenue that a node's reputation implies
+	// under the params provided.
+	impliedRevenue := func(profile []uint64,
+		params Params) uint64 {
+
+		reputation := profileReputation(
+			profile, params.reputationPeriod(), params.weighting(),
+		)
+
+		return revenueFromReputation(reputation, params)
+	}
+
+	// With constant traffic, both modes imply the node's actual revenue.
+	constant := make([]uint64, reputationPeriodWeeks)
+	for i := range constant {
+		constant[i] = 12_000
+	}
+	require.EqualValues(t, 24_000, impliedRevenue(constant, flat))
+	require.InDelta(t, 24_000, impliedRevenue(constant, ema), 1)
+
+	// A node whose traffic has ramped up over the reputation period, most
+	// recent week first, earned 47_000 in the last two weeks.
+	ramp := make([]uint64, reputationPeriodWeeks)
+	for i := range ramp {
+		ramp[i] = uint64(reputationPeriodWeeks-i) * 1_000
+	}
+
+	// The flat window averages the ramp out, significantly understating
+	// the node's recent revenue, while the moving average tracks it more
+	// closely.
+	flatRevenue := impliedRevenue(ramp, flat)
+	emaRevenue := impliedRevenue(ramp, ema)
+
+	require.EqualValues(t, 25_000, flatRevenue)
+	require.Greater(t, emaRevenue, flatRevenue)
+	require.Less(t, emaRevenue, uint64(47_000))
+
+	// The ladder uses the params' weighting to calculate reputation, so
+	// the moving average also reduces the reputation of a node with
+	// constant traffic, because older traffic has decayed.
+	cfg := newScenario(
+		120_000, []uint8{100, 10, 25}, 30_000, 300,
+	).cfg
+
+	flatLadder, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	cfg.params = ema
+	emaLadder, err := newLadderingAttack(cfg)
+	require.NoError(t, err)
+
+	for i, channel := range emaLadder.channels {
+		require.Less(t, channel.incomingReputation,
+			flatLadder.channels[i].incomingReputation)
+		require.Equal(t, channel.outgoingRevenue,
+			flatLadder.channels[i].outgoingRevenue)
+	}
+}
+
+// TestLinearRecencyWeighting tests that linear recency weighting spans the
+// reputation period of the params provided.
+func TestLinearRecencyWeighting(t *testing.T) {
+	weighting := linearRecencyWeighting(Params{})
+	require.EqualValues(t, 192, weighting(0))
+	require.EqualValues(t, 8, weighting(reputationPeriodWeeks-1))
+	require.Zero(t, weighting(reputationPeriodWeeks))
+
+	// A shorter reputation period stops weighting traffic sooner.
+	weighting = linearRecencyWeighting(Params{ReputationPeriodWeeks: 4})
+	require.EqualValues(t, 160, weighting(0))
+	require.EqualValues(t, 40, weighting(3))
+	require.Zero(t, weighting(4))
+}
+
+// TestWeightedReputationSaturates tests that weighting large amounts of
+// traffic saturates rather than wrapping around to a small reputation.
+func TestWeightedReputationSaturates(t *testing.T) {
+	var (
+		ema    = emaWeighting(4)
+		linear = linearRecencyWeighting(Params{})
+	)
+
+	// Weighted traffic that doesn't fit in the product is scaled down by
+	// the total weight rather than wrapping.
+	reputation := weightedReputation(
+		math.MaxUint64, reputationPeriodWeeks, ema,
+	)
+	require.Greater(t, reputation, uint64(math.MaxUint64/5))
+
+	reputation = weightedReputation(
+		math.MaxUint64, reputationPeriodWeeks, linear,
+	)
+	require.Greater(t, reputation, uint64(math.MaxUint64/2))
+
+	// Summing weeks of large traffic saturates.
+	profile := []uint64{math.MaxUint64, math.MaxUint64}
+	require.EqualValues(t, uint64(math.MaxUint64), profileReputation(
+		profile, reputationPeriodWeeks, nil,
+	))
+	require.EqualValues(t, uint64(math.MaxUint64), profileReputation(
+		profile, reputationPeriodWeeks, ema,
+	))
+
+	// A week's weight over 100% saturates rather than wrapping.
+	require.EqualValues(t, uint64(math.MaxUint64), profileReputation(
+		profile[:1], reputationPeriodWeeks, linear,
+	))
+}